@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"time"
 
 	"github.com/cybre/order-packing/internal/api"
+	"github.com/cybre/order-packing/internal/models"
 	"github.com/cybre/order-packing/internal/providers"
 	"github.com/cybre/order-packing/internal/services"
 	"github.com/joho/godotenv"
@@ -17,9 +22,241 @@ func main() {
 
 	godotenv.Load()
 
-	packSizeProvider := providers.NewJSONPackSizeProvider(os.Getenv("PACKSIZES_JSON_FILE_PATH"))
-	packingService := services.NewPackingService(packSizeProvider)
+	policy, err := packingPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure packing policy: %v", err)
+	}
+
+	hierarchy, err := packagingHierarchyFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure packaging hierarchy: %v", err)
+	}
+
+	rules, err := packSizeRulesFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure pack size rules: %v", err)
+	}
+
+	packer, err := services.LookupPacker(os.Getenv("PACKING_STRATEGY"))
+	if err != nil {
+		log.Fatalf("failed to configure packing strategy: %v", err)
+	}
+
+	packSizeProvider, err := packSizeProviderFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure pack size provider: %v", err)
+	}
+	defer packSizeProvider.Close()
+
+	packingService := services.NewPackingService(
+		packSizeProvider,
+		services.WithPackingPolicy(policy),
+		services.WithPacker(packer),
+		services.WithPackagingHierarchy(hierarchy),
+		services.WithPackSizeRules(rules),
+		services.WithPackSizeHistory(packSizeProvider),
+		services.WithPackSizeSchedule(packSizeProvider),
+	)
 
 	// Start the server and block until the context is canceled (e.g. by pressing Ctrl+C in the terminal)
 	api.StartServer(ctx, os.Getenv("API_ADDRESS"), packingService)
 }
+
+// packSizeStore is a pack size provider keeping the history and schedule of the pack sizes
+type packSizeStore interface {
+	services.PackSizeProvider
+	services.PackSizeHistory
+	services.PackSizeSchedule
+	Close() error
+}
+
+// packSizeProviderFromEnv opens the pack size provider selected by PACKSIZE_PROVIDER, the pack sizes file by default
+func packSizeProviderFromEnv() (packSizeStore, error) {
+	// PACKSIZES_JSON_FILE_PATH is still read for deployments set up before other file formats were supported
+	filePath := os.Getenv("PACKSIZES_FILE_PATH")
+	if filePath == "" {
+		filePath = os.Getenv("PACKSIZES_JSON_FILE_PATH")
+	}
+
+	switch provider := os.Getenv("PACKSIZE_PROVIDER"); provider {
+	case "", "file", "json":
+		fileProvider := providers.NewFilePackSizeProvider(filePath)
+		restored, err := fileProvider.Recover()
+		if err != nil {
+			return nil, fmt.Errorf("failed to recover pack sizes file: %w", err)
+		}
+		if restored {
+			log.Printf("pack sizes file could not be read, restored it from its backup")
+		}
+
+		// Pack sizes are served from memory, and reloaded when the file is changed by hand or by other replicas.
+		// Replicas on other hosts sharing a network volume are only noticed by checking the file every so often.
+		statInterval := providers.DefaultCachingStatInterval
+		if value := os.Getenv("PACKSIZES_FILE_STAT_INTERVAL"); value != "" {
+			if statInterval, err = time.ParseDuration(value); err != nil {
+				return nil, fmt.Errorf("failed to parse PACKSIZES_FILE_STAT_INTERVAL: %w", err)
+			}
+		}
+
+		return providers.NewCachingPackSizeProvider(fileProvider, filePath, providers.WithCachingStatInterval(statInterval))
+	case "db":
+		dbProvider, err := providers.NewBoltPackSizeProvider(os.Getenv("PACKSIZES_DB_FILE_PATH"))
+		if err != nil {
+			return nil, err
+		}
+
+		// The pack sizes file is imported into a new database once
+		if filePath != "" {
+			imported, err := dbProvider.ImportFile(filePath)
+			if err != nil {
+				dbProvider.Close()
+				return nil, fmt.Errorf("failed to import pack sizes file: %w", err)
+			}
+			if imported {
+				log.Printf("imported pack sizes from %s into the database", filePath)
+			}
+		}
+
+		return dbProvider, nil
+	case "http":
+		return httpPackSizeProviderFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown pack size provider %q", provider)
+	}
+}
+
+// httpPackSizeProviderFromEnv configures the provider reading pack sizes from the catalog at PACKSIZES_CATALOG_URL,
+// the timeout, retries, cache and circuit breaker settings are optional
+func httpPackSizeProviderFromEnv() (packSizeStore, error) {
+	baseURL := os.Getenv("PACKSIZES_CATALOG_URL")
+	if baseURL == "" {
+		return nil, fmt.Errorf("PACKSIZES_CATALOG_URL is required")
+	}
+
+	timeout, freshFor := providers.DefaultHTTPTimeout, providers.DefaultHTTPFreshFor
+	retries, backoff := providers.DefaultHTTPRetries, providers.DefaultHTTPBackoff
+	failures, openFor := providers.DefaultHTTPFailureThreshold, providers.DefaultHTTPOpenFor
+	cacheSize := providers.DefaultHTTPCacheSize
+
+	for name, duration := range map[string]*time.Duration{
+		"PACKSIZES_CATALOG_TIMEOUT":   &timeout,
+		"PACKSIZES_CATALOG_FRESH_FOR": &freshFor,
+		"PACKSIZES_CATALOG_BACKOFF":   &backoff,
+		"PACKSIZES_CATALOG_OPEN_FOR":  &openFor,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+			*duration = parsed
+		}
+	}
+
+	for name, count := range map[string]*int{
+		"PACKSIZES_CATALOG_RETRIES":           &retries,
+		"PACKSIZES_CATALOG_FAILURE_THRESHOLD": &failures,
+		"PACKSIZES_CATALOG_CACHE_SIZE":        &cacheSize,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+			if parsed < 0 {
+				return nil, fmt.Errorf("%s cannot be negative", name)
+			}
+			*count = parsed
+		}
+	}
+
+	return providers.NewHTTPPackSizeProvider(baseURL,
+		providers.WithHTTPTimeout(timeout),
+		providers.WithHTTPFreshFor(freshFor),
+		providers.WithHTTPRetries(retries, backoff),
+		providers.WithHTTPCircuitBreaker(failures, openFor),
+		providers.WithHTTPCacheSize(cacheSize),
+	), nil
+}
+
+// packingPolicyFromEnv reads the global packing policy from the environment, every limit is optional
+func packingPolicyFromEnv() (models.PackingPolicy, error) {
+	policy := models.PackingPolicy{Mode: models.PolicyMode(os.Getenv("PACKING_POLICY_MODE"))}
+
+	for name, limit := range map[string]**int{
+		"PACKING_MAX_OVERSHOOT": &policy.MaxOvershoot,
+		"PACKING_MAX_UNDERFILL": &policy.MaxUnderfill,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return policy, fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+			*limit = &parsed
+		}
+	}
+
+	for name, limit := range map[string]**float64{
+		"PACKING_MAX_OVERSHOOT_PERCENT": &policy.MaxOvershootPercent,
+		"PACKING_MAX_UNDERFILL_PERCENT": &policy.MaxUnderfillPercent,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return policy, fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+			*limit = &parsed
+		}
+	}
+
+	if !policy.IsValid() {
+		return policy, services.ErrInvalidPolicy
+	}
+
+	return policy, nil
+}
+
+// packagingHierarchyFromEnv reads the global packaging hierarchy from the environment, e.g. "carton:4,pallet:20"
+func packagingHierarchyFromEnv() (models.PackagingHierarchy, error) {
+	value := os.Getenv("PACKAGING_HIERARCHY")
+	if value == "" {
+		return nil, nil
+	}
+
+	hierarchy, err := models.ParsePackagingHierarchy(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hierarchy.IsValid() {
+		return nil, services.ErrInvalidHierarchy
+	}
+
+	return hierarchy, nil
+}
+
+// packSizeRulesFromEnv reads the rules pack sizes have to follow from the environment, every limit is optional.
+// Pack sizes hold at most DefaultMaxPackSizeItems unless PACK_SIZE_MAX_ITEMS says otherwise, 0 lifting the limit.
+func packSizeRulesFromEnv() (services.PackSizeRules, error) {
+	rules := services.PackSizeRules{MaxItems: services.DefaultMaxPackSizeItems}
+
+	for name, limit := range map[string]*int{
+		"PACK_SIZE_MIN_ITEMS": &rules.MinItems,
+		"PACK_SIZE_MAX_ITEMS": &rules.MaxItems,
+		"PACK_SIZE_MAX_COUNT": &rules.MaxCount,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return rules, fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+			*limit = parsed
+		}
+	}
+
+	if !rules.IsValid() {
+		return rules, services.ErrInvalidPackSizeRules
+	}
+
+	return rules, nil
+}
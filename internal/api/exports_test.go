@@ -1,7 +1,21 @@
 package api
 
 var (
-	GetPackSizesHandler   = getPackSizesHandler
-	UpdatePackSizesHadler = updatePackSizesHadler
-	PackOrderHandler      = packOrderHandler
+	GetPackSizesHandler          = getPackSizesHandler
+	UpdatePackSizesHadler        = updatePackSizesHadler
+	PackOrderHandler             = packOrderHandler
+	PackOrderV2Handler           = packOrderV2Handler
+	PackOrderLinesHandler        = packOrderLinesHandler
+	PackOrderShipmentsHandler    = packOrderShipmentsHandler
+	PackOrderBatchHandler        = packOrderBatchHandler
+	AdjustStockHandler           = adjustStockHandler
+	GetPackSizeVersionsHandler   = getPackSizeVersionsHandler
+	GetPackSizeVersionHandler    = getPackSizeVersionHandler
+	RollbackPackSizesHandler     = rollbackPackSizesHandler
+	GetScheduledPackSizesHandler = getScheduledPackSizesHandler
+	SchedulePackSizesHandler     = schedulePackSizesHandler
+	UnschedulePackSizesHandler   = unschedulePackSizesHandler
+	AddPackSizeHandler           = addPackSizeHandler
+	PatchPackSizeHandler         = patchPackSizeHandler
+	RemovePackSizeHandler        = removePackSizeHandler
 )
@@ -2,21 +2,42 @@ package api
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
 type PackingService interface {
-	CalculatePacks(context.Context, models.Order) (map[int]int, error)
-	UpdatePackSizes(context.Context, []models.PackSize) error
-	GetPackSizes(context.Context) ([]models.PackSize, error)
+	CalculatePacks(context.Context, models.Order) (models.PackingResult, error)
+	UpdatePackSizesIfMatch(context.Context, string, string, []models.PackSize) (string, error)
+	GetPackSizes(context.Context, string) ([]models.PackSize, error)
+	CalculateOrderPacks(context.Context, models.MultiLineOrder) (models.MultiLinePacking, error)
+	CalculateShipments(context.Context, models.ShipmentOrder) (models.ShipmentPlan, error)
+	NewBatch() services.BatchFunc
+	AdjustStock(context.Context, string, []models.StockAdjustment) error
+	PackSizeVersions(context.Context, string) ([]models.PackSizeSetVersion, error)
+	PackSizeVersion(context.Context, string, int) (models.PackSizeSetVersion, error)
+	RollbackPackSizes(context.Context, string, int) (models.PackSizeSetVersion, error)
+	SchedulePackSizes(context.Context, string, time.Time, []models.PackSize) (models.ScheduledPackSizes, error)
+	ScheduledPackSizes(context.Context, string) ([]models.ScheduledPackSizes, error)
+	UnschedulePackSizes(context.Context, string, int) error
+	AddPackSize(context.Context, string, models.PackSize) (models.PackSize, error)
+	RemovePackSize(context.Context, string, int) error
+	PatchPackSize(context.Context, string, int, models.PackSizePatch) (models.PackSize, error)
 }
 
+// authorHeader names who makes a change to the pack sizes
+const authorHeader = "X-Author"
+
 // StartServer starts an HTTP server on the specified address and blocks until the context is canceled.
 func StartServer(ctx context.Context, address string, packingService PackingService) error {
 	e := echo.New()
@@ -48,20 +69,67 @@ func StartServer(ctx context.Context, address string, packingService PackingServ
 func buildRoutes(e *echo.Echo, packingService PackingService) {
 	e.GET("/pack-sizes", getPackSizesHandler(packingService))
 	e.PUT("/pack-sizes", updatePackSizesHadler(packingService))
+	e.POST("/pack-sizes", addPackSizeHandler(packingService))
+	e.PATCH("/pack-sizes/:maxItems", patchPackSizeHandler(packingService))
+	e.DELETE("/pack-sizes/:maxItems", removePackSizeHandler(packingService))
+	e.POST("/pack-sizes/stock", adjustStockHandler(packingService))
+	e.GET("/pack-sizes/versions", getPackSizeVersionsHandler(packingService))
+	e.GET("/pack-sizes/versions/:id", getPackSizeVersionHandler(packingService))
+	e.POST("/pack-sizes/versions/:id/rollback", rollbackPackSizesHandler(packingService))
+	e.GET("/pack-sizes/schedule", getScheduledPackSizesHandler(packingService))
+	e.POST("/pack-sizes/schedule", schedulePackSizesHandler(packingService))
+	e.DELETE("/pack-sizes/schedule/:id", unschedulePackSizesHandler(packingService))
 	e.POST("/pack-order", packOrderHandler(packingService))
+	e.POST("/v2/pack-order", packOrderV2Handler(packingService))
+	e.POST("/pack-order-lines", packOrderLinesHandler(packingService))
+	e.POST("/pack-order-shipments", packOrderShipmentsHandler(packingService))
+	e.POST("/pack-order-batch", packOrderBatchHandler(packingService))
+
+	// Product specific pack sizes, the routes above work with the default pack sizes
+	e.GET("/products/:sku/pack-sizes", getPackSizesHandler(packingService))
+	e.PUT("/products/:sku/pack-sizes", updatePackSizesHadler(packingService))
+	e.POST("/products/:sku/pack-sizes", addPackSizeHandler(packingService))
+	e.PATCH("/products/:sku/pack-sizes/:maxItems", patchPackSizeHandler(packingService))
+	e.DELETE("/products/:sku/pack-sizes/:maxItems", removePackSizeHandler(packingService))
+	e.POST("/products/:sku/pack-sizes/stock", adjustStockHandler(packingService))
+	e.GET("/products/:sku/pack-sizes/versions", getPackSizeVersionsHandler(packingService))
+	e.GET("/products/:sku/pack-sizes/versions/:id", getPackSizeVersionHandler(packingService))
+	e.POST("/products/:sku/pack-sizes/versions/:id/rollback", rollbackPackSizesHandler(packingService))
+	e.GET("/products/:sku/pack-sizes/schedule", getScheduledPackSizesHandler(packingService))
+	e.POST("/products/:sku/pack-sizes/schedule", schedulePackSizesHandler(packingService))
+	e.DELETE("/products/:sku/pack-sizes/schedule/:id", unschedulePackSizesHandler(packingService))
 }
 
 func getPackSizesHandler(packingService PackingService) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		packSizes, err := packingService.GetPackSizes(c.Request().Context())
+		packSizes, err := packingService.GetPackSizes(c.Request().Context(), c.Param("sku"))
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 
+		c.Response().Header().Set("ETag", packSizesETag(models.PackSizesVersion(packSizes)))
+
 		return c.JSON(http.StatusOK, packSizes)
 	}
 }
 
+// packSizesETag returns the entity tag of the pack sizes with the version
+func packSizesETag(version string) string {
+	return `"` + version + `"`
+}
+
+// ifMatchVersion returns the version of the pack sizes the If-Match header asks for, empty when it matches any version
+func ifMatchVersion(header string) string {
+	tag := strings.TrimPrefix(strings.TrimSpace(header), "W/")
+	if tag == "*" {
+		return ""
+	}
+
+	return strings.Trim(tag, `"`)
+}
+
+// updatePackSizesHadler updates the pack sizes if they still have the version of the If-Match header, which
+// is the ETag of the pack sizes they replace, so concurrent updates do not overwrite each other unnoticed
 func updatePackSizesHadler(packingService PackingService) func(c echo.Context) error {
 	return func(c echo.Context) error {
 		var packSizes []models.PackSize
@@ -73,30 +141,544 @@ func updatePackSizesHadler(packingService PackingService) func(c echo.Context) e
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "pack sizes cannot be empty"})
 		}
 
-		if err := packingService.UpdatePackSizes(c.Request().Context(), packSizes); err != nil {
+		ifMatch := c.Request().Header.Get("If-Match")
+		if ifMatch == "" {
+			return c.JSON(http.StatusPreconditionRequired, map[string]string{"error": "If-Match header is required"})
+		}
+
+		version, err := packingService.UpdatePackSizesIfMatch(authorContext(c), c.Param("sku"), ifMatchVersion(ifMatch), packSizes)
+		if err != nil {
+			var packSizesErr *services.PackSizesError
+			if errors.As(err, &packSizesErr) {
+				return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{"error": err.Error(), "fields": packSizesErr.Fields})
+			}
+
+			if errors.Is(err, services.ErrVersionConflict) {
+				return c.JSON(http.StatusPreconditionFailed, map[string]string{"error": err.Error()})
+			}
+
+			if errors.Is(err, services.ErrReadOnlyPackSizes) {
+				return c.JSON(http.StatusNotImplemented, map[string]string{"error": err.Error()})
+			}
+
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 
+		c.Response().Header().Set("ETag", packSizesETag(version))
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// addPackSizeHandler adds a single pack size and responds with it
+func addPackSizeHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var packSize models.PackSize
+		if err := c.Bind(&packSize); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		added, err := packingService.AddPackSize(authorContext(c), c.Param("sku"), packSize)
+		if err != nil {
+			return packSizeChangeError(c, err)
+		}
+
+		return c.JSON(http.StatusCreated, added)
+	}
+}
+
+// patchPackSizeHandler changes the attributes of the request of a single pack size and responds with the changed pack size
+func patchPackSizeHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		maxItems, err := strconv.Atoi(c.Param("maxItems"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid pack size"})
+		}
+
+		var patch models.PackSizePatch
+		if err := c.Bind(&patch); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		packSize, err := packingService.PatchPackSize(authorContext(c), c.Param("sku"), maxItems, patch)
+		if err != nil {
+			return packSizeChangeError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, packSize)
+	}
+}
+
+func removePackSizeHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		maxItems, err := strconv.Atoi(c.Param("maxItems"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid pack size"})
+		}
+
+		if err := packingService.RemovePackSize(authorContext(c), c.Param("sku"), maxItems); err != nil {
+			return packSizeChangeError(c, err)
+		}
+
 		return c.NoContent(http.StatusNoContent)
 	}
 }
 
+// packSizeChangeError responds with the error of adding, patching or removing a single pack size
+func packSizeChangeError(c echo.Context, err error) error {
+	var packSizesErr *services.PackSizesError
+	switch {
+	case errors.As(err, &packSizesErr):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{"error": err.Error(), "fields": packSizesErr.Fields})
+	case errors.Is(err, services.ErrPackSizeExists):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrUnknownPackSize):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrReadOnlyPackSizes):
+		return c.JSON(http.StatusNotImplemented, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
+
+// authorContext returns the context of the request naming the author of the changes it makes
+func authorContext(c echo.Context) context.Context {
+	return services.ContextWithAuthor(c.Request().Context(), c.Request().Header.Get(authorHeader))
+}
+
+func getPackSizeVersionsHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		versions, err := packingService.PackSizeVersions(c.Request().Context(), c.Param("sku"))
+		if err != nil {
+			return c.JSON(historyErrorStatus(err), map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, versions)
+	}
+}
+
+func getPackSizeVersionHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid pack size version"})
+		}
+
+		version, err := packingService.PackSizeVersion(c.Request().Context(), c.Param("sku"), id)
+		if err != nil {
+			return c.JSON(historyErrorStatus(err), map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, version)
+	}
+}
+
+// rollbackPackSizesHandler restores the pack sizes of a version and responds with the version the rollback is saved as
+func rollbackPackSizesHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid pack size version"})
+		}
+
+		version, err := packingService.RollbackPackSizes(authorContext(c), c.Param("sku"), id)
+		if err != nil {
+			// The pack size rules may have changed since the version was saved
+			var packSizesErr *services.PackSizesError
+			if errors.As(err, &packSizesErr) {
+				return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{"error": err.Error(), "fields": packSizesErr.Fields})
+			}
+
+			return c.JSON(historyErrorStatus(err), map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, version)
+	}
+}
+
+// historyErrorStatus returns the status code of an error getting or restoring pack size versions
+func historyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUnknownVersion):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrHistoryUnavailable):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func getScheduledPackSizesHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		schedule, err := packingService.ScheduledPackSizes(c.Request().Context(), c.Param("sku"))
+		if err != nil {
+			return c.JSON(scheduleErrorStatus(err), map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, schedule)
+	}
+}
+
+// schedulePackSizesHandler schedules the pack sizes of the request to take effect at its effectiveFrom time
+// and responds with the scheduled pack sizes
+func schedulePackSizesHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var scheduled models.ScheduledPackSizes
+		if err := c.Bind(&scheduled); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		if len(scheduled.PackSizes) == 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "pack sizes cannot be empty"})
+		}
+
+		scheduled, err := packingService.SchedulePackSizes(authorContext(c), c.Param("sku"), scheduled.EffectiveFrom, scheduled.PackSizes)
+		if err != nil {
+			var packSizesErr *services.PackSizesError
+			if errors.As(err, &packSizesErr) {
+				return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{"error": err.Error(), "fields": packSizesErr.Fields})
+			}
+
+			return c.JSON(scheduleErrorStatus(err), map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusCreated, scheduled)
+	}
+}
+
+func unschedulePackSizesHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid scheduled pack sizes"})
+		}
+
+		if err := packingService.UnschedulePackSizes(c.Request().Context(), c.Param("sku"), id); err != nil {
+			return c.JSON(scheduleErrorStatus(err), map[string]string{"error": err.Error()})
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// scheduleErrorStatus returns the status code of an error scheduling pack sizes
+func scheduleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUnknownSchedule):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrScheduleUnavailable):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func adjustStockHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var adjustments []models.StockAdjustment
+		if err := c.Bind(&adjustments); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		if len(adjustments) == 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "stock adjustments cannot be empty"})
+		}
+
+		err := packingService.AdjustStock(c.Request().Context(), c.Param("sku"), adjustments)
+		switch {
+		case errors.Is(err, services.ErrInsufficientStock):
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		case errors.Is(err, services.ErrUnknownPackSize), errors.Is(err, services.ErrUnlimitedStock):
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case errors.Is(err, services.ErrReadOnlyPackSizes):
+			return c.JSON(http.StatusNotImplemented, map[string]string{"error": err.Error()})
+		case err != nil:
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// packOrderHandler responds with a map of pack size to the number of packs, the original shape of the response.
+// Explanations and alternatives do not fit that shape, so the full packing result is returned when they are requested.
+// Packaging plans are only returned by /v2/pack-order, as a global packaging hierarchy would change every response.
 func packOrderHandler(packingService PackingService) func(c echo.Context) error {
+	return packOrder(packingService, func(result models.PackingResult) interface{} {
+		if result.Explanation != nil || result.Alternatives != nil {
+			return result
+		}
+
+		return result.PackMap()
+	})
+}
+
+// packOrderV2Handler responds with the full packing result
+func packOrderV2Handler(packingService PackingService) func(c echo.Context) error {
+	return packOrder(packingService, func(result models.PackingResult) interface{} {
+		return result
+	})
+}
+
+func packOrder(packingService PackingService, response func(models.PackingResult) interface{}) func(c echo.Context) error {
 	return func(c echo.Context) error {
 		var order models.Order
 		if err := c.Bind(&order); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
 
-		if order.ItemQty <= 0 {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "order quantity must be greater than 0"})
+		if message := invalidOrder(order); message != "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": message})
+		}
+
+		if explain := c.QueryParam("explain"); explain != "" {
+			var err error
+			if order.Explain, err = strconv.ParseBool(explain); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "explain must be a boolean"})
+			}
+		}
+
+		if alternatives := c.QueryParam("alternatives"); alternatives != "" {
+			var err error
+			if order.Alternatives, err = strconv.Atoi(alternatives); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "alternatives must be a number"})
+			}
+		}
+
+		if order.Alternatives < 0 || order.Alternatives > services.MaxAlternatives {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": services.ErrAlternatives.Error()})
+		}
+
+		if asOf := c.QueryParam("asOf"); asOf != "" {
+			at, err := time.Parse(time.RFC3339, asOf)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "asOf must be an RFC 3339 time"})
+			}
+			order.AsOf = &at
+		}
+
+		result, err := packingService.CalculatePacks(c.Request().Context(), order)
+		if err != nil {
+			return c.JSON(packingErrorStatus(err), map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, response(result))
+	}
+}
+
+// packOrderShipmentsHandler responds with the packing result of the order split into shipments within the limits
+func packOrderShipmentsHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var order models.ShipmentOrder
+		if err := c.Bind(&order); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		if message := invalidOrder(order.Order); message != "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": message})
+		}
+
+		if !order.Limits.IsValid() {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": services.ErrInvalidShipmentLimits.Error()})
 		}
 
-		packs, err := packingService.CalculatePacks(c.Request().Context(), order)
+		plan, err := packingService.CalculateShipments(c.Request().Context(), order)
 		if err != nil {
+			return c.JSON(packingErrorStatus(err), map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusOK, plan)
+	}
+}
+
+// packOrderBatchHandler packs a batch of orders sent as a JSON array, or as NDJSON (one order per line) when the
+// content type is application/x-ndjson, and streams the results back in the same format and order. Orders are
+// decoded and packed one at a time so the memory used does not grow with the batch, and packing stops when the
+// client disconnects. Every order gets its own result with the status packing it on its own would have.
+func packOrderBatchHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ndjson := strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), ndjsonContentType)
+
+		decoder := json.NewDecoder(c.Request().Body)
+		if !ndjson {
+			if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "batch must be a JSON array of orders"})
+			}
+		}
+
+		res := c.Response()
+		writer := &batchWriter{res: res, encoder: json.NewEncoder(res), ndjson: ndjson}
+		if ndjson {
+			res.Header().Set(echo.HeaderContentType, ndjsonContentType)
+		} else {
+			res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		res.WriteHeader(http.StatusOK)
+
+		pack := packingService.NewBatch()
+		index := 0
+		for ; decoder.More(); index++ {
+			if ctx.Err() != nil {
+				// The client is gone, nobody reads the rest of the results
+				return nil
+			}
+
+			item := models.BatchResult{Index: index}
+
+			var order models.Order
+			err := decoder.Decode(&order)
+			var typeErr *json.UnmarshalTypeError
+			if err != nil && !errors.As(err, &typeErr) {
+				// The rest of the batch cannot be read after a syntax error
+				item.Status, item.Error = http.StatusBadRequest, fmt.Sprintf("failed to decode order: %v", err)
+				writer.write(item)
+
+				return writer.close()
+			}
+
+			if err != nil {
+				item.Status, item.Error = http.StatusBadRequest, fmt.Sprintf("failed to decode order: %v", err)
+			} else if message := invalidOrder(order); message != "" {
+				item.Status, item.Error = http.StatusBadRequest, message
+			} else if result, err := pack(ctx, order); err != nil {
+				item.Status, item.Error = packingErrorStatus(err), err.Error()
+			} else {
+				item.Status, item.Result = http.StatusOK, &result
+			}
+
+			if err := writer.write(item); err != nil {
+				return nil
+			}
+		}
+
+		if !ndjson {
+			if _, err := decoder.Token(); err != nil {
+				writer.write(models.BatchResult{Index: index, Status: http.StatusBadRequest, Error: fmt.Sprintf("failed to decode order: %v", err)})
+			}
+		}
+
+		return writer.close()
+	}
+}
+
+// ndjsonContentType is the content type of newline delimited JSON
+const ndjsonContentType = "application/x-ndjson"
+
+// batchWriter writes the results of a batch as they are packed, as a JSON array or as NDJSON
+type batchWriter struct {
+	res     *echo.Response
+	encoder *json.Encoder
+	ndjson  bool
+	written int
+}
+
+// write writes the result and flushes it to the client
+func (w *batchWriter) write(item models.BatchResult) error {
+	if !w.ndjson {
+		separator := ","
+		if w.written == 0 {
+			separator = "["
+		}
+		if _, err := w.res.Write([]byte(separator)); err != nil {
+			return err
+		}
+	}
+
+	if err := w.encoder.Encode(item); err != nil {
+		return err
+	}
+	w.written++
+	w.res.Flush()
+
+	return nil
+}
+
+// close ends the JSON array, NDJSON needs no ending
+func (w *batchWriter) close() error {
+	if w.ndjson {
+		return nil
+	}
+
+	ending := "]"
+	if w.written == 0 {
+		ending = "[]"
+	}
+	_, err := w.res.Write([]byte(ending))
+
+	return err
+}
+
+// invalidOrder returns why the order is invalid, or an empty string if it is valid
+func invalidOrder(order models.Order) string {
+	switch {
+	case order.ItemQty <= 0:
+		return "order quantity must be greater than 0"
+	case !order.Objective.IsValid():
+		return "unknown packing objective"
+	case order.Policy != nil && !order.Policy.IsValid():
+		return "invalid packing policy"
+	case !order.Hierarchy.IsValid():
+		return services.ErrInvalidHierarchy.Error()
+	case order.ItemWeight < 0 || order.ItemVolume < 0:
+		return "item weight and volume cannot be negative"
+	case order.Alternatives < 0 || order.Alternatives > services.MaxAlternatives:
+		return services.ErrAlternatives.Error()
+	default:
+		return ""
+	}
+}
+
+// packingErrorStatus returns the status code of an error packing an order
+func packingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInsufficientStock):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrPolicyNotSatisfied), errors.Is(err, services.ErrPackLimitsExceeded),
+		errors.Is(err, services.ErrShipmentLimitsExceeded), errors.Is(err, services.ErrPackingTooLarge):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrExplainUnsupported), errors.Is(err, services.ErrReserveAsOf):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrReadOnlyPackSizes):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func packOrderLinesHandler(packingService PackingService) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var order models.MultiLineOrder
+		if err := c.Bind(&order); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		if len(order.Lines) == 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "order must have at least one line"})
+		}
+
+		if !order.Objective.IsValid() {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "unknown packing objective"})
+		}
+
+		if order.Policy != nil && !order.Policy.IsValid() {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid packing policy"})
+		}
+
+		if !order.Hierarchy.IsValid() {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": services.ErrInvalidHierarchy.Error()})
+		}
+
+		result, err := packingService.CalculateOrderPacks(c.Request().Context(), order)
+		if err != nil {
+			var linesErr *services.OrderLinesError
+			if errors.As(err, &linesErr) {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error(), "lines": linesErr.Lines})
+			}
+
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 
-		return c.JSON(http.StatusOK, packs)
+		return c.JSON(http.StatusOK, result)
 	}
 }
@@ -2,16 +2,20 @@ package api_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cybre/order-packing/internal/api"
 	"github.com/cybre/order-packing/internal/api/testdata"
 	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
 	"github.com/labstack/echo/v4"
 )
 
@@ -51,6 +55,49 @@ func TestGetPackSizesHandler_Success(t *testing.T) {
 	if !reflect.DeepEqual(packSizes, expectedPackSizes) {
 		t.Errorf("Expected pack sizes %+v, got %+v", expectedPackSizes, packSizes)
 	}
+
+	expectedETag := `"` + models.PackSizesVersion(expectedPackSizes) + `"`
+	if etag := rec.Header().Get("ETag"); etag != expectedETag {
+		t.Errorf("Expected ETag %s, got %s", expectedETag, etag)
+	}
+}
+
+func TestGetPackSizesHandler_ProductSuccess(t *testing.T) {
+	expectedPackSizes := []models.PackSize{
+		{MaxItems: 6},
+		{MaxItems: 12},
+	}
+	mockPackingService := &testdata.MockPackingService{
+		PackSizes:        []models.PackSize{{MaxItems: 250}},
+		ProductPackSizes: map[string][]models.PackSize{"SKU-1": expectedPackSizes},
+	}
+
+	handler := api.GetPackSizesHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/products/SKU-1/pack-sizes", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("sku")
+	c.SetParamValues("SKU-1")
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	// Check the response body
+	var packSizes []models.PackSize
+	_ = json.Unmarshal(rec.Body.Bytes(), &packSizes)
+	if !reflect.DeepEqual(packSizes, expectedPackSizes) {
+		t.Errorf("Expected pack sizes %+v, got %+v", expectedPackSizes, packSizes)
+	}
 }
 
 func TestGetPackSizesHandler_ServiceError(t *testing.T) {
@@ -86,6 +133,7 @@ func TestUpdatePackSizesHandler_Success(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPut, "/pack-sizes", bytes.NewReader([]byte(`[{"maxItems": 250}]`)))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", "*")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
@@ -100,6 +148,78 @@ func TestUpdatePackSizesHandler_Success(t *testing.T) {
 	}
 }
 
+func TestUpdatePackSizesHandler_ProductSuccess(t *testing.T) {
+	mockPackingService := &testdata.MockPackingService{}
+
+	handler := api.UpdatePackSizesHadler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/products/SKU-1/pack-sizes", bytes.NewReader([]byte(`[{"maxItems": 6}]`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", "*")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("sku")
+	c.SetParamValues("SKU-1")
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, rec.Code)
+	}
+}
+
+func TestUpdatePackSizesHandler_Preconditions(t *testing.T) {
+	packSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}
+
+	testCases := []struct {
+		name           string
+		ifMatch        string
+		expectedStatus int
+		expectedETag   string
+	}{
+		{name: "Current version", ifMatch: `"` + models.PackSizesVersion(packSizes) + `"`, expectedStatus: http.StatusNoContent, expectedETag: `"` + models.PackSizesVersion([]models.PackSize{{MaxItems: 1000}}) + `"`},
+		{name: "Any version", ifMatch: "*", expectedStatus: http.StatusNoContent, expectedETag: `"` + models.PackSizesVersion([]models.PackSize{{MaxItems: 1000}}) + `"`},
+		{name: "Outdated version", ifMatch: `"0123456789abcdef"`, expectedStatus: http.StatusPreconditionFailed},
+		{name: "No version", expectedStatus: http.StatusPreconditionRequired},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := api.UpdatePackSizesHadler(&testdata.MockPackingService{PackSizes: packSizes})
+
+			// Create a new Echo context for testing
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/pack-sizes", bytes.NewReader([]byte(`[{"maxItems": 1000}]`)))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// Call the handler
+			err := handler(c)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, rec.Code)
+			}
+
+			if etag := rec.Header().Get("ETag"); etag != tc.expectedETag {
+				t.Errorf("Expected ETag %s, got %s", tc.expectedETag, etag)
+			}
+		})
+	}
+}
+
 func TestUpdatePackSizesHandler_ServiceError(t *testing.T) {
 	mockPackingService := &testdata.MockPackingService{
 		Error: errors.New("service error"),
@@ -111,6 +231,7 @@ func TestUpdatePackSizesHandler_ServiceError(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPut, "/pack-sizes", bytes.NewReader([]byte(`[{"maxItems": 250}]`)))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", "*")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
@@ -148,12 +269,56 @@ func TestUpdatePackSizesHandler_BadInputError(t *testing.T) {
 	}
 }
 
+func TestUpdatePackSizesHandler_InvalidPackSizesError(t *testing.T) {
+	fields := []models.FieldError{
+		{Field: "packSizes[0].maxItems", Message: "pack size must hold at least 1 item(s)"},
+		{Field: "packSizes[1].cost", Message: "pack cost cannot be negative"},
+	}
+	mockPackingService := &testdata.MockPackingService{
+		Error: &services.PackSizesError{Fields: fields},
+	}
+
+	handler := api.UpdatePackSizesHadler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/pack-sizes", bytes.NewReader([]byte(`[{"maxItems": 0}, {"maxItems": 250, "cost": -1}]`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", "*")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	var response struct {
+		Fields []models.FieldError `json:"fields"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &response)
+	if !reflect.DeepEqual(response.Fields, fields) {
+		t.Errorf("Expected field errors %+v, got %+v", fields, response.Fields)
+	}
+}
+
 func TestPackOrderHandler_Success(t *testing.T) {
 	expectedResponse := map[int]int{
 		500: 1,
 	}
 	mockPackingService := &testdata.MockPackingService{
-		Packs: expectedResponse,
+		PackingResult: models.PackingResult{
+			Packs:        []models.PackLine{{MaxItems: 500, Quantity: 1}},
+			RequestedQty: 251,
+			ItemsShipped: 500,
+			Overshoot:    249,
+			PackCount:    1,
+		},
 	}
 
 	handler := api.PackOrderHandler(mockPackingService)
@@ -182,6 +347,128 @@ func TestPackOrderHandler_Success(t *testing.T) {
 	}
 }
 
+func TestPackOrderV2Handler_Success(t *testing.T) {
+	expectedResponse := models.PackingResult{
+		Packs:            []models.PackLine{{MaxItems: 500, Quantity: 1}},
+		RequestedQty:     251,
+		ItemsShipped:     500,
+		Overshoot:        249,
+		PackCount:        1,
+		PackSizesVersion: "v1",
+	}
+	mockPackingService := &testdata.MockPackingService{
+		PackingResult: expectedResponse,
+	}
+
+	handler := api.PackOrderV2Handler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/v2/pack-order", bytes.NewReader([]byte(`{"itemQty": 251}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var result models.PackingResult
+	_ = json.Unmarshal(rec.Body.Bytes(), &result)
+	if !reflect.DeepEqual(result, expectedResponse) {
+		t.Errorf("Expected result %+v, got %+v", expectedResponse, result)
+	}
+}
+
+func TestPackOrderHandler_Explain(t *testing.T) {
+	mockPackingService := &testdata.MockPackingService{
+		PackingResult: models.PackingResult{
+			Packs:        []models.PackLine{{MaxItems: 500, Quantity: 1}},
+			RequestedQty: 251,
+			ItemsShipped: 500,
+			Overshoot:    249,
+			PackCount:    1,
+		},
+	}
+
+	handler := api.PackOrderHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order?explain=true", bytes.NewReader([]byte(`{"itemQty": 251}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var result models.PackingResult
+	_ = json.Unmarshal(rec.Body.Bytes(), &result)
+	if result.Explanation == nil || result.Explanation.Summary != "explained" {
+		t.Errorf("Expected an explanation, got %+v", result)
+	}
+}
+
+func TestPackOrderHandler_Alternatives(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{name: "ranked alternatives", query: "?alternatives=3", expectedStatus: http.StatusOK, expectedCount: 3},
+		{name: "not a number", query: "?alternatives=many", expectedStatus: http.StatusBadRequest},
+		{name: "too many", query: "?alternatives=11", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := api.PackOrderHandler(&testdata.MockPackingService{})
+
+			// Create a new Echo context for testing
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/pack-order"+tt.query, bytes.NewReader([]byte(`{"itemQty": 251}`)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// Call the handler
+			err := handler(c)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, rec.Code)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var result models.PackingResult
+			_ = json.Unmarshal(rec.Body.Bytes(), &result)
+			if len(result.Alternatives) != tt.expectedCount {
+				t.Errorf("Expected %d alternatives, got %d", tt.expectedCount, len(result.Alternatives))
+			}
+		})
+	}
+}
+
 func TestPackOrderHandler_BadInputError(t *testing.T) {
 	mockPackingService := &testdata.MockPackingService{}
 
@@ -205,16 +492,39 @@ func TestPackOrderHandler_BadInputError(t *testing.T) {
 	}
 }
 
-func TestPackOrderHandler_ServiceError(t *testing.T) {
+func TestPackOrderHandler_UnknownObjectiveError(t *testing.T) {
+	mockPackingService := &testdata.MockPackingService{}
+
+	handler := api.PackOrderHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order", bytes.NewReader([]byte(`{"itemQty": 251, "objective": "cheapest"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestPackOrderHandler_InsufficientStockError(t *testing.T) {
 	mockPackingService := &testdata.MockPackingService{
-		Error: errors.New("service error"),
+		Error: services.ErrInsufficientStock,
 	}
 
 	handler := api.PackOrderHandler(mockPackingService)
 
 	// Create a new Echo context for testing
 	e := echo.New()
-	req := httptest.NewRequest(http.MethodPost, "/pack-order", bytes.NewReader([]byte(`{"itemQty": 251}`)))
+	req := httptest.NewRequest(http.MethodPost, "/pack-order", bytes.NewReader([]byte(`{"itemQty": 251, "reserve": true}`)))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
@@ -225,7 +535,831 @@ func TestPackOrderHandler_ServiceError(t *testing.T) {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
-	if rec.Code != http.StatusInternalServerError {
-		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, rec.Code)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, rec.Code)
+	}
+}
+
+func TestPackOrderHandler_PolicyNotSatisfiedError(t *testing.T) {
+	mockPackingService := &testdata.MockPackingService{
+		Error: services.ErrPolicyNotSatisfied,
+	}
+
+	handler := api.PackOrderHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order", bytes.NewReader([]byte(`{"itemQty": 251, "policy": {"maxOvershoot": 10}}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestPackOrderHandler_PackLimitsExceededError(t *testing.T) {
+	mockPackingService := &testdata.MockPackingService{
+		Error: services.ErrPackLimitsExceeded,
+	}
+
+	handler := api.PackOrderHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order", bytes.NewReader([]byte(`{"itemQty": 251, "itemWeight": 100}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+}
+
+func TestPackOrderHandler_ExplainUnsupportedError(t *testing.T) {
+	mockPackingService := &testdata.MockPackingService{
+		Error: services.ErrExplainUnsupported,
+	}
+
+	handler := api.PackOrderHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order?explain=true", bytes.NewReader([]byte(`{"itemQty": 251}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestPackOrderHandler_InvalidPolicyError(t *testing.T) {
+	handler := api.PackOrderHandler(&testdata.MockPackingService{})
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order", bytes.NewReader([]byte(`{"itemQty": 251, "policy": {"maxOvershoot": -1}}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestPackOrderHandler_InvalidHierarchyError(t *testing.T) {
+	handler := api.PackOrderV2Handler(&testdata.MockPackingService{})
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/v2/pack-order", bytes.NewReader([]byte(`{"itemQty": 251, "hierarchy": [{"name": "carton", "holds": 0}]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestPackOrderHandler_ServiceError(t *testing.T) {
+	mockPackingService := &testdata.MockPackingService{
+		Error: errors.New("service error"),
+	}
+
+	handler := api.PackOrderHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order", bytes.NewReader([]byte(`{"itemQty": 251}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestPackOrderLinesHandler_Success(t *testing.T) {
+	expectedResponse := models.MultiLinePacking{
+		Lines: []models.LinePacking{
+			{SKU: "SKU-1", PackingResult: models.PackingResult{
+				Packs:        []models.PackLine{{MaxItems: 500, Quantity: 1}},
+				RequestedQty: 251,
+				ItemsShipped: 500,
+				Overshoot:    249,
+				PackCount:    1,
+			}},
+		},
+		PackCount:    1,
+		ItemsShipped: 500,
+		Overshoot:    249,
+	}
+	mockPackingService := &testdata.MockPackingService{
+		MultiLinePacking: expectedResponse,
+	}
+
+	handler := api.PackOrderLinesHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order-lines", bytes.NewReader([]byte(`{"lines": [{"sku": "SKU-1", "itemQty": 251}]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var result models.MultiLinePacking
+	_ = json.Unmarshal(rec.Body.Bytes(), &result)
+	if !reflect.DeepEqual(result, expectedResponse) {
+		t.Errorf("Expected result %+v, got %+v", expectedResponse, result)
+	}
+}
+
+func TestPackOrderShipmentsHandler_Success(t *testing.T) {
+	expectedResponse := models.ShipmentPlan{
+		PackingResult: models.PackingResult{
+			Packs:        []models.PackLine{{MaxItems: 500, Quantity: 3}},
+			RequestedQty: 1500,
+			ItemsShipped: 1500,
+			PackCount:    3,
+		},
+		ShipmentCount: 2,
+		Shipments: []models.Shipment{
+			{Quantity: 1, Packs: []models.PackLine{{MaxItems: 500, Quantity: 2}}, PackCount: 2, ItemsShipped: 1000},
+			{Quantity: 1, Packs: []models.PackLine{{MaxItems: 500, Quantity: 1}}, PackCount: 1, ItemsShipped: 500},
+		},
+	}
+	mockPackingService := &testdata.MockPackingService{
+		ShipmentPlan: expectedResponse,
+	}
+
+	handler := api.PackOrderShipmentsHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order-shipments", bytes.NewReader([]byte(`{"itemQty": 1500, "limits": {"maxPacks": 2}}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var result models.ShipmentPlan
+	_ = json.Unmarshal(rec.Body.Bytes(), &result)
+	if !reflect.DeepEqual(result, expectedResponse) {
+		t.Errorf("Expected result %+v, got %+v", expectedResponse, result)
+	}
+}
+
+func TestPackOrderShipmentsHandler_Errors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{name: "Invalid order", body: `{"itemQty": 0, "limits": {"maxPacks": 2}}`, expectedStatus: http.StatusBadRequest},
+		{name: "Negative limits", body: `{"itemQty": 1500, "limits": {"maxItems": -1}}`, expectedStatus: http.StatusBadRequest},
+		{
+			name:           "Shipment limits exceeded",
+			body:           `{"itemQty": 1500, "limits": {"maxItems": 100}}`,
+			serviceErr:     services.ErrShipmentLimitsExceeded,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Service error",
+			body:           `{"itemQty": 1500, "limits": {"maxPacks": 2}}`,
+			serviceErr:     errors.New("service error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := api.PackOrderShipmentsHandler(&testdata.MockPackingService{Error: tc.serviceErr})
+
+			// Create a new Echo context for testing
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/pack-order-shipments", bytes.NewReader([]byte(tc.body)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// Call the handler
+			err := handler(c)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestPackOrderBatchHandler(t *testing.T) {
+	packingResult := models.PackingResult{Packs: []models.PackLine{{MaxItems: 500, Quantity: 1}}, RequestedQty: 251, ItemsShipped: 500, PackCount: 1}
+	expectedResults := []models.BatchResult{
+		{Index: 0, Status: http.StatusOK, Result: &packingResult},
+		{Index: 1, Status: http.StatusBadRequest, Error: "order quantity must be greater than 0"},
+		{Index: 2, Status: http.StatusBadRequest, Error: "failed to decode order: json: cannot unmarshal string into Go struct field Order.itemQty of type int"},
+		{Index: 3, Status: http.StatusOK, Result: &packingResult},
+	}
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		decode      func(body []byte) []models.BatchResult
+	}{
+		{
+			name:        "JSON array",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `[{"itemQty": 251}, {"itemQty": 0}, {"itemQty": "many"}, {"itemQty": 251}]`,
+			decode: func(body []byte) []models.BatchResult {
+				var results []models.BatchResult
+				_ = json.Unmarshal(body, &results)
+				return results
+			},
+		},
+		{
+			name:        "NDJSON",
+			contentType: "application/x-ndjson",
+			body:        "{\"itemQty\": 251}\n{\"itemQty\": 0}\n{\"itemQty\": \"many\"}\n{\"itemQty\": 251}\n",
+			decode: func(body []byte) []models.BatchResult {
+				results := []models.BatchResult{}
+				for _, line := range bytes.Split(bytes.TrimSpace(body), []byte("\n")) {
+					var result models.BatchResult
+					_ = json.Unmarshal(line, &result)
+					results = append(results, result)
+				}
+				return results
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := api.PackOrderBatchHandler(&testdata.MockPackingService{PackingResult: packingResult})
+
+			// Create a new Echo context for testing
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/pack-order-batch", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// Call the handler
+			err := handler(c)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			if results := tc.decode(rec.Body.Bytes()); !reflect.DeepEqual(results, expectedResults) {
+				t.Errorf("Expected results %+v, got %+v", expectedResults, results)
+			}
+		})
+	}
+}
+
+func TestPackOrderBatchHandler_SyntaxError_StopsBatch(t *testing.T) {
+	handler := api.PackOrderBatchHandler(&testdata.MockPackingService{})
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order-batch", strings.NewReader(`[{"itemQty": 251}, {"itemQty": }, {"itemQty": 251}]`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	var results []models.BatchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Expected a JSON array, got %q", rec.Body.String())
+	}
+
+	if len(results) != 2 || results[1].Status != http.StatusBadRequest {
+		t.Errorf("Expected the batch to stop with an error at the second order, got %+v", results)
+	}
+}
+
+func TestPackOrderBatchHandler_NotAnArray_Error(t *testing.T) {
+	handler := api.PackOrderBatchHandler(&testdata.MockPackingService{})
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order-batch", strings.NewReader(`{"itemQty": 251}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestPackOrderBatchHandler_ClientGone_StopsPacking(t *testing.T) {
+	handler := api.PackOrderBatchHandler(&testdata.MockPackingService{})
+
+	// Create a new Echo context for testing, with the request of a client that already disconnected
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order-batch", strings.NewReader(`[{"itemQty": 251}, {"itemQty": 251}]`)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if strings.Contains(rec.Body.String(), "index") {
+		t.Errorf("Expected no results, got %q", rec.Body.String())
+	}
+}
+
+func TestPackOrderLinesHandler_LinesError(t *testing.T) {
+	expectedLines := []models.OrderLineError{{Line: 1, Error: "order quantity must be greater than 0"}}
+	mockPackingService := &testdata.MockPackingService{
+		Error: &services.OrderLinesError{Lines: expectedLines},
+	}
+
+	handler := api.PackOrderLinesHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order-lines", bytes.NewReader([]byte(`{"lines": [{"itemQty": 251}, {"itemQty": 0}]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	var response struct {
+		Lines []models.OrderLineError `json:"lines"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &response)
+	if !reflect.DeepEqual(response.Lines, expectedLines) {
+		t.Errorf("Expected line errors %+v, got %+v", expectedLines, response.Lines)
+	}
+}
+
+func TestPackOrderLinesHandler_ServiceError(t *testing.T) {
+	mockPackingService := &testdata.MockPackingService{
+		Error: errors.New("service error"),
+	}
+
+	handler := api.PackOrderLinesHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/pack-order-lines", bytes.NewReader([]byte(`{"lines": [{"itemQty": 251}]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestGetPackSizeVersionsHandler_Success(t *testing.T) {
+	expectedVersions := []models.PackSizeSetVersion{
+		{ID: 2, Author: "alice", PackSizes: []models.PackSize{{MaxItems: 500}}},
+		{ID: 1, PackSizes: []models.PackSize{{MaxItems: 250}}},
+	}
+	mockPackingService := &testdata.MockPackingService{Versions: expectedVersions}
+
+	handler := api.GetPackSizeVersionsHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/pack-sizes/versions", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var versions []models.PackSizeSetVersion
+	_ = json.Unmarshal(rec.Body.Bytes(), &versions)
+	if !reflect.DeepEqual(versions, expectedVersions) {
+		t.Errorf("Expected versions %+v, got %+v", expectedVersions, versions)
+	}
+}
+
+func TestGetPackSizeVersionHandler_Errors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		id             string
+		err            error
+		expectedStatus int
+	}{
+		{name: "Invalid version", id: "latest", expectedStatus: http.StatusBadRequest},
+		{name: "Unknown version", id: "3", expectedStatus: http.StatusNotFound},
+		{name: "No history", id: "1", err: services.ErrHistoryUnavailable, expectedStatus: http.StatusNotImplemented},
+		{name: "Service error", id: "1", err: errors.New("service error"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPackingService := &testdata.MockPackingService{
+				Error:    tc.err,
+				Versions: []models.PackSizeSetVersion{{ID: 1, PackSizes: []models.PackSize{{MaxItems: 250}}}},
+			}
+
+			handler := api.GetPackSizeVersionHandler(mockPackingService)
+
+			// Create a new Echo context for testing
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/pack-sizes/versions/"+tc.id, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tc.id)
+
+			// Call the handler
+			err := handler(c)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRollbackPackSizesHandler_Success(t *testing.T) {
+	mockPackingService := &testdata.MockPackingService{
+		Versions: []models.PackSizeSetVersion{
+			{ID: 2, PackSizes: []models.PackSize{{MaxItems: 500}}},
+			{ID: 1, PackSizes: []models.PackSize{{MaxItems: 250}}},
+		},
+	}
+
+	handler := api.RollbackPackSizesHandler(mockPackingService)
+
+	// Create a new Echo context for testing
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/products/SKU-1/pack-sizes/versions/1/rollback", nil)
+	req.Header.Set("X-Author", "alice")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("sku", "id")
+	c.SetParamValues("SKU-1", "1")
+
+	// Call the handler
+	err := handler(c)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	expectedVersion := models.PackSizeSetVersion{ID: 3, SKU: "SKU-1", Author: "alice", PackSizes: []models.PackSize{{MaxItems: 250}}, RestoredFrom: 1}
+	var version models.PackSizeSetVersion
+	_ = json.Unmarshal(rec.Body.Bytes(), &version)
+	if !reflect.DeepEqual(version, expectedVersion) {
+		t.Errorf("Expected version %+v, got %+v", expectedVersion, version)
+	}
+}
+
+func TestAdjustStockHandler(t *testing.T) {
+	testCases := []struct {
+		name         string
+		body         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{name: "Success", body: `[{"maxItems": 500, "delta": -2}]`, expectedCode: http.StatusNoContent},
+		{name: "Empty adjustments", body: `[]`, expectedCode: http.StatusBadRequest},
+		{name: "Insufficient stock", body: `[{"maxItems": 500, "delta": -2}]`, serviceErr: services.ErrInsufficientStock, expectedCode: http.StatusConflict},
+		{name: "Unknown pack size", body: `[{"maxItems": 42, "delta": 1}]`, serviceErr: services.ErrUnknownPackSize, expectedCode: http.StatusBadRequest},
+		{name: "Read-only pack sizes", body: `[{"maxItems": 500, "delta": -2}]`, serviceErr: services.ErrReadOnlyPackSizes, expectedCode: http.StatusNotImplemented},
+		{name: "Service error", body: `[{"maxItems": 500, "delta": 1}]`, serviceErr: errors.New("service error"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := api.AdjustStockHandler(&testdata.MockPackingService{Error: tc.serviceErr})
+
+			// Create a new Echo context for testing
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/pack-sizes/stock", bytes.NewReader([]byte(tc.body)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// Call the handler
+			err := handler(c)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if rec.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, rec.Code)
+			}
+		})
+	}
+}
+
+func TestSchedulePackSizesHandler(t *testing.T) {
+	testCases := []struct {
+		name         string
+		body         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{name: "Success", body: `{"effectiveFrom": "2030-01-01T00:00:00Z", "packSizes": [{"maxItems": 300}]}`, expectedCode: http.StatusCreated},
+		{name: "Empty pack sizes", body: `{"effectiveFrom": "2030-01-01T00:00:00Z", "packSizes": []}`, expectedCode: http.StatusBadRequest},
+		{name: "Invalid time", body: `{"effectiveFrom": "tomorrow", "packSizes": [{"maxItems": 300}]}`, expectedCode: http.StatusBadRequest},
+		{name: "Invalid pack sizes", body: `{"effectiveFrom": "2020-01-01T00:00:00Z", "packSizes": [{"maxItems": 300}]}`, serviceErr: &services.PackSizesError{Fields: []models.FieldError{{Field: "effectiveFrom", Message: "pack sizes must take effect after now"}}}, expectedCode: http.StatusUnprocessableEntity},
+		{name: "No schedule", body: `{"effectiveFrom": "2030-01-01T00:00:00Z", "packSizes": [{"maxItems": 300}]}`, serviceErr: services.ErrScheduleUnavailable, expectedCode: http.StatusNotImplemented},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := api.SchedulePackSizesHandler(&testdata.MockPackingService{Error: tc.serviceErr})
+
+			// Create a new Echo context for testing
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/products/SKU-1/pack-sizes/schedule", bytes.NewReader([]byte(tc.body)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Author", "alice")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("sku")
+			c.SetParamValues("SKU-1")
+
+			// Call the handler
+			err := handler(c)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if rec.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, rec.Code)
+			}
+
+			if tc.expectedCode != http.StatusCreated {
+				return
+			}
+
+			expectedScheduled := models.ScheduledPackSizes{
+				ID:            1,
+				SKU:           "SKU-1",
+				Author:        "alice",
+				EffectiveFrom: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+				PackSizes:     []models.PackSize{{MaxItems: 300}},
+			}
+			var scheduled models.ScheduledPackSizes
+			_ = json.Unmarshal(rec.Body.Bytes(), &scheduled)
+			if !reflect.DeepEqual(scheduled, expectedScheduled) {
+				t.Errorf("Expected scheduled pack sizes %+v, got %+v", expectedScheduled, scheduled)
+			}
+		})
+	}
+}
+
+func TestUnschedulePackSizesHandler(t *testing.T) {
+	testCases := []struct {
+		name         string
+		id           string
+		expectedCode int
+	}{
+		{name: "Success", id: "1", expectedCode: http.StatusNoContent},
+		{name: "Unknown", id: "2", expectedCode: http.StatusNotFound},
+		{name: "Invalid", id: "first", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := api.UnschedulePackSizesHandler(&testdata.MockPackingService{Scheduled: []models.ScheduledPackSizes{{ID: 1}}})
+
+			// Create a new Echo context for testing
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/pack-sizes/schedule/"+tc.id, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tc.id)
+
+			// Call the handler
+			err := handler(c)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if rec.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, rec.Code)
+			}
+		})
+	}
+}
+
+func TestPackOrderHandler_AsOf(t *testing.T) {
+	testCases := []struct {
+		name         string
+		query        string
+		body         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{name: "In the body", body: `{"itemQty": 10, "asOf": "2030-01-01T00:00:00Z"}`, expectedCode: http.StatusOK},
+		{name: "In the query", query: "?asOf=2030-01-01T00:00:00Z", body: `{"itemQty": 10}`, expectedCode: http.StatusOK},
+		{name: "Invalid query", query: "?asOf=tomorrow", body: `{"itemQty": 10}`, expectedCode: http.StatusBadRequest},
+		{name: "Reservation", body: `{"itemQty": 10, "reserve": true, "asOf": "2030-01-01T00:00:00Z"}`, serviceErr: services.ErrReserveAsOf, expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := api.PackOrderHandler(&testdata.MockPackingService{Error: tc.serviceErr})
+
+			// Create a new Echo context for testing
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/pack-order"+tc.query, bytes.NewReader([]byte(tc.body)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// Call the handler
+			err := handler(c)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if rec.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, rec.Code)
+			}
+		})
+	}
+}
+
+func TestPackSizeChangeHandlers(t *testing.T) {
+	mockPackingService := &testdata.MockPackingService{
+		PackSizes:        []models.PackSize{{MaxItems: 250}, {MaxItems: 500}},
+		ProductPackSizes: map[string][]models.PackSize{"SKU-1": {{MaxItems: 6}}},
+	}
+	invalidErr := &services.PackSizesError{Fields: []models.FieldError{{Field: "cost", Message: "pack cost cannot be negative"}}}
+
+	testCases := []struct {
+		name         string
+		handler      func(api.PackingService) func(echo.Context) error
+		method       string
+		sku          string
+		maxItems     string
+		body         string
+		serviceErr   error
+		added        models.PackSize
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "Add", handler: api.AddPackSizeHandler, method: http.MethodPost, body: `{"maxItems": 750, "cost": 2}`, added: models.PackSize{MaxItems: 750, Cost: 2}, expectedCode: http.StatusCreated, expectedBody: `{"maxItems":750,"cost":2}`},
+		{name: "Add responds with the saved pack size", handler: api.AddPackSizeHandler, method: http.MethodPost, body: `{"maxItems": 750, "cost": 2}`, added: models.PackSize{MaxItems: 750, Cost: 2.5}, expectedCode: http.StatusCreated, expectedBody: `{"maxItems":750,"cost":2.5}`},
+		{name: "Add product pack size", handler: api.AddPackSizeHandler, method: http.MethodPost, sku: "SKU-1", body: `{"maxItems": 250}`, added: models.PackSize{MaxItems: 250}, expectedCode: http.StatusCreated, expectedBody: `{"maxItems":250}`},
+		{name: "Add existing", handler: api.AddPackSizeHandler, method: http.MethodPost, body: `{"maxItems": 500}`, expectedCode: http.StatusConflict},
+		{name: "Add invalid", handler: api.AddPackSizeHandler, method: http.MethodPost, body: `{"maxItems": 750, "cost": -1}`, serviceErr: invalidErr, expectedCode: http.StatusUnprocessableEntity},
+		{name: "Patch", handler: api.PatchPackSizeHandler, method: http.MethodPatch, maxItems: "500", body: `{"cost": 3, "stock": 10}`, expectedCode: http.StatusOK, expectedBody: `{"maxItems":500,"cost":3,"stock":10}`},
+		{name: "Patch unknown", handler: api.PatchPackSizeHandler, method: http.MethodPatch, maxItems: "42", body: `{"cost": 3}`, expectedCode: http.StatusNotFound},
+		{name: "Patch invalid size", handler: api.PatchPackSizeHandler, method: http.MethodPatch, maxItems: "large", body: `{"cost": 3}`, expectedCode: http.StatusBadRequest},
+		{name: "Remove", handler: api.RemovePackSizeHandler, method: http.MethodDelete, maxItems: "250", expectedCode: http.StatusNoContent},
+		{name: "Remove unknown", handler: api.RemovePackSizeHandler, method: http.MethodDelete, sku: "SKU-1", maxItems: "250", expectedCode: http.StatusNotFound},
+		{name: "Service error", handler: api.RemovePackSizeHandler, method: http.MethodDelete, maxItems: "250", serviceErr: errors.New("service error"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := *mockPackingService
+			service.Error = tc.serviceErr
+			service.Added = tc.added
+			handler := tc.handler(service)
+
+			// Create a new Echo context for testing
+			e := echo.New()
+			req := httptest.NewRequest(tc.method, "/pack-sizes", bytes.NewReader([]byte(tc.body)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("sku", "maxItems")
+			c.SetParamValues(tc.sku, tc.maxItems)
+
+			// Call the handler
+			err := handler(c)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if rec.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, rec.Code)
+			}
+
+			if tc.expectedBody != "" && strings.TrimSpace(rec.Body.String()) != tc.expectedBody {
+				t.Errorf("Expected body %s, got %s", tc.expectedBody, rec.Body.String())
+			}
+		})
 	}
 }
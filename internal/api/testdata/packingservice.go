@@ -2,32 +2,208 @@ package testdata
 
 import (
 	"context"
+	"time"
 
 	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
 )
 
 type MockPackingService struct {
-	Error     error
-	PackSizes []models.PackSize
-	Packs     map[int]int
+	Error            error
+	PackSizes        []models.PackSize
+	ProductPackSizes map[string][]models.PackSize
+	PackingResult    models.PackingResult
+	MultiLinePacking models.MultiLinePacking
+	ShipmentPlan     models.ShipmentPlan
+	Versions         []models.PackSizeSetVersion
+	Scheduled        []models.ScheduledPackSizes
+	// Added is the pack size AddPackSize responds with as saved
+	Added models.PackSize
 }
 
-func (m MockPackingService) GetPackSizes(ctx context.Context) ([]models.PackSize, error) {
+func (m MockPackingService) GetPackSizes(ctx context.Context, sku string) ([]models.PackSize, error) {
 	if m.Error != nil {
 		return nil, m.Error
 	}
 
+	if packSizes, ok := m.ProductPackSizes[sku]; ok {
+		return packSizes, nil
+	}
+
 	return m.PackSizes, nil
 }
 
-func (m MockPackingService) CalculatePacks(context.Context, models.Order) (map[int]int, error) {
+func (m MockPackingService) CalculatePacks(ctx context.Context, order models.Order) (models.PackingResult, error) {
 	if m.Error != nil {
-		return nil, m.Error
+		return models.PackingResult{}, m.Error
+	}
+
+	result := m.PackingResult
+	if order.Explain {
+		result.Explanation = &models.PackingExplanation{Summary: "explained"}
+	}
+	if order.Alternatives > 0 {
+		result.Alternatives = make([]models.PackingCandidate, order.Alternatives)
 	}
 
-	return m.Packs, nil
+	return result, nil
 }
 
-func (m MockPackingService) UpdatePackSizes(ctx context.Context, packSizes []models.PackSize) error {
+func (m MockPackingService) UpdatePackSizesIfMatch(ctx context.Context, sku, version string, packSizes []models.PackSize) (string, error) {
+	if m.Error != nil {
+		return "", m.Error
+	}
+
+	current, err := m.GetPackSizes(ctx, sku)
+	if err != nil {
+		return "", err
+	}
+
+	if version != "" && version != models.PackSizesVersion(current) {
+		return "", services.ErrVersionConflict
+	}
+
+	return models.PackSizesVersion(packSizes), nil
+}
+
+func (m MockPackingService) CalculateOrderPacks(context.Context, models.MultiLineOrder) (models.MultiLinePacking, error) {
+	if m.Error != nil {
+		return models.MultiLinePacking{}, m.Error
+	}
+
+	return m.MultiLinePacking, nil
+}
+
+func (m MockPackingService) CalculateShipments(context.Context, models.ShipmentOrder) (models.ShipmentPlan, error) {
+	if m.Error != nil {
+		return models.ShipmentPlan{}, m.Error
+	}
+
+	return m.ShipmentPlan, nil
+}
+
+func (m MockPackingService) NewBatch() services.BatchFunc {
+	return m.CalculatePacks
+}
+
+func (m MockPackingService) AdjustStock(ctx context.Context, sku string, adjustments []models.StockAdjustment) error {
 	return m.Error
 }
+
+func (m MockPackingService) PackSizeVersions(ctx context.Context, sku string) ([]models.PackSizeSetVersion, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+
+	return m.Versions, nil
+}
+
+func (m MockPackingService) PackSizeVersion(ctx context.Context, sku string, id int) (models.PackSizeSetVersion, error) {
+	if m.Error != nil {
+		return models.PackSizeSetVersion{}, m.Error
+	}
+
+	for _, version := range m.Versions {
+		if version.ID == id {
+			return version, nil
+		}
+	}
+
+	return models.PackSizeSetVersion{}, services.ErrUnknownVersion
+}
+
+func (m MockPackingService) RollbackPackSizes(ctx context.Context, sku string, id int) (models.PackSizeSetVersion, error) {
+	version, err := m.PackSizeVersion(ctx, sku, id)
+	if err != nil {
+		return models.PackSizeSetVersion{}, err
+	}
+
+	return models.PackSizeSetVersion{
+		ID:           len(m.Versions) + 1,
+		SKU:          sku,
+		Author:       services.AuthorFromContext(ctx),
+		PackSizes:    version.PackSizes,
+		RestoredFrom: id,
+	}, nil
+}
+
+func (m MockPackingService) SchedulePackSizes(ctx context.Context, sku string, effectiveFrom time.Time, packSizes []models.PackSize) (models.ScheduledPackSizes, error) {
+	if m.Error != nil {
+		return models.ScheduledPackSizes{}, m.Error
+	}
+
+	return models.ScheduledPackSizes{
+		ID:            len(m.Scheduled) + 1,
+		SKU:           sku,
+		Author:        services.AuthorFromContext(ctx),
+		EffectiveFrom: effectiveFrom,
+		PackSizes:     packSizes,
+	}, nil
+}
+
+func (m MockPackingService) ScheduledPackSizes(ctx context.Context, sku string) ([]models.ScheduledPackSizes, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+
+	return m.Scheduled, nil
+}
+
+func (m MockPackingService) UnschedulePackSizes(ctx context.Context, sku string, id int) error {
+	if m.Error != nil {
+		return m.Error
+	}
+
+	for _, scheduled := range m.Scheduled {
+		if scheduled.ID == id {
+			return nil
+		}
+	}
+
+	return services.ErrUnknownSchedule
+}
+
+func (m MockPackingService) AddPackSize(ctx context.Context, sku string, packSize models.PackSize) (models.PackSize, error) {
+	current, err := m.GetPackSizes(ctx, sku)
+	if err != nil {
+		return models.PackSize{}, err
+	}
+
+	for _, other := range current {
+		if other.MaxItems == packSize.MaxItems {
+			return models.PackSize{}, services.ErrPackSizeExists
+		}
+	}
+
+	return m.Added, nil
+}
+
+func (m MockPackingService) RemovePackSize(ctx context.Context, sku string, maxItems int) error {
+	_, err := m.packSize(ctx, sku, maxItems)
+
+	return err
+}
+
+func (m MockPackingService) PatchPackSize(ctx context.Context, sku string, maxItems int, patch models.PackSizePatch) (models.PackSize, error) {
+	packSize, err := m.packSize(ctx, sku, maxItems)
+	if err != nil {
+		return models.PackSize{}, err
+	}
+
+	return patch.Apply(packSize), nil
+}
+
+func (m MockPackingService) packSize(ctx context.Context, sku string, maxItems int) (models.PackSize, error) {
+	current, err := m.GetPackSizes(ctx, sku)
+	if err != nil {
+		return models.PackSize{}, err
+	}
+
+	for _, packSize := range current {
+		if packSize.MaxItems == maxItems {
+			return packSize, nil
+		}
+	}
+
+	return models.PackSize{}, services.ErrUnknownPackSize
+}
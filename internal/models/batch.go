@@ -0,0 +1,13 @@
+package models
+
+// BatchResult is the result of a single order of a batch, either a packing result or an error
+type BatchResult struct {
+	// Index is the position of the order in the batch, starting at 0
+	Index int `json:"index"`
+	// Status is the status code packing the order on its own would have been answered with
+	Status int `json:"status"`
+	// Result is the packing result of the order, only set when it was packed
+	Result *PackingResult `json:"result,omitempty"`
+	// Error is why the order could not be packed
+	Error string `json:"error,omitempty"`
+}
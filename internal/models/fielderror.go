@@ -0,0 +1,9 @@
+package models
+
+// FieldError describes why a single field of a request is invalid
+type FieldError struct {
+	// Field is the path of the field in the request, e.g. "packSizes[1].maxItems"
+	Field string `json:"field"`
+	// Message is the reason the field is invalid
+	Message string `json:"message"`
+}
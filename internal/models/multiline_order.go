@@ -0,0 +1,58 @@
+package models
+
+// OrderLine represents a single line of a multi-line order
+type OrderLine struct {
+	// SKU identifies the product being ordered, the default pack sizes are used when empty
+	SKU string `json:"sku,omitempty"`
+	// ItemQty is the number of items ordered on this line
+	ItemQty int `json:"itemQty"`
+	// ItemWeight is the weight of a single item of the line
+	ItemWeight float64 `json:"itemWeight,omitempty"`
+	// ItemVolume is the volume of a single item of the line
+	ItemVolume float64 `json:"itemVolume,omitempty"`
+}
+
+// MultiLineOrder represents an order made of several lines that are packed together
+type MultiLineOrder struct {
+	// Lines are the lines of the order
+	Lines []OrderLine `json:"lines"`
+	// Objective is the packing objective used for every line, defaults to ObjectiveFewestItems
+	Objective Objective `json:"objective,omitempty"`
+	// Policy is the packing policy used for every line, the global policy is used when nil
+	Policy *PackingPolicy `json:"policy,omitempty"`
+	// Hierarchy are the packaging levels the packs of every line are packed into, the global hierarchy is used when nil
+	Hierarchy PackagingHierarchy `json:"hierarchy,omitempty"`
+}
+
+// LinePacking is the packing result of a single order line
+type LinePacking struct {
+	// SKU is the SKU of the line
+	SKU string `json:"sku,omitempty"`
+	PackingResult
+}
+
+// MultiLinePacking is the packing result of a multi-line order
+type MultiLinePacking struct {
+	// Lines are the packing results of every order line, in order
+	Lines []LinePacking `json:"lines"`
+	// PackCount is the total number of packs across all lines
+	PackCount int `json:"packCount"`
+	// ItemsShipped is the total number of items shipped across all lines
+	ItemsShipped int `json:"itemsShipped"`
+	// Overshoot is the total number of items shipped above the ordered quantities
+	Overshoot int `json:"overshoot"`
+	// Underfill is the total number of items left out of the ordered quantities
+	Underfill int `json:"underfill,omitempty"`
+	// TotalCost is the total cost of the packs across all lines
+	TotalCost float64 `json:"totalCost,omitempty"`
+}
+
+// OrderLineError describes why a single order line cannot be packed
+type OrderLineError struct {
+	// Line is the zero-based index of the line in the order
+	Line int `json:"line"`
+	// SKU is the SKU of the line
+	SKU string `json:"sku,omitempty"`
+	// Error is the reason the line cannot be packed
+	Error string `json:"error"`
+}
@@ -1,7 +1,51 @@
 package models
 
+import "time"
+
+// Objective is the goal the packing calculation optimises for
+type Objective string
+
+const (
+	// ObjectiveFewestItems minimises the number of items shipped, then the number of packs, then the cost
+	ObjectiveFewestItems Objective = "fewest-items"
+	// ObjectiveFewestPacks minimises the number of packs, then the number of items shipped, then the cost
+	ObjectiveFewestPacks Objective = "fewest-packs"
+	// ObjectiveLowestCost minimises the total cost, then the number of items shipped, then the number of packs
+	ObjectiveLowestCost Objective = "lowest-cost"
+)
+
+// IsValid reports whether the objective is known (an empty objective means the default)
+func (o Objective) IsValid() bool {
+	switch o {
+	case "", ObjectiveFewestItems, ObjectiveFewestPacks, ObjectiveLowestCost:
+		return true
+	default:
+		return false
+	}
+}
+
 // Order represents an order
 type Order struct {
 	// ItemQty is the number of items the user wants to order
 	ItemQty int `json:"itemQty" form:"itemQty"`
+	// SKU identifies the product being ordered, the default pack sizes are used when empty
+	SKU string `json:"sku,omitempty" form:"sku"`
+	// Objective is the packing objective, defaults to ObjectiveFewestItems
+	Objective Objective `json:"objective,omitempty" form:"objective"`
+	// Reserve confirms the order, taking the packs it uses out of the available stock
+	Reserve bool `json:"reserve,omitempty" form:"reserve"`
+	// Explain asks for an explanation of why the packs were chosen
+	Explain bool `json:"explain,omitempty" form:"explain"`
+	// Alternatives is the number of best packings to rank, none when 0
+	Alternatives int `json:"alternatives,omitempty" form:"alternatives"`
+	// ItemWeight is the weight of a single item, checked against the weight limits of the packs
+	ItemWeight float64 `json:"itemWeight,omitempty" form:"itemWeight"`
+	// ItemVolume is the volume of a single item, checked against the volume limits of the packs
+	ItemVolume float64 `json:"itemVolume,omitempty" form:"itemVolume"`
+	// Policy limits how far the items shipped may be from the ordered quantity, the global policy is used when nil
+	Policy *PackingPolicy `json:"policy,omitempty"`
+	// Hierarchy are the packaging levels the packs are packed into, the global hierarchy is used when nil
+	Hierarchy PackagingHierarchy `json:"hierarchy,omitempty"`
+	// AsOf is when the order is packed, quoting it with the pack sizes scheduled to be in effect then, now when nil
+	AsOf *time.Time `json:"asOf,omitempty"`
 }
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PackagingLevel is a level of the packaging hierarchy above the packs, such as cartons or pallets
+type PackagingLevel struct {
+	// Name names the units of the level
+	Name string `json:"name"`
+	// Holds is the number of units of the level below (packs for the first level) a unit of this level holds
+	Holds int `json:"holds"`
+}
+
+// PackagingHierarchy are the levels packs are packed into, from the lowest level to the top level
+type PackagingHierarchy []PackagingLevel
+
+// IsValid reports whether every level of the hierarchy is named and holds at least one unit of the level below
+func (h PackagingHierarchy) IsValid() bool {
+	for _, level := range h {
+		if level.Name == "" || level.Holds < 1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParsePackagingHierarchy parses comma-separated "name:holds" levels from the lowest to the top level,
+// e.g. "carton:4,pallet:20"
+func ParsePackagingHierarchy(value string) (PackagingHierarchy, error) {
+	hierarchy := PackagingHierarchy{}
+	for _, level := range strings.Split(value, ",") {
+		name, holds, ok := strings.Cut(strings.TrimSpace(level), ":")
+		if !ok {
+			return nil, fmt.Errorf("failed to parse packaging level: %q", level)
+		}
+
+		holdsInt, err := strconv.Atoi(holds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse packaging level: %w", err)
+		}
+
+		hierarchy = append(hierarchy, PackagingLevel{Name: name, Holds: holdsInt})
+	}
+
+	return hierarchy, nil
+}
+
+// PackagingUnit is a group of identical units of a packaging level together with what each of them holds
+type PackagingUnit struct {
+	// Level is the name of the level the units belong to, "pack" for the packs themselves
+	Level string `json:"level"`
+	// Quantity is the number of identical units
+	Quantity int `json:"quantity"`
+	// Items is the number of items a single unit holds
+	Items int `json:"items"`
+	// Partial reports whether the units hold fewer units of the level below than the level allows
+	Partial bool `json:"partial,omitempty"`
+	// Contents are the units of the level below a single unit holds, empty for packs
+	Contents []PackagingUnit `json:"contents,omitempty"`
+}
+
+// PackagingLevelCount is the number of units a packaging plan uses on a level
+type PackagingLevelCount struct {
+	// Level is the name of the level
+	Level string `json:"level"`
+	// Count is the number of units on the level
+	Count int `json:"count"`
+	// Partial is the number of those units that are only partially filled
+	Partial int `json:"partial,omitempty"`
+}
+
+// PackagingPlan is how the packs of an order are packed into the levels of the packaging hierarchy
+type PackagingPlan struct {
+	// Levels counts the units of every level, from the packs up to the top level
+	Levels []PackagingLevelCount `json:"levels"`
+	// Units are the units of the top level
+	Units []PackagingUnit `json:"units"`
+}
@@ -0,0 +1,87 @@
+package models
+
+// PackLine is the number of packs of a single size used by a packing
+type PackLine struct {
+	// MaxItems is the size of the pack
+	MaxItems int `json:"maxItems"`
+	// Quantity is the number of packs of this size
+	Quantity int `json:"quantity"`
+}
+
+// PackingResult is the result of packing an order
+type PackingResult struct {
+	// Packs are the packs used, ordered from the largest pack size to the smallest
+	Packs []PackLine `json:"packs"`
+	// RequestedQty is the number of items ordered
+	RequestedQty int `json:"requestedQty"`
+	// ItemsShipped is the total number of items the packs hold
+	ItemsShipped int `json:"itemsShipped"`
+	// Overshoot is the number of items shipped above the ordered quantity
+	Overshoot int `json:"overshoot"`
+	// Underfill is the number of items left out of the ordered quantity, only allowed by a packing policy
+	Underfill int `json:"underfill,omitempty"`
+	// PackCount is the total number of packs
+	PackCount int `json:"packCount"`
+	// TotalCost is the total cost of the packs
+	TotalCost float64 `json:"totalCost,omitempty"`
+	// PolicyViolated reports whether the packing overshoots the limit of the packing policy,
+	// which only happens when the policy packs orders it cannot satisfy on a best-effort basis
+	PolicyViolated bool `json:"policyViolated,omitempty"`
+	// PackSizesVersion is the version of the pack sizes the order was packed with
+	PackSizesVersion string `json:"packSizesVersion"`
+	// Explanation describes why the packing was chosen, only set when an explanation was requested
+	Explanation *PackingExplanation `json:"explanation,omitempty"`
+	// Alternatives are the best packings ranked by the objective, starting with the chosen one,
+	// only set when alternatives were requested
+	Alternatives []PackingCandidate `json:"alternatives,omitempty"`
+	// Packaging is how the packs are packed into the levels of the packaging hierarchy,
+	// only set when there is a hierarchy
+	Packaging *PackagingPlan `json:"packaging,omitempty"`
+}
+
+// PackingCandidate is a packing that was considered for an order
+type PackingCandidate struct {
+	// ItemsShipped is the total number of items the packs hold
+	ItemsShipped int `json:"itemsShipped"`
+	// PackCount is the total number of packs
+	PackCount int `json:"packCount"`
+	// TotalCost is the total cost of the packs
+	TotalCost float64 `json:"totalCost,omitempty"`
+	// Packs are the packs used, ordered from the largest pack size to the smallest
+	Packs []PackLine `json:"packs,omitempty"`
+}
+
+// PackingExplanation describes why a packing was chosen for an order
+type PackingExplanation struct {
+	// Objective is the objective the packing was optimised for
+	Objective Objective `json:"objective"`
+	// ExactMatch reports whether the ordered quantity could be packed without overshooting
+	ExactMatch bool `json:"exactMatch"`
+	// Reason explains why an exact match was or was not possible
+	Reason string `json:"reason"`
+	// PrefilledPacks are the packs filled up front for large orders, which every candidate contains
+	PrefilledPacks *PackLine `json:"prefilledPacks,omitempty"`
+	// Candidates are the best packing of every total that was considered, ordered by the total
+	Candidates []PackingCandidate `json:"candidates"`
+	// RunnersUp are the best packings that were not chosen, from the best to the worst
+	RunnersUp []PackingCandidate `json:"runnersUp"`
+	// DecidingRule is the rule that ranked the chosen packing above the first runner-up,
+	// empty when there is no runner-up or the two are tied
+	DecidingRule Objective `json:"decidingRule,omitempty"`
+	// Summary summarises the decision
+	Summary string `json:"summary"`
+}
+
+// PackMap returns the packs as a map of pack size to the number of packs of that size
+func (r PackingResult) PackMap() map[int]int {
+	if r.Packs == nil {
+		return nil
+	}
+
+	packs := make(map[int]int, len(r.Packs))
+	for _, line := range r.Packs {
+		packs[line.MaxItems] = line.Quantity
+	}
+
+	return packs
+}
@@ -1,7 +1,123 @@
 package models
 
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+)
+
 // PackSize represents a pack size
 type PackSize struct {
 	// MaxItems is the number of items that can fit in the pack
 	MaxItems int `json:"maxItems"`
+	// Cost is the price of shipping a single pack, used when optimising for the lowest cost
+	Cost float64 `json:"cost,omitempty"`
+	// Stock is the number of packs available, the supply is unlimited when nil
+	Stock *int `json:"stock,omitempty"`
+	// MaxWeight is the most the items in a full pack may weigh, unlimited when 0
+	MaxWeight float64 `json:"maxWeight,omitempty"`
+	// MaxVolume is the most space the items in a full pack may take up, unlimited when 0
+	MaxVolume float64 `json:"maxVolume,omitempty"`
+}
+
+// Equal reports whether both pack sizes have the same size, cost, stock and limits
+func (p PackSize) Equal(other PackSize) bool {
+	sameStock := (p.Stock == nil && other.Stock == nil) || (p.Stock != nil && other.Stock != nil && *p.Stock == *other.Stock)
+
+	return sameStock && p.MaxItems == other.MaxItems && p.Cost == other.Cost && p.MaxWeight == other.MaxWeight && p.MaxVolume == other.MaxVolume
+}
+
+// Holds reports whether a full pack of items with the given weight and volume stays within the limits of the pack
+func (p PackSize) Holds(itemWeight, itemVolume float64) bool {
+	// The limits get a rounding margin so a pack filled exactly to its limit is allowed
+	within := func(perItem, limit float64) bool {
+		return limit == 0 || float64(p.MaxItems)*perItem <= limit*(1+1e-9)
+	}
+
+	return within(itemWeight, p.MaxWeight) && within(itemVolume, p.MaxVolume)
+}
+
+// PackSizePatch changes some of the attributes of a pack size, attributes that are nil are left unchanged
+type PackSizePatch struct {
+	// Cost is the new cost of the pack
+	Cost *float64 `json:"cost,omitempty"`
+	// Stock is the new number of packs available
+	Stock *int `json:"stock,omitempty"`
+	// UnlimitedStock makes the supply of the pack unlimited, Stock must be nil
+	UnlimitedStock bool `json:"unlimitedStock,omitempty"`
+	// MaxWeight is the new weight limit of the pack
+	MaxWeight *float64 `json:"maxWeight,omitempty"`
+	// MaxVolume is the new volume limit of the pack
+	MaxVolume *float64 `json:"maxVolume,omitempty"`
+}
+
+// Apply returns a copy of the pack size with the changes of the patch
+func (p PackSizePatch) Apply(packSize PackSize) PackSize {
+	if p.Cost != nil {
+		packSize.Cost = *p.Cost
+	}
+
+	if p.Stock != nil {
+		stock := *p.Stock
+		packSize.Stock = &stock
+	} else if p.UnlimitedStock {
+		packSize.Stock = nil
+	}
+
+	if p.MaxWeight != nil {
+		packSize.MaxWeight = *p.MaxWeight
+	}
+
+	if p.MaxVolume != nil {
+		packSize.MaxVolume = *p.MaxVolume
+	}
+
+	return packSize
+}
+
+// StockAdjustment changes the available stock of a pack size
+type StockAdjustment struct {
+	// MaxItems identifies the pack size to adjust
+	MaxItems int `json:"maxItems"`
+	// Delta is the number of packs added to (or removed from, when negative) the stock
+	Delta int `json:"delta"`
+}
+
+// PackSizesVersion returns a version identifier derived from the contents of the pack sizes.
+// The order of the pack sizes does not affect the version.
+func PackSizesVersion(packSizes []PackSize) string {
+	// Pack sizes are ordered by size and then by their encoding, so pack sizes of the same size that differ
+	// in any attribute always come in the same order. Marshalling plain pack sizes cannot fail.
+	type encodedPackSize struct {
+		maxItems int
+		data     []byte
+	}
+	encoded := make([]encodedPackSize, len(packSizes))
+	for i, packSize := range packSizes {
+		data, _ := json.Marshal(packSize)
+		encoded[i] = encodedPackSize{maxItems: packSize.MaxItems, data: data}
+	}
+	slices.SortFunc(encoded, func(a, b encodedPackSize) int {
+		if a.maxItems != b.maxItems {
+			return cmp.Compare(a.maxItems, b.maxItems)
+		}
+
+		return bytes.Compare(a.data, b.data)
+	})
+
+	// The encoded pack sizes are hashed as a JSON array, like the pack sizes are stored
+	data := []byte{'['}
+	for i, packSize := range encoded {
+		if i > 0 {
+			data = append(data, ',')
+		}
+		data = append(data, packSize.data...)
+	}
+	data = append(data, ']')
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:8])
 }
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// PackSizeChangeType describes how a pack size changed between two versions of a pack size set
+type PackSizeChangeType string
+
+const (
+	// PackSizeAdded is a pack size that was not in the previous version
+	PackSizeAdded PackSizeChangeType = "added"
+	// PackSizeRemoved is a pack size that is no longer in the version
+	PackSizeRemoved PackSizeChangeType = "removed"
+	// PackSizeChanged is a pack size whose cost, stock or limits changed
+	PackSizeChanged PackSizeChangeType = "changed"
+)
+
+// PackSizeChange describes how a single pack size changed
+type PackSizeChange struct {
+	// MaxItems identifies the pack size
+	MaxItems int `json:"maxItems"`
+	// Type is how the pack size changed
+	Type PackSizeChangeType `json:"type"`
+	// Before is the pack size before the change, nil when it was added
+	Before *PackSize `json:"before,omitempty"`
+	// After is the pack size after the change, nil when it was removed
+	After *PackSize `json:"after,omitempty"`
+}
+
+// PackSizeSetVersion is a saved version of the pack sizes of a SKU
+type PackSizeSetVersion struct {
+	// ID identifies the version among the versions of the SKU, later versions have larger IDs
+	ID int `json:"id"`
+	// SKU is the SKU the pack sizes are for, empty for the default pack sizes
+	SKU string `json:"sku,omitempty"`
+	// Author is who saved the version, empty when unknown
+	Author string `json:"author,omitempty"`
+	// CreatedAt is when the version was saved
+	CreatedAt time.Time `json:"createdAt"`
+	// PackSizes are the pack sizes of the version
+	PackSizes []PackSize `json:"packSizes"`
+	// Changes describes what changed compared to the pack sizes the version replaced
+	Changes []PackSizeChange `json:"changes"`
+	// RestoredFrom is the ID of the version the pack sizes were rolled back to, 0 when they were not
+	RestoredFrom int `json:"restoredFrom,omitempty"`
+}
+
+// DiffPackSizes returns the changes turning the before pack sizes into the after pack sizes, ordered by size.
+// Only the first pack of a repeated size is compared.
+func DiffPackSizes(before, after []PackSize) []PackSizeChange {
+	bySize := func(packSizes []PackSize) map[int]PackSize {
+		sizes := make(map[int]PackSize, len(packSizes))
+		for _, packSize := range packSizes {
+			if _, ok := sizes[packSize.MaxItems]; !ok {
+				sizes[packSize.MaxItems] = packSize
+			}
+		}
+
+		return sizes
+	}
+	beforeSizes, afterSizes := bySize(before), bySize(after)
+
+	changes := []PackSizeChange{}
+	for size, beforeSize := range beforeSizes {
+		beforeSize := beforeSize
+		afterSize, ok := afterSizes[size]
+		switch {
+		case !ok:
+			changes = append(changes, PackSizeChange{MaxItems: size, Type: PackSizeRemoved, Before: &beforeSize})
+		case !beforeSize.Equal(afterSize):
+			changes = append(changes, PackSizeChange{MaxItems: size, Type: PackSizeChanged, Before: &beforeSize, After: &afterSize})
+		}
+	}
+
+	for size, afterSize := range afterSizes {
+		afterSize := afterSize
+		if _, ok := beforeSizes[size]; !ok {
+			changes = append(changes, PackSizeChange{MaxItems: size, Type: PackSizeAdded, After: &afterSize})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b PackSizeChange) int {
+		return a.MaxItems - b.MaxItems
+	})
+
+	return changes
+}
@@ -0,0 +1,49 @@
+package models
+
+// PolicyMode decides what happens to an order that cannot be packed within its packing policy
+type PolicyMode string
+
+const (
+	// PolicyModeReject rejects orders that cannot be packed within the policy
+	PolicyModeReject PolicyMode = "reject"
+	// PolicyModeBestEffort packs orders that cannot be packed within the policy as if overshooting was not limited
+	PolicyModeBestEffort PolicyMode = "best-effort"
+)
+
+// PackingPolicy limits how far the items shipped may be from the ordered quantity.
+// When both an absolute and a percentage limit are set, the packing has to stay within both.
+type PackingPolicy struct {
+	// MaxOvershoot is the most items that may be shipped above the ordered quantity, not limited when nil
+	MaxOvershoot *int `json:"maxOvershoot,omitempty"`
+	// MaxOvershootPercent is the most items that may be shipped above the ordered quantity as a percentage of it,
+	// not limited when nil
+	MaxOvershootPercent *float64 `json:"maxOvershootPercent,omitempty"`
+	// MaxUnderfill is the most items that may be left out of the ordered quantity
+	MaxUnderfill *int `json:"maxUnderfill,omitempty"`
+	// MaxUnderfillPercent is the most items that may be left out of the ordered quantity as a percentage of it
+	MaxUnderfillPercent *float64 `json:"maxUnderfillPercent,omitempty"`
+	// Mode decides what happens when the order cannot be packed within the policy, defaults to PolicyModeReject
+	Mode PolicyMode `json:"mode,omitempty"`
+}
+
+// IsValid reports whether every limit of the policy is non-negative and its mode is known
+func (p PackingPolicy) IsValid() bool {
+	for _, limit := range []*int{p.MaxOvershoot, p.MaxUnderfill} {
+		if limit != nil && *limit < 0 {
+			return false
+		}
+	}
+
+	for _, limit := range []*float64{p.MaxOvershootPercent, p.MaxUnderfillPercent} {
+		if limit != nil && *limit < 0 {
+			return false
+		}
+	}
+
+	switch p.Mode {
+	case "", PolicyModeReject, PolicyModeBestEffort:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ScheduledPackSizes are pack sizes of a SKU that replace its pack sizes at a later time
+type ScheduledPackSizes struct {
+	// ID identifies the scheduled pack sizes among the scheduled pack sizes of the SKU
+	ID int `json:"id"`
+	// SKU is the SKU the pack sizes are for, empty for the default pack sizes
+	SKU string `json:"sku,omitempty"`
+	// Author is who scheduled the pack sizes, empty when unknown
+	Author string `json:"author,omitempty"`
+	// EffectiveFrom is when the pack sizes take effect
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	// PackSizes are the pack sizes taking effect
+	PackSizes []PackSize `json:"packSizes"`
+}
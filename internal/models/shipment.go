@@ -0,0 +1,42 @@
+package models
+
+// ShipmentLimits are the most a single shipment may hold, a limit of 0 means no limit
+type ShipmentLimits struct {
+	// MaxPacks is the most packs a shipment may hold
+	MaxPacks int `json:"maxPacks,omitempty" form:"maxPacks"`
+	// MaxItems is the most items a shipment may hold
+	MaxItems int `json:"maxItems,omitempty" form:"maxItems"`
+}
+
+// IsValid reports whether the limits are non-negative
+func (l ShipmentLimits) IsValid() bool {
+	return l.MaxPacks >= 0 && l.MaxItems >= 0
+}
+
+// ShipmentOrder is an order that is split into shipments within the limits
+type ShipmentOrder struct {
+	Order
+	// Limits are the limits of every shipment
+	Limits ShipmentLimits `json:"limits"`
+}
+
+// Shipment is a group of identical shipments of a split order
+type Shipment struct {
+	// Quantity is the number of identical shipments
+	Quantity int `json:"quantity"`
+	// Packs are the packs of a single shipment, ordered from the largest pack size to the smallest
+	Packs []PackLine `json:"packs"`
+	// PackCount is the number of packs of a single shipment
+	PackCount int `json:"packCount"`
+	// ItemsShipped is the number of items a single shipment holds
+	ItemsShipped int `json:"itemsShipped"`
+}
+
+// ShipmentPlan is the packing result of an order split into shipments
+type ShipmentPlan struct {
+	PackingResult
+	// ShipmentCount is the total number of shipments
+	ShipmentCount int `json:"shipmentCount"`
+	// Shipments are the shipments, the fullest ones first
+	Shipments []Shipment `json:"shipments"`
+}
@@ -0,0 +1,463 @@
+package providers
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// metaBucket holds the schema version of the database
+	metaBucket = []byte("meta")
+	// packSizesBucket holds the pack sizes of every SKU, keyed by skuKey
+	packSizesBucket = []byte("packSizes")
+	// historyBucket holds a bucket of the versions of the pack sizes of every SKU keyed by skuKey,
+	// the versions are keyed by their ID
+	historyBucket = []byte("history")
+	// scheduleBucket holds a bucket of the scheduled pack sizes of every SKU keyed by skuKey, the scheduled
+	// pack sizes are keyed by their ID, which is taken from the sequence of the schedule bucket
+	scheduleBucket = []byte("schedule")
+
+	// schemaVersionKey is the key of the schema version in the meta bucket
+	schemaVersionKey = []byte("schemaVersion")
+)
+
+// boltMigrations are the changes bringing the database schema from one version to the next,
+// the database has schema version i+1 once the migration at index i has run
+var boltMigrations = []func(tx *bolt.Tx) error{
+	// Version 1 has the pack sizes, their history and their schedule
+	func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{packSizesBucket, historyBucket, scheduleBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+// BoltPackSizeProvider is a PackSizeProvider that stores pack sizes, their history and their schedule in an
+// embedded bbolt database. Every change is made in a single transaction.
+type BoltPackSizeProvider struct {
+	db *bolt.DB
+}
+
+// NewBoltPackSizeProvider opens the database at the specified file path, creating it when it does not exist,
+// and migrates it to the current schema. The database can only be opened by one process at a time.
+func NewBoltPackSizeProvider(filePath string) (*BoltPackSizeProvider, error) {
+	db, err := bolt.Open(filePath, 0o644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltPackSizeProvider{db: db}, nil
+}
+
+// Close closes the database
+func (p *BoltPackSizeProvider) Close() error {
+	return p.db.Close()
+}
+
+// migrate runs the migrations the database has not had yet in a single transaction, so the database is left
+// unchanged when one of them fails
+func migrate(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+
+		version := 0
+		if data := meta.Get(schemaVersionKey); data != nil {
+			version = int(binary.BigEndian.Uint64(data))
+		}
+
+		if version > len(boltMigrations) {
+			return fmt.Errorf("unsupported database schema version %d", version)
+		}
+
+		for ; version < len(boltMigrations); version++ {
+			if err := boltMigrations[version](tx); err != nil {
+				return fmt.Errorf("failed to migrate database to schema version %d: %w", version+1, err)
+			}
+		}
+
+		return meta.Put(schemaVersionKey, itob(version))
+	})
+}
+
+// ImportFile imports the pack sizes, history and schedule of a pack sizes file in any of the formats of the
+// FilePackSizeProvider into the database. The file is only imported into a database without pack sizes, so
+// importing it at every start only imports it once. It reports whether the file was imported, a missing file
+// is not imported.
+func (p *BoltPackSizeProvider) ImportFile(filePath string) (bool, error) {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	file, err := packSizeFileFormatFor(filePath).unmarshal(data)
+	if err != nil {
+		return false, err
+	}
+
+	imported := false
+	err = p.db.Update(func(tx *bolt.Tx) error {
+		if key, _ := tx.Bucket(packSizesBucket).Cursor().First(); key != nil {
+			return nil
+		}
+
+		if err := putPackSizes(tx, "", file.PackSizes); err != nil {
+			return err
+		}
+		for sku, packSizes := range file.Products {
+			if err := putPackSizes(tx, sku, packSizes); err != nil {
+				return err
+			}
+		}
+
+		for sku, versions := range file.History {
+			bucket, err := tx.Bucket(historyBucket).CreateBucketIfNotExists(skuKey(sku))
+			if err != nil {
+				return fmt.Errorf("failed to import history: %w", err)
+			}
+
+			for _, version := range versions {
+				if err := putJSON(bucket, itob(version.ID), version); err != nil {
+					return err
+				}
+				if uint64(version.ID) > bucket.Sequence() {
+					if err := bucket.SetSequence(uint64(version.ID)); err != nil {
+						return fmt.Errorf("failed to import history: %w", err)
+					}
+				}
+			}
+		}
+
+		schedule := tx.Bucket(scheduleBucket)
+		lastScheduleID := uint64(file.LastScheduleID)
+		for sku, scheduled := range file.Schedule {
+			bucket, err := schedule.CreateBucketIfNotExists(skuKey(sku))
+			if err != nil {
+				return fmt.Errorf("failed to import schedule: %w", err)
+			}
+
+			for _, s := range scheduled {
+				if err := putJSON(bucket, itob(s.ID), s); err != nil {
+					return err
+				}
+				lastScheduleID = max(lastScheduleID, uint64(s.ID))
+			}
+		}
+
+		imported = true
+
+		return schedule.SetSequence(lastScheduleID)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return imported, nil
+}
+
+// skuKey returns the key the records of the specified SKU (the default pack sizes when empty) are stored under
+func skuKey(sku string) []byte {
+	return []byte("sku:" + sku)
+}
+
+// itob returns the key of an ID, IDs are stored big-endian so they are ordered by value
+func itob(id int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+
+	return b
+}
+
+// putJSON stores the value in the bucket as JSON
+func putJSON(bucket *bolt.Bucket, key []byte, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	return bucket.Put(key, data)
+}
+
+// getPackSizes returns the pack sizes used for the specified SKU and the SKU they are stored under,
+// which is empty when the SKU uses the default pack sizes
+func getPackSizes(tx *bolt.Tx, sku string) ([]models.PackSize, string, error) {
+	bucket := tx.Bucket(packSizesBucket)
+
+	data := bucket.Get(skuKey(sku))
+	if data == nil {
+		sku = ""
+		data = bucket.Get(skuKey(sku))
+	}
+
+	packSizes := []models.PackSize{}
+	if data == nil {
+		return packSizes, sku, nil
+	}
+
+	if err := json.Unmarshal(data, &packSizes); err != nil {
+		return nil, sku, fmt.Errorf("failed to unmarshal pack sizes: %w", err)
+	}
+
+	return packSizes, sku, nil
+}
+
+// putPackSizes sets the pack sizes for the specified SKU (the default pack sizes when empty)
+func putPackSizes(tx *bolt.Tx, sku string, packSizes []models.PackSize) error {
+	if packSizes == nil {
+		packSizes = []models.PackSize{}
+	}
+
+	return putJSON(tx.Bucket(packSizesBucket), skuKey(sku), packSizes)
+}
+
+// modifyPackSizes changes the pack sizes used for the specified SKU and stores them as the pack sizes of the
+// SKU, products without pack sizes of their own get a changed copy of the default pack sizes
+func (p *BoltPackSizeProvider) modifyPackSizes(sku string, change func(packSizes []models.PackSize) ([]models.PackSize, error)) error {
+	return p.db.Update(func(tx *bolt.Tx) error {
+		packSizes, _, err := getPackSizes(tx, sku)
+		if err != nil {
+			return err
+		}
+
+		packSizes, err = change(packSizes)
+		if err != nil {
+			return err
+		}
+
+		return putPackSizes(tx, sku, packSizes)
+	})
+}
+
+// GetPackSizes returns the available pack sizes for the specified SKU, falling back to the default pack sizes
+func (p *BoltPackSizeProvider) GetPackSizes(ctx context.Context, sku string) ([]models.PackSize, error) {
+	var packSizes []models.PackSize
+	err := p.db.View(func(tx *bolt.Tx) error {
+		var err error
+		packSizes, _, err = getPackSizes(tx, sku)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return packSizes, nil
+}
+
+// Update updates the pack sizes for the specified SKU (the default pack sizes when empty) in the database
+func (p *BoltPackSizeProvider) Update(ctx context.Context, sku string, packSizes []models.PackSize) error {
+	return p.db.Update(func(tx *bolt.Tx) error {
+		return putPackSizes(tx, sku, packSizes)
+	})
+}
+
+// CompareAndSwap updates the pack sizes for the specified SKU in the database if the pack sizes used
+// for the SKU still have the version
+func (p *BoltPackSizeProvider) CompareAndSwap(ctx context.Context, sku, version string, packSizes []models.PackSize) error {
+	return p.modifyPackSizes(sku, func(current []models.PackSize) ([]models.PackSize, error) {
+		if models.PackSizesVersion(current) != version {
+			return nil, services.ErrVersionConflict
+		}
+
+		return packSizes, nil
+	})
+}
+
+// AdjustStock applies the stock adjustments to the pack sizes used for the specified SKU in the database
+func (p *BoltPackSizeProvider) AdjustStock(ctx context.Context, sku string, adjustments []models.StockAdjustment) error {
+	return p.db.Update(func(tx *bolt.Tx) error {
+		packSizes, sku, err := getPackSizes(tx, sku)
+		if err != nil {
+			return err
+		}
+
+		packSizes, err = adjustStock(packSizes, adjustments)
+		if err != nil {
+			return err
+		}
+
+		return putPackSizes(tx, sku, packSizes)
+	})
+}
+
+// AddPackSizeVersion saves the version of the pack sizes in the database, the oldest versions past maxPackSizeVersions are forgotten
+func (p *BoltPackSizeProvider) AddPackSizeVersion(ctx context.Context, version models.PackSizeSetVersion) (models.PackSizeSetVersion, error) {
+	err := p.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(historyBucket).CreateBucketIfNotExists(skuKey(version.SKU))
+		if err != nil {
+			return fmt.Errorf("failed to create history: %w", err)
+		}
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return fmt.Errorf("failed to get version ID: %w", err)
+		}
+		version.ID = int(id)
+
+		if err := putJSON(bucket, itob(version.ID), version); err != nil {
+			return err
+		}
+
+		// Version IDs follow each other, so the versions to forget are the ones from the first key on
+		for {
+			key, _ := bucket.Cursor().First()
+			if key == nil || int(binary.BigEndian.Uint64(key)) > version.ID-maxPackSizeVersions {
+				return nil
+			}
+
+			if err := bucket.Delete(key); err != nil {
+				return fmt.Errorf("failed to forget version: %w", err)
+			}
+		}
+	})
+	if err != nil {
+		return models.PackSizeSetVersion{}, err
+	}
+
+	return version, nil
+}
+
+// PackSizeVersions returns the versions of the pack sizes for the specified SKU in the database, the newest first
+func (p *BoltPackSizeProvider) PackSizeVersions(ctx context.Context, sku string) ([]models.PackSizeSetVersion, error) {
+	versions := []models.PackSizeSetVersion{}
+	err := p.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(historyBucket).Bucket(skuKey(sku))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for _, data := c.Last(); data != nil; _, data = c.Prev() {
+			var version models.PackSizeSetVersion
+			if err := json.Unmarshal(data, &version); err != nil {
+				return fmt.Errorf("failed to unmarshal version: %w", err)
+			}
+			versions = append(versions, version)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// PackSizeVersion returns a version of the pack sizes for the specified SKU in the database
+func (p *BoltPackSizeProvider) PackSizeVersion(ctx context.Context, sku string, id int) (models.PackSizeSetVersion, error) {
+	var version models.PackSizeSetVersion
+	err := p.db.View(func(tx *bolt.Tx) error {
+		var data []byte
+		if bucket := tx.Bucket(historyBucket).Bucket(skuKey(sku)); bucket != nil && id > 0 {
+			data = bucket.Get(itob(id))
+		}
+		if data == nil {
+			return fmt.Errorf("%w: %d", services.ErrUnknownVersion, id)
+		}
+
+		if err := json.Unmarshal(data, &version); err != nil {
+			return fmt.Errorf("failed to unmarshal version: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return models.PackSizeSetVersion{}, err
+	}
+
+	return version, nil
+}
+
+// SchedulePackSizes saves the scheduled pack sizes in the database
+func (p *BoltPackSizeProvider) SchedulePackSizes(ctx context.Context, scheduled models.ScheduledPackSizes) (models.ScheduledPackSizes, error) {
+	err := p.db.Update(func(tx *bolt.Tx) error {
+		schedule := tx.Bucket(scheduleBucket)
+
+		id, err := schedule.NextSequence()
+		if err != nil {
+			return fmt.Errorf("failed to get schedule ID: %w", err)
+		}
+		scheduled.ID = int(id)
+
+		bucket, err := schedule.CreateBucketIfNotExists(skuKey(scheduled.SKU))
+		if err != nil {
+			return fmt.Errorf("failed to create schedule: %w", err)
+		}
+
+		return putJSON(bucket, itob(scheduled.ID), scheduled)
+	})
+	if err != nil {
+		return models.ScheduledPackSizes{}, err
+	}
+
+	return scheduled, nil
+}
+
+// ScheduledPackSizes returns the scheduled pack sizes for the specified SKU in the database
+func (p *BoltPackSizeProvider) ScheduledPackSizes(ctx context.Context, sku string) ([]models.ScheduledPackSizes, error) {
+	schedule := []models.ScheduledPackSizes{}
+	err := p.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(scheduleBucket).Bucket(skuKey(sku))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(_, data []byte) error {
+			var scheduled models.ScheduledPackSizes
+			if err := json.Unmarshal(data, &scheduled); err != nil {
+				return fmt.Errorf("failed to unmarshal scheduled pack sizes: %w", err)
+			}
+			schedule = append(schedule, scheduled)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Scheduled pack sizes are stored by ID, those taking effect at the same time stay in the order they were scheduled
+	slices.SortStableFunc(schedule, func(a, b models.ScheduledPackSizes) int {
+		return a.EffectiveFrom.Compare(b.EffectiveFrom)
+	})
+
+	return schedule, nil
+}
+
+// UnschedulePackSizes removes scheduled pack sizes for the specified SKU from the database
+func (p *BoltPackSizeProvider) UnschedulePackSizes(ctx context.Context, sku string, id int) error {
+	return p.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(scheduleBucket).Bucket(skuKey(sku))
+		if bucket == nil || id <= 0 || bucket.Get(itob(id)) == nil {
+			return fmt.Errorf("%w: %d", services.ErrUnknownSchedule, id)
+		}
+
+		return bucket.Delete(itob(id))
+	})
+}
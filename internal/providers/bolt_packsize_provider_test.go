@@ -0,0 +1,257 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/providers"
+	"github.com/cybre/order-packing/internal/services"
+)
+
+// openBoltPackSizeProvider opens a database in the directory, closing it when the test ends
+func openBoltPackSizeProvider(t *testing.T, dir string) *providers.BoltPackSizeProvider {
+	t.Helper()
+
+	p, err := providers.NewBoltPackSizeProvider(filepath.Join(dir, "test_pack_sizes.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+
+	return p
+}
+
+func TestBoltPackSizeProvider_PackSizes(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	p := openBoltPackSizeProvider(t, dir)
+	stock := 5
+
+	// Act
+	if err := p.Update(context.Background(), "", []models.PackSize{{MaxItems: 250}, {MaxItems: 500, Stock: &stock}}); err != nil {
+		t.Fatalf("failed to update pack sizes: %v", err)
+	}
+	if err := p.Update(context.Background(), "SKU-1", []models.PackSize{{MaxItems: 6}, {MaxItems: 12}}); err != nil {
+		t.Fatalf("failed to update pack sizes: %v", err)
+	}
+	if err := p.AdjustStock(context.Background(), "SKU-2", []models.StockAdjustment{{MaxItems: 500, Delta: -2}}); err != nil {
+		t.Fatalf("failed to adjust stock: %v", err)
+	}
+	adjustErr := p.AdjustStock(context.Background(), "", []models.StockAdjustment{{MaxItems: 250, Delta: 1}, {MaxItems: 500, Delta: -4}})
+	swapErr := p.CompareAndSwap(context.Background(), "SKU-2", models.PackSizesVersion([]models.PackSize{{MaxItems: 250}}), nil)
+
+	// Reopen the database to check the changes were stored
+	p.Close()
+	p = openBoltPackSizeProvider(t, dir)
+
+	// Assert
+	if !errors.Is(adjustErr, services.ErrUnlimitedStock) {
+		t.Fatalf("unexpected error adjusting unlimited stock, got %v, want %v", adjustErr, services.ErrUnlimitedStock)
+	}
+	if !errors.Is(swapErr, services.ErrVersionConflict) {
+		t.Fatalf("unexpected error swapping outdated pack sizes, got %v, want %v", swapErr, services.ErrVersionConflict)
+	}
+
+	remaining := 3
+	expectedPackSizes := map[string][]models.PackSize{
+		"":      {{MaxItems: 250}, {MaxItems: 500, Stock: &remaining}},
+		"SKU-1": {{MaxItems: 6}, {MaxItems: 12}},
+		"SKU-2": {{MaxItems: 250}, {MaxItems: 500, Stock: &remaining}},
+	}
+	for sku, expected := range expectedPackSizes {
+		packSizes, err := p.GetPackSizes(context.Background(), sku)
+		if err != nil {
+			t.Fatalf("failed to get pack sizes for %q: %v", sku, err)
+		}
+		if !reflect.DeepEqual(packSizes, expected) {
+			t.Fatalf("unexpected pack sizes for %q, got %+v, want %+v", sku, packSizes, expected)
+		}
+	}
+}
+
+func TestBoltPackSizeProvider_HistoryAndSchedule(t *testing.T) {
+	// Arrange
+	p := openBoltPackSizeProvider(t, t.TempDir())
+	from := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Act
+	for i := 1; i <= 3; i++ {
+		version := models.PackSizeSetVersion{SKU: "SKU-1", Author: "admin", PackSizes: []models.PackSize{{MaxItems: i}}}
+		if _, err := p.AddPackSizeVersion(context.Background(), version); err != nil {
+			t.Fatalf("failed to add pack size version: %v", err)
+		}
+	}
+	for _, days := range []int{2, 1, 3} {
+		scheduled := models.ScheduledPackSizes{SKU: "SKU-1", EffectiveFrom: from.AddDate(0, 0, days), PackSizes: []models.PackSize{{MaxItems: days}}}
+		if _, err := p.SchedulePackSizes(context.Background(), scheduled); err != nil {
+			t.Fatalf("failed to schedule pack sizes: %v", err)
+		}
+	}
+	if err := p.UnschedulePackSizes(context.Background(), "SKU-1", 1); err != nil {
+		t.Fatalf("failed to unschedule pack sizes: %v", err)
+	}
+	scheduled, err := p.SchedulePackSizes(context.Background(), models.ScheduledPackSizes{SKU: "SKU-1", EffectiveFrom: from, PackSizes: []models.PackSize{{MaxItems: 6}}})
+	if err != nil {
+		t.Fatalf("failed to schedule pack sizes: %v", err)
+	}
+
+	// Assert
+	versions, err := p.PackSizeVersions(context.Background(), "SKU-1")
+	if err != nil {
+		t.Fatalf("failed to get pack size versions: %v", err)
+	}
+	if len(versions) != 3 || versions[0].ID != 3 || versions[2].ID != 1 {
+		t.Fatalf("unexpected versions, got %+v, want versions from 3 to 1", versions)
+	}
+
+	version, err := p.PackSizeVersion(context.Background(), "SKU-1", 2)
+	if err != nil {
+		t.Fatalf("failed to get pack size version: %v", err)
+	}
+	expectedVersion := models.PackSizeSetVersion{ID: 2, SKU: "SKU-1", Author: "admin", PackSizes: []models.PackSize{{MaxItems: 2}}}
+	if !reflect.DeepEqual(version, expectedVersion) {
+		t.Fatalf("unexpected version, got %+v, want %+v", version, expectedVersion)
+	}
+
+	if _, err := p.PackSizeVersion(context.Background(), "", 2); !errors.Is(err, services.ErrUnknownVersion) {
+		t.Fatalf("unexpected error for an unknown version, got %v, want %v", err, services.ErrUnknownVersion)
+	}
+
+	if scheduled.ID != 4 {
+		t.Fatalf("unexpected scheduled pack sizes ID, got %d, want %d", scheduled.ID, 4)
+	}
+
+	schedule, err := p.ScheduledPackSizes(context.Background(), "SKU-1")
+	if err != nil {
+		t.Fatalf("failed to get scheduled pack sizes: %v", err)
+	}
+	ids := make([]int, len(schedule))
+	for i, scheduled := range schedule {
+		ids[i] = scheduled.ID
+	}
+	if expectedIDs := []int{4, 2, 3}; !reflect.DeepEqual(ids, expectedIDs) {
+		t.Fatalf("unexpected scheduled pack sizes, got IDs %v, want %v", ids, expectedIDs)
+	}
+
+	if err := p.UnschedulePackSizes(context.Background(), "SKU-1", 1); !errors.Is(err, services.ErrUnknownSchedule) {
+		t.Fatalf("unexpected error unscheduling removed pack sizes, got %v, want %v", err, services.ErrUnknownSchedule)
+	}
+}
+
+func TestBoltPackSizeProvider_HistoryLimit(t *testing.T) {
+	// Arrange
+	p := openBoltPackSizeProvider(t, t.TempDir())
+
+	// Act
+	for i := 1; i <= 102; i++ {
+		version := models.PackSizeSetVersion{SKU: "SKU-1", Author: "admin", PackSizes: []models.PackSize{{MaxItems: i}}}
+		if _, err := p.AddPackSizeVersion(context.Background(), version); err != nil {
+			t.Fatalf("failed to add pack size version: %v", err)
+		}
+	}
+	if _, err := p.AddPackSizeVersion(context.Background(), models.PackSizeSetVersion{SKU: "SKU-2", PackSizes: []models.PackSize{{MaxItems: 1}}}); err != nil {
+		t.Fatalf("failed to add pack size version: %v", err)
+	}
+
+	// Assert
+	versions, err := p.PackSizeVersions(context.Background(), "SKU-1")
+	if err != nil {
+		t.Fatalf("failed to get pack size versions: %v", err)
+	}
+	if len(versions) != 100 || versions[0].ID != 102 || versions[99].ID != 3 {
+		t.Fatalf("unexpected versions, got %d versions from %d to %d, want 100 versions from 102 to 3", len(versions), versions[0].ID, versions[len(versions)-1].ID)
+	}
+
+	if _, err := p.PackSizeVersion(context.Background(), "SKU-1", 2); !errors.Is(err, services.ErrUnknownVersion) {
+		t.Fatalf("unexpected error for a forgotten version, got %v, want %v", err, services.ErrUnknownVersion)
+	}
+
+	otherVersions, err := p.PackSizeVersions(context.Background(), "SKU-2")
+	if err != nil {
+		t.Fatalf("failed to get pack size versions: %v", err)
+	}
+	if len(otherVersions) != 1 {
+		t.Fatalf("unexpected versions of another SKU, got %+v", otherVersions)
+	}
+}
+
+func TestBoltPackSizeProvider_ImportFile(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	name := filepath.Join(dir, "packsizes.json")
+	data := `{
+		"version": 4,
+		"packSizes": [{"maxItems": 250}, {"maxItems": 500}],
+		"products": {"SKU-1": [{"maxItems": 6}]},
+		"history": {"SKU-1": [{"id": 7, "sku": "SKU-1", "createdAt": "2024-01-01T00:00:00Z", "packSizes": [{"maxItems": 6}], "changes": []}]},
+		"schedule": {"": [{"id": 3, "effectiveFrom": "2030-01-01T00:00:00Z", "packSizes": [{"maxItems": 1000}]}]},
+		"lastScheduleId": 5
+	}`
+	if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+		t.Fatalf("failed to write test data to file: %v", err)
+	}
+
+	p := openBoltPackSizeProvider(t, dir)
+
+	// Act
+	imported, err := p.ImportFile(name)
+	if err != nil {
+		t.Fatalf("failed to import file: %v", err)
+	}
+	if err := p.Update(context.Background(), "", []models.PackSize{{MaxItems: 42}}); err != nil {
+		t.Fatalf("failed to update pack sizes: %v", err)
+	}
+	importedAgain, err := p.ImportFile(name)
+	if err != nil {
+		t.Fatalf("failed to import file: %v", err)
+	}
+
+	// Assert
+	if !imported || importedAgain {
+		t.Fatalf("unexpected imports, got %t and %t, want the file imported only the first time", imported, importedAgain)
+	}
+
+	expectedPackSizes := map[string][]models.PackSize{
+		"":      {{MaxItems: 42}},
+		"SKU-1": {{MaxItems: 6}},
+	}
+	for sku, expected := range expectedPackSizes {
+		packSizes, err := p.GetPackSizes(context.Background(), sku)
+		if err != nil {
+			t.Fatalf("failed to get pack sizes for %q: %v", sku, err)
+		}
+		if !reflect.DeepEqual(packSizes, expected) {
+			t.Fatalf("unexpected pack sizes for %q, got %+v, want %+v", sku, packSizes, expected)
+		}
+	}
+
+	version, err := p.AddPackSizeVersion(context.Background(), models.PackSizeSetVersion{SKU: "SKU-1"})
+	if err != nil {
+		t.Fatalf("failed to add pack size version: %v", err)
+	}
+	if version.ID != 8 {
+		t.Fatalf("unexpected version ID after the imported history, got %d, want %d", version.ID, 8)
+	}
+
+	scheduled, err := p.SchedulePackSizes(context.Background(), models.ScheduledPackSizes{EffectiveFrom: time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("failed to schedule pack sizes: %v", err)
+	}
+	if scheduled.ID != 6 {
+		t.Fatalf("unexpected scheduled pack sizes ID after the imported schedule, got %d, want %d", scheduled.ID, 6)
+	}
+
+	schedule, err := p.ScheduledPackSizes(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to get scheduled pack sizes: %v", err)
+	}
+	if len(schedule) != 2 || schedule[0].ID != 3 {
+		t.Fatalf("unexpected scheduled default pack sizes, got %+v", schedule)
+	}
+}
@@ -0,0 +1,288 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultCachingStatInterval is how often a CachingPackSizeProvider checks the file for changes by default
+const DefaultCachingStatInterval = 2 * time.Second
+
+// CachingPackSizeProvider is a PackSizeProvider serving the pack sizes and scheduled pack sizes of another
+// provider from memory. The cache is dropped whenever pack sizes are changed through it, and whenever the file
+// the other provider stores them in changes on disk, so changes made by editing the file or by other processes
+// sharing it are picked up. It keeps the history and schedule of the other provider when it has them.
+//
+// Changes to the file are watched for, and the file is also checked for changes when it is read after the stat
+// interval, since writes by other hosts to network volumes are not reported to the watch.
+type CachingPackSizeProvider struct {
+	provider     services.PackSizeProvider
+	history      services.PackSizeHistory
+	schedule     services.PackSizeSchedule
+	watcher      *fsnotify.Watcher
+	filePath     string
+	statInterval time.Duration
+	now          func() time.Time
+	// checkedAt is when the file was last checked for changes, in nanoseconds since the Unix epoch
+	checkedAt atomic.Int64
+
+	mu         sync.RWMutex
+	generation uint64
+	fileInfo   os.FileInfo
+	packSizes  map[string][]models.PackSize
+	schedules  map[string][]models.ScheduledPackSizes
+}
+
+// CachingOption configures a CachingPackSizeProvider
+type CachingOption func(*CachingPackSizeProvider)
+
+// WithCachingStatInterval sets how often the file is checked for changes when it is read,
+// DefaultCachingStatInterval by default. The file is only watched when the interval is not positive.
+func WithCachingStatInterval(interval time.Duration) CachingOption {
+	return func(p *CachingPackSizeProvider) {
+		p.statInterval = interval
+	}
+}
+
+// WithCachingClock sets the time source the stat interval is measured with, time.Now by default
+func WithCachingClock(now func() time.Time) CachingOption {
+	return func(p *CachingPackSizeProvider) {
+		p.now = now
+	}
+}
+
+// NewCachingPackSizeProvider returns a new CachingPackSizeProvider caching the provider, dropping the cache
+// when the file at the specified path changes. The file is not watched when the path is empty.
+func NewCachingPackSizeProvider(provider services.PackSizeProvider, filePath string, opts ...CachingOption) (*CachingPackSizeProvider, error) {
+	p := &CachingPackSizeProvider{
+		provider:     provider,
+		statInterval: DefaultCachingStatInterval,
+		now:          time.Now,
+		packSizes:    map[string][]models.PackSize{},
+		schedules:    map[string][]models.ScheduledPackSizes{},
+	}
+	p.history, _ = provider.(services.PackSizeHistory)
+	p.schedule, _ = provider.(services.PackSizeSchedule)
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if filePath == "" {
+		return p, nil
+	}
+
+	filePath = filepath.Clean(filePath)
+	p.filePath = filePath
+	p.fileInfo, _ = os.Stat(filePath)
+	p.checkedAt.Store(p.now().UnixNano())
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch file: %w", err)
+	}
+
+	// The directory is watched rather than the file, as replacing the file by renaming another one over it
+	// ends the watch of the file
+	if err := watcher.Add(filepath.Dir(filePath)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch file: %w", err)
+	}
+	p.watcher = watcher
+
+	go p.watch(filePath)
+
+	return p, nil
+}
+
+// Close stops watching the file
+func (p *CachingPackSizeProvider) Close() error {
+	if p.watcher == nil {
+		return nil
+	}
+
+	return p.watcher.Close()
+}
+
+// watch drops the cache every time the file changes, and when changes may have been missed
+func (p *CachingPackSizeProvider) watch(filePath string) {
+	for {
+		select {
+		case event, ok := <-p.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if filepath.Clean(event.Name) == filePath {
+				p.invalidate()
+			}
+		case _, ok := <-p.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			p.invalidate()
+		}
+	}
+}
+
+// invalidate drops the cache, loads that started before are not cached
+func (p *CachingPackSizeProvider) invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.generation++
+	clear(p.packSizes)
+	clear(p.schedules)
+}
+
+// revalidate drops the cache when the file changed since it was last checked, at most once per stat interval.
+// A file replaced by renaming another one over it, or written with another size or modification time, changed.
+func (p *CachingPackSizeProvider) revalidate() {
+	if p.filePath == "" || p.statInterval <= 0 {
+		return
+	}
+
+	now, checkedAt := p.now().UnixNano(), p.checkedAt.Load()
+	if time.Duration(now-checkedAt) < p.statInterval || !p.checkedAt.CompareAndSwap(checkedAt, now) {
+		return
+	}
+
+	info, _ := os.Stat(p.filePath)
+
+	p.mu.Lock()
+	previous := p.fileInfo
+	p.fileInfo = info
+	p.mu.Unlock()
+
+	if info == nil && previous == nil {
+		return
+	}
+	if info != nil && previous != nil && os.SameFile(info, previous) && info.Size() == previous.Size() && info.ModTime().Equal(previous.ModTime()) {
+		return
+	}
+
+	p.invalidate()
+}
+
+// cached returns the value cached for the SKU, loading and caching it when there is none
+func cached[T any](p *CachingPackSizeProvider, cache map[string][]T, sku string, load func() ([]T, error)) ([]T, error) {
+	p.revalidate()
+
+	p.mu.RLock()
+	values, ok := cache[sku]
+	generation := p.generation
+	p.mu.RUnlock()
+	if ok {
+		return slices.Clone(values), nil
+	}
+
+	values, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if p.generation == generation {
+		cache[sku] = values
+	}
+	p.mu.Unlock()
+
+	return slices.Clone(values), nil
+}
+
+// GetPackSizes returns the pack sizes for the specified SKU from the cache
+func (p *CachingPackSizeProvider) GetPackSizes(ctx context.Context, sku string) ([]models.PackSize, error) {
+	return cached(p, p.packSizes, sku, func() ([]models.PackSize, error) {
+		return p.provider.GetPackSizes(ctx, sku)
+	})
+}
+
+// Update updates the pack sizes for the specified SKU
+func (p *CachingPackSizeProvider) Update(ctx context.Context, sku string, packSizes []models.PackSize) error {
+	defer p.invalidate()
+
+	return p.provider.Update(ctx, sku, packSizes)
+}
+
+// CompareAndSwap updates the pack sizes for the specified SKU if they still have the version
+func (p *CachingPackSizeProvider) CompareAndSwap(ctx context.Context, sku, version string, packSizes []models.PackSize) error {
+	defer p.invalidate()
+
+	return p.provider.CompareAndSwap(ctx, sku, version, packSizes)
+}
+
+// AdjustStock applies the stock adjustments to the pack sizes used for the specified SKU
+func (p *CachingPackSizeProvider) AdjustStock(ctx context.Context, sku string, adjustments []models.StockAdjustment) error {
+	defer p.invalidate()
+
+	return p.provider.AdjustStock(ctx, sku, adjustments)
+}
+
+// AddPackSizeVersion saves the version of the pack sizes in the history of the provider
+func (p *CachingPackSizeProvider) AddPackSizeVersion(ctx context.Context, version models.PackSizeSetVersion) (models.PackSizeSetVersion, error) {
+	if p.history == nil {
+		return models.PackSizeSetVersion{}, services.ErrHistoryUnavailable
+	}
+
+	return p.history.AddPackSizeVersion(ctx, version)
+}
+
+// PackSizeVersions returns the versions of the pack sizes for the specified SKU from the history of the provider
+func (p *CachingPackSizeProvider) PackSizeVersions(ctx context.Context, sku string) ([]models.PackSizeSetVersion, error) {
+	if p.history == nil {
+		return nil, services.ErrHistoryUnavailable
+	}
+
+	return p.history.PackSizeVersions(ctx, sku)
+}
+
+// PackSizeVersion returns a version of the pack sizes for the specified SKU from the history of the provider
+func (p *CachingPackSizeProvider) PackSizeVersion(ctx context.Context, sku string, id int) (models.PackSizeSetVersion, error) {
+	if p.history == nil {
+		return models.PackSizeSetVersion{}, services.ErrHistoryUnavailable
+	}
+
+	return p.history.PackSizeVersion(ctx, sku, id)
+}
+
+// SchedulePackSizes saves the scheduled pack sizes in the schedule of the provider
+func (p *CachingPackSizeProvider) SchedulePackSizes(ctx context.Context, scheduled models.ScheduledPackSizes) (models.ScheduledPackSizes, error) {
+	if p.schedule == nil {
+		return models.ScheduledPackSizes{}, services.ErrScheduleUnavailable
+	}
+	defer p.invalidate()
+
+	return p.schedule.SchedulePackSizes(ctx, scheduled)
+}
+
+// ScheduledPackSizes returns the scheduled pack sizes for the specified SKU from the cache
+func (p *CachingPackSizeProvider) ScheduledPackSizes(ctx context.Context, sku string) ([]models.ScheduledPackSizes, error) {
+	if p.schedule == nil {
+		return nil, services.ErrScheduleUnavailable
+	}
+
+	return cached(p, p.schedules, sku, func() ([]models.ScheduledPackSizes, error) {
+		return p.schedule.ScheduledPackSizes(ctx, sku)
+	})
+}
+
+// UnschedulePackSizes removes scheduled pack sizes for the specified SKU from the schedule of the provider
+func (p *CachingPackSizeProvider) UnschedulePackSizes(ctx context.Context, sku string, id int) error {
+	if p.schedule == nil {
+		return services.ErrScheduleUnavailable
+	}
+	defer p.invalidate()
+
+	return p.schedule.UnschedulePackSizes(ctx, sku, id)
+}
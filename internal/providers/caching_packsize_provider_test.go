@@ -0,0 +1,136 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/providers"
+	"github.com/cybre/order-packing/internal/services"
+	"github.com/cybre/order-packing/internal/services/testdata"
+)
+
+func TestCachingPackSizeProvider_GetPackSizes(t *testing.T) {
+	// Arrange
+	provider := &testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}}
+	p, err := providers.NewCachingPackSizeProvider(provider, "")
+	if err != nil {
+		t.Fatalf("failed to create caching provider: %v", err)
+	}
+	defer p.Close()
+
+	// Act
+	for i := 0; i < 3; i++ {
+		if _, err := p.GetPackSizes(context.Background(), ""); err != nil {
+			t.Fatalf("failed to get pack sizes: %v", err)
+		}
+	}
+	if err := p.Update(context.Background(), "", []models.PackSize{{MaxItems: 1000}}); err != nil {
+		t.Fatalf("failed to update pack sizes: %v", err)
+	}
+	packSizes, err := p.GetPackSizes(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to get pack sizes: %v", err)
+	}
+
+	// Assert
+	if fetches := provider.Fetches.Load(); fetches != 2 {
+		t.Fatalf("unexpected number of fetches, got %d, want %d", fetches, 2)
+	}
+
+	expectedPackSizes := []models.PackSize{{MaxItems: 1000}}
+	if !reflect.DeepEqual(packSizes, expectedPackSizes) {
+		t.Fatalf("unexpected pack sizes, got %+v, want %+v", packSizes, expectedPackSizes)
+	}
+
+	if _, err := p.ScheduledPackSizes(context.Background(), ""); !errors.Is(err, services.ErrScheduleUnavailable) {
+		t.Fatalf("unexpected error getting a schedule the provider does not keep, got %v, want %v", err, services.ErrScheduleUnavailable)
+	}
+}
+
+func TestCachingPackSizeProvider_FileChanged(t *testing.T) {
+	// Create a temporary file for testing
+	name := filepath.Join(t.TempDir(), "test_pack_sizes.json")
+	if err := os.WriteFile(name, []byte(`[{"maxItems": 250}]`), 0o644); err != nil {
+		t.Fatalf("failed to write test data to file: %v", err)
+	}
+
+	// Create an instance of CachingPackSizeProvider caching a FilePackSizeProvider
+	p, err := providers.NewCachingPackSizeProvider(providers.NewFilePackSizeProvider(name), name)
+	if err != nil {
+		t.Fatalf("failed to create caching provider: %v", err)
+	}
+	defer p.Close()
+
+	if _, err := p.GetPackSizes(context.Background(), ""); err != nil {
+		t.Fatalf("failed to get pack sizes: %v", err)
+	}
+
+	// Act
+	if err := os.WriteFile(name, []byte(`[{"maxItems": 250}, {"maxItems": 500}]`), 0o644); err != nil {
+		t.Fatalf("failed to edit file: %v", err)
+	}
+
+	// Assert
+	expectedPackSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		packSizes, err := p.GetPackSizes(context.Background(), "")
+		if err != nil {
+			t.Fatalf("failed to get pack sizes: %v", err)
+		}
+		if reflect.DeepEqual(packSizes, expectedPackSizes) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pack sizes were not reloaded after the file changed, got %+v, want %+v", packSizes, expectedPackSizes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCachingPackSizeProvider_FileChangedUnwatched(t *testing.T) {
+	// Arrange
+	name := filepath.Join(t.TempDir(), "test_pack_sizes.json")
+	if err := os.WriteFile(name, []byte(`[{"maxItems": 250}]`), 0o644); err != nil {
+		t.Fatalf("failed to write test data to file: %v", err)
+	}
+
+	clk := &clock{}
+	p, err := providers.NewCachingPackSizeProvider(providers.NewFilePackSizeProvider(name), name,
+		providers.WithCachingStatInterval(time.Minute),
+		providers.WithCachingClock(clk.now),
+	)
+	if err != nil {
+		t.Fatalf("failed to create caching provider: %v", err)
+	}
+	defer p.Close()
+
+	if _, err := p.GetPackSizes(context.Background(), ""); err != nil {
+		t.Fatalf("failed to get pack sizes: %v", err)
+	}
+
+	// Act
+	// Another host writes the file on a shared volume, which is not reported to the watch
+	if err := os.WriteFile(name, []byte(`[{"maxItems": 250}, {"maxItems": 500}]`), 0o644); err != nil {
+		t.Fatalf("failed to edit file: %v", err)
+	}
+	clk.advance(time.Minute)
+
+	packSizes, err := p.GetPackSizes(context.Background(), "")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to get pack sizes: %v", err)
+	}
+
+	expectedPackSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}
+	if !reflect.DeepEqual(packSizes, expectedPackSizes) {
+		t.Fatalf("pack sizes were not reloaded after the file changed, got %+v, want %+v", packSizes, expectedPackSizes)
+	}
+}
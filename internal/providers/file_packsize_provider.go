@@ -0,0 +1,474 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
+)
+
+// packSizeFileVersion is the current version of the pack sizes file schema
+const packSizeFileVersion = 4
+
+// maxPackSizeVersions is the number of versions of the pack sizes of a SKU the file and the database keep
+const maxPackSizeVersions = 100
+
+// packSizeFile is the versioned schema of the pack sizes file.
+// Version 1 files are a flat array of the default pack sizes, version 2 files have no history
+// and version 3 files have no schedule. CSV files only hold the pack sizes.
+type packSizeFile struct {
+	// Version is the schema version of the file
+	Version int `json:"version"`
+	// PackSizes are the default pack sizes
+	PackSizes []models.PackSize `json:"packSizes"`
+	// Products holds the pack sizes of every product with its own packaging, keyed by SKU
+	Products map[string][]models.PackSize `json:"products,omitempty"`
+	// History holds the versions of the pack sizes of every SKU from the oldest to the newest, keyed by SKU
+	History map[string][]models.PackSizeSetVersion `json:"history,omitempty"`
+	// Schedule holds the scheduled pack sizes of every SKU ordered by when they take effect, keyed by SKU
+	Schedule map[string][]models.ScheduledPackSizes `json:"schedule,omitempty"`
+	// LastScheduleID is the ID of the last scheduled pack sizes, so IDs are not reused once they are removed
+	LastScheduleID int `json:"lastScheduleId,omitempty"`
+}
+
+const (
+	// backupFileSuffix is appended to the path of the pack sizes file to get the path of its backup
+	backupFileSuffix = ".bak"
+	// lockFileSuffix is appended to the path of the pack sizes file to get the path of the file writers lock
+	lockFileSuffix = ".lock"
+	// temporaryFilePattern is appended to the path of the pack sizes file to get the pattern of its temporary files
+	temporaryFilePattern = ".tmp-*"
+)
+
+// FilePackSizeProvider is a PackSizeProvider that reads and stores pack sizes in a JSON, YAML, TOML or CSV file,
+// the format is told by the extension of the file. The file is only ever replaced as a whole, and the previous
+// contents are kept in a backup file.
+type FilePackSizeProvider struct {
+	filePath string
+	format   packSizeFileFormat
+	mu       sync.Mutex
+}
+
+// NewFilePackSizeProvider returns a new FilePackSizeProvider with the specified file path. Files with the
+// .yaml or .yml, .toml and .csv extensions are read and written as YAML, TOML and CSV, other files as JSON.
+func NewFilePackSizeProvider(filePath string) *FilePackSizeProvider {
+	return &FilePackSizeProvider{filePath: filePath, format: packSizeFileFormatFor(filePath)}
+}
+
+// packSizes returns the pack sizes used for the specified SKU and the SKU they are stored under,
+// which is empty when the SKU uses the default pack sizes
+func (f packSizeFile) packSizes(sku string) ([]models.PackSize, string) {
+	if packSizes, ok := f.Products[sku]; ok && sku != "" {
+		return packSizes, sku
+	}
+
+	return f.PackSizes, ""
+}
+
+// setPackSizes sets the pack sizes for the specified SKU (the default pack sizes when empty)
+func (f *packSizeFile) setPackSizes(sku string, packSizes []models.PackSize) {
+	if sku == "" {
+		f.PackSizes = packSizes
+		return
+	}
+
+	if f.Products == nil {
+		f.Products = make(map[string][]models.PackSize)
+	}
+	f.Products[sku] = packSizes
+}
+
+// GetPackSizes returns the available pack sizes for the specified SKU, falling back to the default pack sizes
+func (p *FilePackSizeProvider) GetPackSizes(ctx context.Context, sku string) ([]models.PackSize, error) {
+	file, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+
+	packSizes, _ := file.packSizes(sku)
+
+	return packSizes, nil
+}
+
+// Update updates the pack sizes for the specified SKU (the default pack sizes when empty) in the file
+func (p *FilePackSizeProvider) Update(ctx context.Context, sku string, packSizes []models.PackSize) error {
+	return p.modify(func(file *packSizeFile) error {
+		file.setPackSizes(sku, packSizes)
+
+		return nil
+	})
+}
+
+// CompareAndSwap updates the pack sizes for the specified SKU in the file if the pack sizes used
+// for the SKU still have the version
+func (p *FilePackSizeProvider) CompareAndSwap(ctx context.Context, sku, version string, packSizes []models.PackSize) error {
+	return p.modify(func(file *packSizeFile) error {
+		if current, _ := file.packSizes(sku); models.PackSizesVersion(current) != version {
+			return services.ErrVersionConflict
+		}
+
+		file.setPackSizes(sku, packSizes)
+
+		return nil
+	})
+}
+
+// AdjustStock applies the stock adjustments to the pack sizes used for the specified SKU in the file
+func (p *FilePackSizeProvider) AdjustStock(ctx context.Context, sku string, adjustments []models.StockAdjustment) error {
+	return p.modify(func(file *packSizeFile) error {
+		packSizes, sku := file.packSizes(sku)
+		packSizes, err := adjustStock(packSizes, adjustments)
+		if err != nil {
+			return err
+		}
+
+		file.setPackSizes(sku, packSizes)
+
+		return nil
+	})
+}
+
+// AddPackSizeVersion saves the version of the pack sizes in the file, forgetting the oldest
+// versions of the SKU past the most the file keeps
+func (p *FilePackSizeProvider) AddPackSizeVersion(ctx context.Context, version models.PackSizeSetVersion) (models.PackSizeSetVersion, error) {
+	if !p.format.keepsHistory() {
+		return models.PackSizeSetVersion{}, services.ErrHistoryUnavailable
+	}
+
+	err := p.modify(func(file *packSizeFile) error {
+		if file.History == nil {
+			file.History = make(map[string][]models.PackSizeSetVersion)
+		}
+
+		versions := file.History[version.SKU]
+		version.ID = 1
+		if len(versions) > 0 {
+			version.ID = versions[len(versions)-1].ID + 1
+		}
+
+		versions = append(versions, version)
+		if len(versions) > maxPackSizeVersions {
+			versions = versions[len(versions)-maxPackSizeVersions:]
+		}
+		file.History[version.SKU] = versions
+
+		return nil
+	})
+	if err != nil {
+		return models.PackSizeSetVersion{}, err
+	}
+
+	return version, nil
+}
+
+// PackSizeVersions returns the versions of the pack sizes for the specified SKU in the file, the newest first
+func (p *FilePackSizeProvider) PackSizeVersions(ctx context.Context, sku string) ([]models.PackSizeSetVersion, error) {
+	if !p.format.keepsHistory() {
+		return nil, services.ErrHistoryUnavailable
+	}
+
+	file, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := slices.Clone(file.History[sku])
+	slices.Reverse(versions)
+	if versions == nil {
+		versions = []models.PackSizeSetVersion{}
+	}
+
+	return versions, nil
+}
+
+// PackSizeVersion returns a version of the pack sizes for the specified SKU in the file
+func (p *FilePackSizeProvider) PackSizeVersion(ctx context.Context, sku string, id int) (models.PackSizeSetVersion, error) {
+	if !p.format.keepsHistory() {
+		return models.PackSizeSetVersion{}, services.ErrHistoryUnavailable
+	}
+
+	file, err := p.read()
+	if err != nil {
+		return models.PackSizeSetVersion{}, err
+	}
+
+	i := slices.IndexFunc(file.History[sku], func(version models.PackSizeSetVersion) bool {
+		return version.ID == id
+	})
+	if i == -1 {
+		return models.PackSizeSetVersion{}, fmt.Errorf("%w: %d", services.ErrUnknownVersion, id)
+	}
+
+	return file.History[sku][i], nil
+}
+
+// SchedulePackSizes saves the scheduled pack sizes in the file
+func (p *FilePackSizeProvider) SchedulePackSizes(ctx context.Context, scheduled models.ScheduledPackSizes) (models.ScheduledPackSizes, error) {
+	if !p.format.keepsHistory() {
+		return models.ScheduledPackSizes{}, services.ErrScheduleUnavailable
+	}
+
+	err := p.modify(func(file *packSizeFile) error {
+		if file.Schedule == nil {
+			file.Schedule = make(map[string][]models.ScheduledPackSizes)
+		}
+
+		file.LastScheduleID++
+		scheduled.ID = file.LastScheduleID
+
+		schedule := append(file.Schedule[scheduled.SKU], scheduled)
+		slices.SortStableFunc(schedule, func(a, b models.ScheduledPackSizes) int {
+			return a.EffectiveFrom.Compare(b.EffectiveFrom)
+		})
+		file.Schedule[scheduled.SKU] = schedule
+
+		return nil
+	})
+	if err != nil {
+		return models.ScheduledPackSizes{}, err
+	}
+
+	return scheduled, nil
+}
+
+// ScheduledPackSizes returns the scheduled pack sizes for the specified SKU in the file
+func (p *FilePackSizeProvider) ScheduledPackSizes(ctx context.Context, sku string) ([]models.ScheduledPackSizes, error) {
+	if !p.format.keepsHistory() {
+		return nil, services.ErrScheduleUnavailable
+	}
+
+	file, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := slices.Clone(file.Schedule[sku])
+	if schedule == nil {
+		schedule = []models.ScheduledPackSizes{}
+	}
+
+	return schedule, nil
+}
+
+// UnschedulePackSizes removes scheduled pack sizes for the specified SKU from the file
+func (p *FilePackSizeProvider) UnschedulePackSizes(ctx context.Context, sku string, id int) error {
+	if !p.format.keepsHistory() {
+		return services.ErrScheduleUnavailable
+	}
+
+	return p.modify(func(file *packSizeFile) error {
+		i := slices.IndexFunc(file.Schedule[sku], func(scheduled models.ScheduledPackSizes) bool {
+			return scheduled.ID == id
+		})
+		if i == -1 {
+			return fmt.Errorf("%w: %d", services.ErrUnknownSchedule, id)
+		}
+
+		file.Schedule[sku] = slices.Delete(file.Schedule[sku], i, i+1)
+		if len(file.Schedule[sku]) == 0 {
+			delete(file.Schedule, sku)
+		}
+
+		return nil
+	})
+}
+
+// indexOfPackSize returns the index of the pack of the size, -1 when there is none
+func indexOfPackSize(packSizes []models.PackSize, maxItems int) int {
+	return slices.IndexFunc(packSizes, func(packSize models.PackSize) bool {
+		return packSize.MaxItems == maxItems
+	})
+}
+
+// adjustStock returns a copy of the pack sizes with the stock adjustments applied
+func adjustStock(packSizes []models.PackSize, adjustments []models.StockAdjustment) ([]models.PackSize, error) {
+	adjusted := make([]models.PackSize, len(packSizes))
+	for i, packSize := range packSizes {
+		adjusted[i] = packSize
+		if packSize.Stock != nil {
+			stock := *packSize.Stock
+			adjusted[i].Stock = &stock
+		}
+	}
+
+	for _, adjustment := range adjustments {
+		i := indexOfPackSize(adjusted, adjustment.MaxItems)
+		if i == -1 {
+			return nil, fmt.Errorf("%w: %d", services.ErrUnknownPackSize, adjustment.MaxItems)
+		}
+
+		if adjusted[i].Stock == nil {
+			return nil, fmt.Errorf("%w: %d", services.ErrUnlimitedStock, adjustment.MaxItems)
+		}
+
+		if *adjusted[i].Stock+adjustment.Delta < 0 {
+			return nil, fmt.Errorf("%w: %d", services.ErrInsufficientStock, adjustment.MaxItems)
+		}
+
+		*adjusted[i].Stock += adjustment.Delta
+	}
+
+	return adjusted, nil
+}
+
+// read reads the pack sizes file, upgrading older files to the current schema
+func (p *FilePackSizeProvider) read() (packSizeFile, error) {
+	data, err := os.ReadFile(p.filePath)
+	if err != nil {
+		return packSizeFile{Version: packSizeFileVersion, PackSizes: []models.PackSize{}}, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return p.format.unmarshal(data)
+}
+
+// modify changes the pack sizes file and writes it using the current schema, a missing file is created.
+// Writers are serialised by a lock within the process and an advisory lock on a lock file next to the pack
+// sizes file, so processes sharing the file do not overwrite each other's changes. The file is replaced
+// atomically, readers never see a partly written file, and the previous contents are kept in the backup file.
+// Nothing is written if the change fails.
+func (p *FilePackSizeProvider) modify(change func(file *packSizeFile) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	unlock, err := lockFile(p.filePath + lockFileSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to lock file: %w", err)
+	}
+	defer unlock()
+
+	previous, err := os.ReadFile(p.filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+
+	file, err := p.format.unmarshal(previous)
+	if err != nil {
+		return err
+	}
+
+	if err := change(&file); err != nil {
+		return err
+	}
+
+	file.Version = packSizeFileVersion
+	data, err := p.format.marshal(file, previous)
+	if err != nil {
+		return err
+	}
+
+	if len(bytes.TrimSpace(previous)) > 0 {
+		if err := writeFileAtomic(p.filePath+backupFileSuffix, previous); err != nil {
+			return fmt.Errorf("failed to back up file: %w", err)
+		}
+	}
+
+	if err := writeFileAtomic(p.filePath, data); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+// Recover restores the pack sizes file from its backup when the file is missing, empty or cannot be read,
+// e.g. because it was edited by hand or truncated by a crash while it was written in place. Temporary files
+// left behind by interrupted writes are removed. It reports whether the file was restored and should be
+// called before the provider is used.
+func (p *FilePackSizeProvider) Recover() (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	unlock, err := lockFile(p.filePath + lockFileSuffix)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock file: %w", err)
+	}
+	defer unlock()
+
+	// Interrupted writes of the backup leave temporary files of the backup behind
+	for _, pattern := range []string{p.filePath + temporaryFilePattern, p.filePath + backupFileSuffix + temporaryFilePattern} {
+		temporary, err := filepath.Glob(pattern)
+		if err != nil {
+			return false, fmt.Errorf("failed to find temporary files: %w", err)
+		}
+		for _, name := range temporary {
+			if err := os.Remove(name); err != nil {
+				return false, fmt.Errorf("failed to remove temporary file: %w", err)
+			}
+		}
+	}
+
+	data, err := os.ReadFile(p.filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed to open file: %w", err)
+	}
+	if _, parseErr := p.format.unmarshal(data); err == nil && parseErr == nil && len(bytes.TrimSpace(data)) > 0 {
+		return false, nil
+	}
+
+	backup, err := os.ReadFile(p.filePath + backupFileSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open backup file: %w", err)
+	}
+
+	if _, err := p.format.unmarshal(backup); err != nil {
+		return false, fmt.Errorf("failed to read backup file: %w", err)
+	}
+
+	if err := writeFileAtomic(p.filePath, backup); err != nil {
+		return false, fmt.Errorf("failed to restore file: %w", err)
+	}
+
+	return true, nil
+}
+
+// writeFileAtomic replaces the file with the data by writing a temporary file next to it, syncing it to disk
+// and renaming it over the file, so the file either has its old or its new contents after a crash
+func writeFileAtomic(name string, data []byte) error {
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(name)
+	f, err := os.CreateTemp(dir, filepath.Base(name)+temporaryFilePattern)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.Name(), name); err != nil {
+		return err
+	}
+
+	// The rename only survives a crash once the directory is synced
+	return syncDir(dir)
+}
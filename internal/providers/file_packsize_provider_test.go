@@ -0,0 +1,640 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/providers"
+	"github.com/cybre/order-packing/internal/services"
+)
+
+// fileFormats are the file formats every FilePackSizeProvider test runs against
+var fileFormats = []struct {
+	name         string
+	extension    string
+	keepsHistory bool
+	corrupted    string
+}{
+	{name: "JSON", extension: ".json", keepsHistory: true, corrupted: `{"version": 4, "packSi`},
+	{name: "YAML", extension: ".yaml", keepsHistory: true, corrupted: "packSizes:\n  - maxItems: [250"},
+	{name: "TOML", extension: ".toml", keepsHistory: true, corrupted: "[[packSizes]]\nmaxItems = "},
+	{name: "CSV", extension: ".csv", corrupted: "sku,maxItems\n\"SKU-1,250"},
+}
+
+// newFilePackSizeProvider returns a FilePackSizeProvider for a new file with the extension in a temporary
+// directory and the path of the file, the default pack sizes are set when there are any
+func newFilePackSizeProvider(t *testing.T, extension string, packSizes []models.PackSize) (*providers.FilePackSizeProvider, string) {
+	t.Helper()
+
+	name := filepath.Join(t.TempDir(), "test_pack_sizes"+extension)
+	p := providers.NewFilePackSizeProvider(name)
+	if packSizes != nil {
+		if err := p.Update(context.Background(), "", packSizes); err != nil {
+			t.Fatalf("failed to update pack sizes: %v", err)
+		}
+	}
+
+	return p, name
+}
+
+func TestFilePackSizeProvider_GetPackSizes(t *testing.T) {
+	testCases := []struct {
+		name                    string
+		fileName                string
+		contents                string
+		expectedPackSizes       []models.PackSize
+		expectedProductPackSize []models.PackSize
+	}{
+		{
+			name:                    "JSON version 1",
+			fileName:                "packsizes.json",
+			contents:                `[{"maxItems": 10}, {"maxItems": 20}, {"maxItems": 30}]`,
+			expectedPackSizes:       []models.PackSize{{MaxItems: 10}, {MaxItems: 20}, {MaxItems: 30}},
+			expectedProductPackSize: []models.PackSize{{MaxItems: 10}, {MaxItems: 20}, {MaxItems: 30}},
+		},
+		{
+			name:                    "JSON version 2",
+			fileName:                "packsizes.json",
+			contents:                `{"version": 2, "packSizes": [{"maxItems": 250}], "products": {"SKU-1": [{"maxItems": 6, "cost": 1.5}]}}`,
+			expectedPackSizes:       []models.PackSize{{MaxItems: 250}},
+			expectedProductPackSize: []models.PackSize{{MaxItems: 6, Cost: 1.5}},
+		},
+		{
+			name:                    "JSON without an extension",
+			fileName:                "packsizes",
+			contents:                `{"version": 4, "packSizes": [{"maxItems": 250}]}`,
+			expectedPackSizes:       []models.PackSize{{MaxItems: 250}},
+			expectedProductPackSize: []models.PackSize{{MaxItems: 250}},
+		},
+		{
+			name:     "YAML",
+			fileName: "packsizes.yaml",
+			contents: `# Pack sizes
+version: 4
+packSizes:
+  - maxItems: 250
+  - maxItems: 500
+    maxWeight: 12.5
+products:
+  SKU-1:
+    - maxItems: 6
+      stock: 3
+`,
+			expectedPackSizes:       []models.PackSize{{MaxItems: 250}, {MaxItems: 500, MaxWeight: 12.5}},
+			expectedProductPackSize: []models.PackSize{{MaxItems: 6, Stock: intPtr(3)}},
+		},
+		{
+			name:                    "YAML version 1",
+			fileName:                "packsizes.yml",
+			contents:                "- maxItems: 250\n- maxItems: 500\n",
+			expectedPackSizes:       []models.PackSize{{MaxItems: 250}, {MaxItems: 500}},
+			expectedProductPackSize: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}},
+		},
+		{
+			name:     "TOML",
+			fileName: "packsizes.toml",
+			contents: `# Pack sizes
+version = 4
+
+[[packSizes]]
+maxItems = 250
+
+[[packSizes]]
+maxItems = 500
+cost = 2.5
+
+[[products.SKU-1]]
+maxItems = 6
+stock = 3
+`,
+			expectedPackSizes:       []models.PackSize{{MaxItems: 250}, {MaxItems: 500, Cost: 2.5}},
+			expectedProductPackSize: []models.PackSize{{MaxItems: 6, Stock: intPtr(3)}},
+		},
+		{
+			name:     "CSV",
+			fileName: "packsizes.csv",
+			contents: "\xef\xbb\xbf# Pack sizes\nmaxItems,sku,stock,cost\n250,,,\n500,, ,2.5\n6,SKU-1,3,\n12,SKU-1,,\n",
+			expectedPackSizes: []models.PackSize{
+				{MaxItems: 250},
+				{MaxItems: 500, Cost: 2.5},
+			},
+			expectedProductPackSize: []models.PackSize{{MaxItems: 6, Stock: intPtr(3)}, {MaxItems: 12}},
+		},
+		{
+			name:                    "empty YAML",
+			fileName:                "packsizes.yaml",
+			expectedPackSizes:       []models.PackSize{},
+			expectedProductPackSize: []models.PackSize{},
+		},
+		{
+			name:                    "empty TOML",
+			fileName:                "packsizes.toml",
+			expectedPackSizes:       []models.PackSize{},
+			expectedProductPackSize: []models.PackSize{},
+		},
+		{
+			name:                    "empty CSV",
+			fileName:                "packsizes.csv",
+			expectedPackSizes:       []models.PackSize{},
+			expectedProductPackSize: []models.PackSize{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			name := filepath.Join(t.TempDir(), tc.fileName)
+			if err := os.WriteFile(name, []byte(tc.contents), 0o644); err != nil {
+				t.Fatalf("failed to write test data to file: %v", err)
+			}
+			p := providers.NewFilePackSizeProvider(name)
+
+			// Act
+			packSizes, err := p.GetPackSizes(context.Background(), "")
+			if err != nil {
+				t.Fatalf("failed to get pack sizes: %v", err)
+			}
+			productPackSizes, err := p.GetPackSizes(context.Background(), "SKU-1")
+			if err != nil {
+				t.Fatalf("failed to get pack sizes: %v", err)
+			}
+
+			// Assert
+			if !reflect.DeepEqual(packSizes, tc.expectedPackSizes) {
+				t.Fatalf("unexpected pack sizes, got %+v, want %+v", packSizes, tc.expectedPackSizes)
+			}
+			if !reflect.DeepEqual(productPackSizes, tc.expectedProductPackSize) {
+				t.Fatalf("unexpected product pack sizes, got %+v, want %+v", productPackSizes, tc.expectedProductPackSize)
+			}
+		})
+	}
+}
+
+func TestFilePackSizeProvider_Update(t *testing.T) {
+	for _, tc := range fileFormats {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			p, name := newFilePackSizeProvider(t, tc.extension, nil)
+			stock := 0
+			packSizes := []models.PackSize{{MaxItems: 10, Cost: 1.25}, {MaxItems: 20, Stock: &stock}, {MaxItems: 30, MaxWeight: 2, MaxVolume: 0.5}}
+			productPackSizes := []models.PackSize{{MaxItems: 6}, {MaxItems: 12}}
+
+			// Act
+			if err := p.Update(context.Background(), "", packSizes); err != nil {
+				t.Fatalf("failed to update pack sizes: %v", err)
+			}
+			if err := p.Update(context.Background(), "SKU-1", productPackSizes); err != nil {
+				t.Fatalf("failed to update pack sizes: %v", err)
+			}
+			// Products with empty pack sizes must not fall back to the default pack sizes once read again
+			if err := p.Update(context.Background(), "SKU-3", []models.PackSize{}); err != nil {
+				t.Fatalf("failed to update pack sizes: %v", err)
+			}
+
+			// Assert
+			// A new provider reads the file as another process would
+			reader := providers.NewFilePackSizeProvider(name)
+			expectedPackSizes := map[string][]models.PackSize{
+				"":      packSizes,
+				"SKU-1": productPackSizes,
+				"SKU-2": packSizes,
+				"SKU-3": {},
+			}
+			for sku, expected := range expectedPackSizes {
+				packSizes, err := reader.GetPackSizes(context.Background(), sku)
+				if err != nil {
+					t.Fatalf("failed to get pack sizes for %q: %v", sku, err)
+				}
+				if !reflect.DeepEqual(packSizes, expected) {
+					t.Fatalf("unexpected pack sizes for %q, got %+v, want %+v", sku, packSizes, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestFilePackSizeProvider_AdjustStock(t *testing.T) {
+	for _, tc := range fileFormats {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			p, _ := newFilePackSizeProvider(t, tc.extension, []models.PackSize{{MaxItems: 250}, {MaxItems: 500, Stock: intPtr(3)}})
+
+			// Act
+			if err := p.AdjustStock(context.Background(), "SKU-1", []models.StockAdjustment{{MaxItems: 500, Delta: -2}}); err != nil {
+				t.Fatalf("failed to adjust stock: %v", err)
+			}
+			errs := []error{
+				p.AdjustStock(context.Background(), "", []models.StockAdjustment{{MaxItems: 500, Delta: 5}, {MaxItems: 500, Delta: -7}}),
+				p.AdjustStock(context.Background(), "", []models.StockAdjustment{{MaxItems: 250, Delta: 1}}),
+				p.AdjustStock(context.Background(), "", []models.StockAdjustment{{MaxItems: 1000, Delta: 1}}),
+			}
+
+			// Assert
+			expectedErrs := []error{services.ErrInsufficientStock, services.ErrUnlimitedStock, services.ErrUnknownPackSize}
+			for i, err := range errs {
+				if !errors.Is(err, expectedErrs[i]) {
+					t.Fatalf("unexpected error for adjustment %d, got %v, want %v", i, err, expectedErrs[i])
+				}
+			}
+
+			packSizes, err := p.GetPackSizes(context.Background(), "")
+			if err != nil {
+				t.Fatalf("failed to get pack sizes: %v", err)
+			}
+			expectedPackSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500, Stock: intPtr(1)}}
+			if !reflect.DeepEqual(packSizes, expectedPackSizes) {
+				t.Fatalf("unexpected pack sizes, got %+v, want %+v", packSizes, expectedPackSizes)
+			}
+		})
+	}
+}
+
+func TestFilePackSizeProvider_CompareAndSwap(t *testing.T) {
+	for _, tc := range fileFormats {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			p, _ := newFilePackSizeProvider(t, tc.extension, []models.PackSize{{MaxItems: 250}, {MaxItems: 500}})
+			version := models.PackSizesVersion([]models.PackSize{{MaxItems: 500}, {MaxItems: 250}})
+
+			// Act
+			productPackSizes := []models.PackSize{{MaxItems: 6}, {MaxItems: 12}}
+			if err := p.CompareAndSwap(context.Background(), "SKU-1", version, productPackSizes); err != nil {
+				t.Fatalf("failed to swap pack sizes: %v", err)
+			}
+			err := p.CompareAndSwap(context.Background(), "SKU-1", version, []models.PackSize{{MaxItems: 24}})
+
+			// Assert
+			if !errors.Is(err, services.ErrVersionConflict) {
+				t.Fatalf("unexpected error swapping outdated pack sizes, got %v, want %v", err, services.ErrVersionConflict)
+			}
+
+			packSizes, err := p.GetPackSizes(context.Background(), "SKU-1")
+			if err != nil {
+				t.Fatalf("failed to get pack sizes: %v", err)
+			}
+			if !reflect.DeepEqual(packSizes, productPackSizes) {
+				t.Fatalf("unexpected pack sizes, got %+v, want %+v", packSizes, productPackSizes)
+			}
+		})
+	}
+}
+
+func TestFilePackSizeProvider_History(t *testing.T) {
+	for _, tc := range fileFormats {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			p, _ := newFilePackSizeProvider(t, tc.extension, []models.PackSize{{MaxItems: 250}})
+			createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			// Act
+			var addErr error
+			for i := 1; i <= 102 && addErr == nil; i++ {
+				version := models.PackSizeSetVersion{SKU: "SKU-1", Author: "admin", CreatedAt: createdAt, PackSizes: []models.PackSize{{MaxItems: i}}}
+				var saved models.PackSizeSetVersion
+				saved, addErr = p.AddPackSizeVersion(context.Background(), version)
+				if addErr == nil && saved.ID != i {
+					t.Fatalf("unexpected version ID, got %d, want %d", saved.ID, i)
+				}
+			}
+
+			// Assert
+			if !tc.keepsHistory {
+				if !errors.Is(addErr, services.ErrHistoryUnavailable) {
+					t.Fatalf("unexpected error adding a version, got %v, want %v", addErr, services.ErrHistoryUnavailable)
+				}
+				if _, err := p.PackSizeVersions(context.Background(), "SKU-1"); !errors.Is(err, services.ErrHistoryUnavailable) {
+					t.Fatalf("unexpected error getting versions, got %v, want %v", err, services.ErrHistoryUnavailable)
+				}
+				return
+			}
+			if addErr != nil {
+				t.Fatalf("failed to add pack size version: %v", addErr)
+			}
+
+			versions, err := p.PackSizeVersions(context.Background(), "SKU-1")
+			if err != nil {
+				t.Fatalf("failed to get pack size versions: %v", err)
+			}
+			if len(versions) != 100 || versions[0].ID != 102 || versions[99].ID != 3 {
+				t.Fatalf("unexpected versions, got %d versions from %d to %d, want 100 versions from 102 to 3", len(versions), versions[0].ID, versions[len(versions)-1].ID)
+			}
+
+			version, err := p.PackSizeVersion(context.Background(), "SKU-1", 50)
+			if err != nil {
+				t.Fatalf("failed to get pack size version: %v", err)
+			}
+			expectedVersion := models.PackSizeSetVersion{ID: 50, SKU: "SKU-1", Author: "admin", CreatedAt: createdAt, PackSizes: []models.PackSize{{MaxItems: 50}}}
+			if !version.CreatedAt.Equal(createdAt) {
+				t.Fatalf("unexpected version time, got %v, want %v", version.CreatedAt, createdAt)
+			}
+			version.CreatedAt = createdAt
+			if !reflect.DeepEqual(version, expectedVersion) {
+				t.Fatalf("unexpected version, got %+v, want %+v", version, expectedVersion)
+			}
+
+			for _, id := range []int{2, 103} {
+				if _, err := p.PackSizeVersion(context.Background(), "SKU-1", id); !errors.Is(err, services.ErrUnknownVersion) {
+					t.Fatalf("unexpected error for version %d, got %v, want %v", id, err, services.ErrUnknownVersion)
+				}
+			}
+
+			defaultVersions, err := p.PackSizeVersions(context.Background(), "")
+			if err != nil {
+				t.Fatalf("failed to get pack size versions: %v", err)
+			}
+			if len(defaultVersions) != 0 {
+				t.Fatalf("unexpected versions of the default pack sizes, got %+v", defaultVersions)
+			}
+		})
+	}
+}
+
+func TestFilePackSizeProvider_Schedule(t *testing.T) {
+	for _, tc := range fileFormats {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			p, _ := newFilePackSizeProvider(t, tc.extension, []models.PackSize{{MaxItems: 250}})
+			from := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			// Act
+			var scheduleErr error
+			for _, days := range []int{2, 1, 3} {
+				scheduled := models.ScheduledPackSizes{SKU: "SKU-1", EffectiveFrom: from.AddDate(0, 0, days), PackSizes: []models.PackSize{{MaxItems: days}}}
+				if _, scheduleErr = p.SchedulePackSizes(context.Background(), scheduled); scheduleErr != nil {
+					break
+				}
+			}
+
+			// Assert
+			if !tc.keepsHistory {
+				if !errors.Is(scheduleErr, services.ErrScheduleUnavailable) {
+					t.Fatalf("unexpected error scheduling pack sizes, got %v, want %v", scheduleErr, services.ErrScheduleUnavailable)
+				}
+				if _, err := p.ScheduledPackSizes(context.Background(), "SKU-1"); !errors.Is(err, services.ErrScheduleUnavailable) {
+					t.Fatalf("unexpected error getting scheduled pack sizes, got %v, want %v", err, services.ErrScheduleUnavailable)
+				}
+				return
+			}
+			if scheduleErr != nil {
+				t.Fatalf("failed to schedule pack sizes: %v", scheduleErr)
+			}
+
+			if err := p.UnschedulePackSizes(context.Background(), "SKU-1", 1); err != nil {
+				t.Fatalf("failed to unschedule pack sizes: %v", err)
+			}
+			scheduled, err := p.SchedulePackSizes(context.Background(), models.ScheduledPackSizes{SKU: "SKU-1", EffectiveFrom: from, PackSizes: []models.PackSize{{MaxItems: 6}}})
+			if err != nil {
+				t.Fatalf("failed to schedule pack sizes: %v", err)
+			}
+			if scheduled.ID != 4 {
+				t.Fatalf("unexpected scheduled pack sizes ID, got %d, want %d", scheduled.ID, 4)
+			}
+
+			schedule, err := p.ScheduledPackSizes(context.Background(), "SKU-1")
+			if err != nil {
+				t.Fatalf("failed to get scheduled pack sizes: %v", err)
+			}
+			ids := make([]int, len(schedule))
+			for i, scheduled := range schedule {
+				ids[i] = scheduled.ID
+			}
+			if expectedIDs := []int{4, 2, 3}; !reflect.DeepEqual(ids, expectedIDs) {
+				t.Fatalf("unexpected scheduled pack sizes, got IDs %v, want %v", ids, expectedIDs)
+			}
+			if !schedule[0].EffectiveFrom.Equal(from) {
+				t.Fatalf("unexpected effective time, got %v, want %v", schedule[0].EffectiveFrom, from)
+			}
+
+			if err := p.UnschedulePackSizes(context.Background(), "SKU-1", 1); !errors.Is(err, services.ErrUnknownSchedule) {
+				t.Fatalf("unexpected error unscheduling removed pack sizes, got %v, want %v", err, services.ErrUnknownSchedule)
+			}
+
+			defaultSchedule, err := p.ScheduledPackSizes(context.Background(), "")
+			if err != nil {
+				t.Fatalf("failed to get scheduled pack sizes: %v", err)
+			}
+			if len(defaultSchedule) != 0 {
+				t.Fatalf("unexpected scheduled default pack sizes, got %+v", defaultSchedule)
+			}
+		})
+	}
+}
+
+func TestFilePackSizeProvider_KeepsComments(t *testing.T) {
+	testCases := []struct {
+		name             string
+		fileName         string
+		contents         string
+		expectedComments []string
+	}{
+		{
+			name:     "YAML",
+			fileName: "packsizes.yaml",
+			contents: `# Pack sizes maintained by the packaging team
+version: 4
+packSizes:
+  # The smallest box
+  - maxItems: 250
+  - maxItems: 500 # The pallet
+products:
+  # Fragile products
+  SKU-1:
+    - maxItems: 6
+`,
+			expectedComments: []string{"# Pack sizes maintained by the packaging team", "# The smallest box", "- maxItems: 500 # The pallet", "# Fragile products"},
+		},
+		{
+			name:     "YAML version 1",
+			fileName: "packsizes.yaml",
+			contents: `# Pack sizes maintained by the packaging team
+- maxItems: 250 # The smallest box
+- maxItems: 500
+`,
+			expectedComments: []string{"# Pack sizes maintained by the packaging team", "- maxItems: 250 # The smallest box"},
+		},
+		{
+			name:     "TOML",
+			fileName: "packsizes.toml",
+			contents: `# Pack sizes maintained by the packaging team
+
+version = 4
+
+# The smallest box
+[[packSizes]]
+maxItems = 250
+
+[[packSizes]]
+maxItems = 500 # The pallet
+
+# Fragile products
+[[products.SKU-1]]
+maxItems = 6
+
+# End of pack sizes
+`,
+			expectedComments: []string{"# Pack sizes maintained by the packaging team", "# The smallest box", "maxItems = 500 # The pallet", "# Fragile products", "# End of pack sizes"},
+		},
+		{
+			name:             "CSV",
+			fileName:         "packsizes.csv",
+			contents:         "# Pack sizes maintained by the packaging team\nsku,maxItems\n,250\n,500\nSKU-1,6\n",
+			expectedComments: []string{"# Pack sizes maintained by the packaging team"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			name := filepath.Join(t.TempDir(), tc.fileName)
+			if err := os.WriteFile(name, []byte(tc.contents), 0o644); err != nil {
+				t.Fatalf("failed to write test data to file: %v", err)
+			}
+			p := providers.NewFilePackSizeProvider(name)
+
+			// Act
+			if err := p.Update(context.Background(), "", []models.PackSize{{MaxItems: 100}, {MaxItems: 250}, {MaxItems: 500}}); err != nil {
+				t.Fatalf("failed to update pack sizes: %v", err)
+			}
+
+			// Assert
+			data, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			for _, comment := range tc.expectedComments {
+				if !strings.Contains(string(data), comment) {
+					t.Fatalf("expected the file to keep %q, got:\n%s", comment, data)
+				}
+			}
+			if strings.Index(string(data), tc.expectedComments[0]) != 0 {
+				t.Fatalf("expected the file to start with %q, got:\n%s", tc.expectedComments[0], data)
+			}
+
+			packSizes, err := p.GetPackSizes(context.Background(), "")
+			if err != nil {
+				t.Fatalf("failed to get pack sizes: %v", err)
+			}
+			expectedPackSizes := []models.PackSize{{MaxItems: 100}, {MaxItems: 250}, {MaxItems: 500}}
+			if !reflect.DeepEqual(packSizes, expectedPackSizes) {
+				t.Fatalf("unexpected pack sizes, got %+v, want %+v", packSizes, expectedPackSizes)
+			}
+		})
+	}
+}
+
+func TestFilePackSizeProvider_ConcurrentWrites(t *testing.T) {
+	for _, tc := range fileFormats {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			_, name := newFilePackSizeProvider(t, tc.extension, []models.PackSize{{MaxItems: 250}, {MaxItems: 500, Stock: intPtr(0)}})
+
+			// Create two instances of FilePackSizeProvider, as two processes sharing the file would
+			instances := []*providers.FilePackSizeProvider{providers.NewFilePackSizeProvider(name), providers.NewFilePackSizeProvider(name)}
+			const writers = 50
+
+			// Act
+			var wg sync.WaitGroup
+			errs := make(chan error, 2*writers)
+			for i := 0; i < writers; i++ {
+				wg.Add(2)
+				go func(p *providers.FilePackSizeProvider) {
+					defer wg.Done()
+					errs <- p.AdjustStock(context.Background(), "", []models.StockAdjustment{{MaxItems: 500, Delta: 1}})
+				}(instances[i%2])
+				go func(p *providers.FilePackSizeProvider) {
+					defer wg.Done()
+					_, err := p.GetPackSizes(context.Background(), "")
+					errs <- err
+				}(instances[(i+1)%2])
+			}
+			wg.Wait()
+			close(errs)
+
+			// Assert
+			for err := range errs {
+				if err != nil {
+					t.Fatalf("unexpected error while writing concurrently: %v", err)
+				}
+			}
+
+			packSizes, err := instances[0].GetPackSizes(context.Background(), "")
+			if err != nil {
+				t.Fatalf("failed to get pack sizes: %v", err)
+			}
+			if stock := packSizes[1].Stock; stock == nil || *stock != writers {
+				t.Fatalf("unexpected stock, got %v, want %d", stock, writers)
+			}
+		})
+	}
+}
+
+func TestFilePackSizeProvider_Recover(t *testing.T) {
+	for _, tc := range fileFormats {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			first := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}
+			p, name := newFilePackSizeProvider(t, tc.extension, first)
+			if err := p.Update(context.Background(), "", []models.PackSize{{MaxItems: 1000}}); err != nil {
+				t.Fatalf("failed to update pack sizes: %v", err)
+			}
+
+			// Corrupt the file as an interrupted write would, and leave temporary files of the file and its backup behind
+			if err := os.WriteFile(name, []byte(tc.corrupted), 0o644); err != nil {
+				t.Fatalf("failed to corrupt file: %v", err)
+			}
+			if _, err := p.GetPackSizes(context.Background(), ""); err == nil {
+				t.Fatalf("expected the corrupted file not to be readable")
+			}
+			temporary := []string{name + ".tmp-123", name + ".bak.tmp-456"}
+			for _, temporaryName := range temporary {
+				if err := os.WriteFile(temporaryName, []byte(`{`), 0o644); err != nil {
+					t.Fatalf("failed to write temporary file: %v", err)
+				}
+			}
+
+			// Act
+			restored, err := p.Recover()
+			if err != nil {
+				t.Fatalf("failed to recover file: %v", err)
+			}
+
+			// Assert
+			if !restored {
+				t.Fatalf("expected the file to be restored from its backup")
+			}
+
+			packSizes, err := p.GetPackSizes(context.Background(), "")
+			if err != nil {
+				t.Fatalf("failed to get pack sizes: %v", err)
+			}
+			if !reflect.DeepEqual(packSizes, first) {
+				t.Fatalf("unexpected pack sizes, got %+v, want %+v", packSizes, first)
+			}
+
+			for _, temporaryName := range temporary {
+				if _, err := os.Stat(temporaryName); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("expected the temporary file %s to be removed, got %v", temporaryName, err)
+				}
+			}
+
+			restored, err = p.Recover()
+			if err != nil || restored {
+				t.Fatalf("expected a readable file not to be restored, got %v, %v", restored, err)
+			}
+		})
+	}
+}
+
+// intPtr returns a pointer to the value
+func intPtr(value int) *int {
+	return &value
+}
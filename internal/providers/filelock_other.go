@@ -0,0 +1,14 @@
+//go:build !unix
+
+package providers
+
+// lockFile does not lock anything on platforms without advisory file locks, writers in other processes
+// are not serialised
+func lockFile(name string) (func(), error) {
+	return func() {}, nil
+}
+
+// syncDir does nothing on platforms where directories cannot be synced
+func syncDir(name string) error {
+	return nil
+}
@@ -0,0 +1,458 @@
+package providers
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
+)
+
+// The settings of an HTTPPackSizeProvider that are not configured otherwise
+const (
+	DefaultHTTPTimeout          = 5 * time.Second
+	DefaultHTTPRetries          = 2
+	DefaultHTTPBackoff          = 100 * time.Millisecond
+	DefaultHTTPFreshFor         = 30 * time.Second
+	DefaultHTTPFailureThreshold = 5
+	DefaultHTTPOpenFor          = 30 * time.Second
+	DefaultHTTPCacheSize        = 10_000
+)
+
+// ErrCatalogUnavailable is returned when the catalog failed too many times in a row and is not asked
+// for pack sizes until it has had time to recover
+var ErrCatalogUnavailable = fmt.Errorf("pack size catalog is unavailable")
+
+// errProviderClosed is returned when pack sizes are read from the catalog after the provider was closed
+var errProviderClosed = fmt.Errorf("pack size provider is closed")
+
+// HTTPPackSizeProvider is a read-only PackSizeProvider reading pack sizes from a catalog service over HTTP.
+// The catalog serves the default pack sizes at /pack-sizes and the pack sizes of a product at
+// /products/{sku}/pack-sizes, responding with 404 Not Found for products without pack sizes of their own.
+//
+// Pack sizes are served from memory once read. When they are older than the fresh period they are still served
+// while they are read again in the background, so the last known pack sizes are used for as long as the catalog
+// is down. The pack sizes of a limited number of SKUs are kept, the least recently used are dropped first.
+// SKUs without pack sizes of their own only remember that they use the default pack sizes, which are kept once.
+// Concurrent reads of the same SKU share a single request to the catalog. Failed requests are retried with
+// exponential backoff, and the catalog is not asked at all for a while after too many failures in a row.
+type HTTPPackSizeProvider struct {
+	baseURL          string
+	client           *http.Client
+	retries          int
+	backoff          time.Duration
+	freshFor         time.Duration
+	failureThreshold int
+	openFor          time.Duration
+	cacheSize        int
+	now              func() time.Time
+	// ctx is canceled when the provider is closed, ending the requests to the catalog
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+	// fetches holds the requests to the catalog in flight, by SKU
+	fetches map[string]*catalogFetch
+	// cache holds the elements of recent, ordered from the most to the least recently used, by SKU
+	cache     map[string]*list.Element
+	recent    *list.List
+	failures  int
+	openUntil time.Time
+}
+
+// catalogPackSizes are the pack sizes of a SKU read from the catalog
+type catalogPackSizes struct {
+	sku       string
+	packSizes []models.PackSize
+	// usesDefaults marks SKUs without pack sizes of their own, whose pack sizes are those of the empty SKU
+	usesDefaults bool
+	fetchedAt    time.Time
+}
+
+// catalogFetch is a read of the pack sizes of a SKU from the catalog, shared by every read of the SKU meanwhile
+type catalogFetch struct {
+	// done is closed once the read is over
+	done         chan struct{}
+	packSizes    []models.PackSize
+	usesDefaults bool
+	err          error
+}
+
+// HTTPOption configures an HTTPPackSizeProvider
+type HTTPOption func(*HTTPPackSizeProvider)
+
+// WithHTTPClient sets the client the catalog is requested with
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(p *HTTPPackSizeProvider) {
+		p.client = client
+	}
+}
+
+// WithHTTPTimeout sets how long a single request to the catalog may take, 5 seconds by default
+func WithHTTPTimeout(timeout time.Duration) HTTPOption {
+	return func(p *HTTPPackSizeProvider) {
+		client := *p.client
+		client.Timeout = timeout
+		p.client = &client
+	}
+}
+
+// WithHTTPRetries sets how many times a failed request is retried and how long to wait before the first retry,
+// the wait doubles with every retry. Failed requests are retried twice after 100 milliseconds by default.
+func WithHTTPRetries(retries int, backoff time.Duration) HTTPOption {
+	return func(p *HTTPPackSizeProvider) {
+		p.retries = retries
+		p.backoff = backoff
+	}
+}
+
+// WithHTTPFreshFor sets how long pack sizes are served before they are read again, 30 seconds by default
+func WithHTTPFreshFor(freshFor time.Duration) HTTPOption {
+	return func(p *HTTPPackSizeProvider) {
+		p.freshFor = freshFor
+	}
+}
+
+// WithHTTPCircuitBreaker sets after how many failed reads in a row the catalog is not asked for pack sizes,
+// and for how long. The catalog is left alone for 30 seconds after 5 failures by default, 0 failures never
+// leave it alone.
+func WithHTTPCircuitBreaker(failures int, openFor time.Duration) HTTPOption {
+	return func(p *HTTPPackSizeProvider) {
+		p.failureThreshold = failures
+		p.openFor = openFor
+	}
+}
+
+// WithHTTPCacheSize sets the number of SKUs whose pack sizes are kept, 10000 by default
+func WithHTTPCacheSize(size int) HTTPOption {
+	return func(p *HTTPPackSizeProvider) {
+		p.cacheSize = size
+	}
+}
+
+// WithHTTPClock sets the function telling the current time, time.Now by default
+func WithHTTPClock(now func() time.Time) HTTPOption {
+	return func(p *HTTPPackSizeProvider) {
+		p.now = now
+	}
+}
+
+// NewHTTPPackSizeProvider returns a new HTTPPackSizeProvider reading pack sizes from the catalog at the base URL
+func NewHTTPPackSizeProvider(baseURL string, opts ...HTTPOption) *HTTPPackSizeProvider {
+	p := &HTTPPackSizeProvider{
+		baseURL:          strings.TrimSuffix(baseURL, "/"),
+		client:           &http.Client{Timeout: DefaultHTTPTimeout},
+		retries:          DefaultHTTPRetries,
+		backoff:          DefaultHTTPBackoff,
+		freshFor:         DefaultHTTPFreshFor,
+		failureThreshold: DefaultHTTPFailureThreshold,
+		openFor:          DefaultHTTPOpenFor,
+		cacheSize:        DefaultHTTPCacheSize,
+		now:              time.Now,
+		fetches:          map[string]*catalogFetch{},
+		cache:            map[string]*list.Element{},
+		recent:           list.New(),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// GetPackSizes returns the pack sizes for the specified SKU from the catalog, the last known pack sizes are
+// returned while they are read again
+func (p *HTTPPackSizeProvider) GetPackSizes(ctx context.Context, sku string) ([]models.PackSize, error) {
+	packSizes, usesDefaults, stale, ok := p.cached(sku)
+	if stale {
+		p.start(sku)
+	}
+
+	if !ok {
+		fetch := p.start(sku)
+		select {
+		case <-fetch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if fetch.err != nil {
+			return nil, fetch.err
+		}
+		packSizes, usesDefaults = fetch.packSizes, fetch.usesDefaults
+	}
+
+	if usesDefaults {
+		return p.GetPackSizes(ctx, "")
+	}
+
+	return slices.Clone(packSizes), nil
+}
+
+// cached returns the last known pack sizes for the specified SKU, whether the SKU uses the default pack sizes
+// and whether they are stale. It returns false when they are not known.
+func (p *HTTPPackSizeProvider) cached(sku string) ([]models.PackSize, bool, bool, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	element, ok := p.cache[sku]
+	if !ok {
+		return nil, false, false, false
+	}
+	p.recent.MoveToFront(element)
+
+	cached := element.Value.(*catalogPackSizes)
+
+	return cached.packSizes, cached.usesDefaults, p.now().Sub(cached.fetchedAt) >= p.freshFor, true
+}
+
+// start reads the pack sizes for the specified SKU from the catalog in the background, unless they are already
+// being read, and returns the read. The pack sizes read are kept, the last known pack sizes are kept when the
+// read fails. Reads end when the provider is closed.
+func (p *HTTPPackSizeProvider) start(sku string) *catalogFetch {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if fetch, ok := p.fetches[sku]; ok {
+		return fetch
+	}
+
+	fetch := &catalogFetch{done: make(chan struct{})}
+	if p.closed {
+		fetch.err = errProviderClosed
+		close(fetch.done)
+		return fetch
+	}
+
+	p.fetches[sku] = fetch
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		fetch.packSizes, fetch.usesDefaults, fetch.err = p.fetch(p.ctx, sku)
+		if fetch.err == nil {
+			p.store(sku, fetch.packSizes, fetch.usesDefaults)
+		}
+
+		p.mu.Lock()
+		delete(p.fetches, sku)
+		p.mu.Unlock()
+		close(fetch.done)
+	}()
+
+	return fetch
+}
+
+// store keeps the pack sizes for the specified SKU, dropping the least recently used SKU when too many are kept.
+// The default pack sizes are never dropped, every SKU without pack sizes of its own uses them.
+func (p *HTTPPackSizeProvider) store(sku string, packSizes []models.PackSize, usesDefaults bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if element, ok := p.cache[sku]; ok {
+		cached := element.Value.(*catalogPackSizes)
+		cached.packSizes, cached.usesDefaults, cached.fetchedAt = packSizes, usesDefaults, p.now()
+		p.recent.MoveToFront(element)
+		return
+	}
+
+	p.cache[sku] = p.recent.PushFront(&catalogPackSizes{sku: sku, packSizes: packSizes, usesDefaults: usesDefaults, fetchedAt: p.now()})
+
+	for element := p.recent.Back(); element != nil && len(p.cache) > max(p.cacheSize, 1); {
+		previous := element.Prev()
+		if evicted := element.Value.(*catalogPackSizes); evicted.sku != "" {
+			delete(p.cache, evicted.sku)
+			p.recent.Remove(element)
+		}
+		element = previous
+	}
+}
+
+// fetch reads the pack sizes for the specified SKU from the catalog, retrying failed requests. It reports
+// whether the SKU has no pack sizes of its own and uses the default pack sizes instead.
+func (p *HTTPPackSizeProvider) fetch(ctx context.Context, sku string) ([]models.PackSize, bool, error) {
+	if err := p.allow(); err != nil {
+		return nil, false, err
+	}
+
+	var err error
+	for attempt := 0; attempt <= p.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(p.backoff << (attempt - 1)):
+			case <-ctx.Done():
+				return nil, false, ctx.Err()
+			}
+		}
+
+		var packSizes []models.PackSize
+		var usesDefaults, retry bool
+		packSizes, usesDefaults, retry, err = p.get(ctx, sku)
+		if err == nil {
+			p.record(nil)
+			return packSizes, usesDefaults, nil
+		}
+		if !retry || ctx.Err() != nil {
+			break
+		}
+	}
+
+	// Requests ended by closing the provider say nothing about the catalog
+	if ctx.Err() == nil {
+		p.record(err)
+	}
+
+	return nil, false, err
+}
+
+// get requests the pack sizes for the specified SKU from the catalog once. It reports whether the SKU uses
+// the default pack sizes, and whether a failed request is worth retrying.
+func (p *HTTPPackSizeProvider) get(ctx context.Context, sku string) ([]models.PackSize, bool, bool, error) {
+	endpoint := p.baseURL + "/pack-sizes"
+	if sku != "" {
+		endpoint = p.baseURL + "/products/" + url.PathEscape(sku) + "/pack-sizes"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, false, true, fmt.Errorf("failed to request pack sizes: %w", err)
+	}
+	defer func() {
+		// The body is read to the end so the connection can be reused
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound && sku != "":
+		// The product uses the default pack sizes
+		return nil, true, false, nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError:
+		return nil, false, true, fmt.Errorf("failed to request pack sizes: catalog responded with %s", res.Status)
+	case res.StatusCode != http.StatusOK:
+		return nil, false, false, fmt.Errorf("failed to request pack sizes: catalog responded with %s", res.Status)
+	}
+
+	packSizes := []models.PackSize{}
+	if err := json.NewDecoder(res.Body).Decode(&packSizes); err != nil {
+		return nil, false, false, fmt.Errorf("failed to decode pack sizes: %w", err)
+	}
+	if packSizes == nil {
+		packSizes = []models.PackSize{}
+	}
+
+	return packSizes, false, false, nil
+}
+
+// allow returns ErrCatalogUnavailable while the catalog is left alone after failing too many times in a row.
+// Once the time is up a single read is let through to try the catalog again, the others keep failing until
+// the catalog has been left alone for another while.
+func (p *HTTPPackSizeProvider) allow() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failureThreshold <= 0 || p.failures < p.failureThreshold {
+		return nil
+	}
+
+	now := p.now()
+	if now.Before(p.openUntil) {
+		return ErrCatalogUnavailable
+	}
+	p.openUntil = now.Add(p.openFor)
+
+	return nil
+}
+
+// record counts the failed reads in a row, leaving the catalog alone once there are too many
+func (p *HTTPPackSizeProvider) record(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil {
+		p.failures = 0
+		return
+	}
+
+	p.failures++
+	if p.failureThreshold > 0 && p.failures >= p.failureThreshold {
+		p.openUntil = p.now().Add(p.openFor)
+	}
+}
+
+// Update returns ErrReadOnlyPackSizes, pack sizes are changed in the catalog
+func (p *HTTPPackSizeProvider) Update(ctx context.Context, sku string, packSizes []models.PackSize) error {
+	return services.ErrReadOnlyPackSizes
+}
+
+// CompareAndSwap returns ErrReadOnlyPackSizes, pack sizes are changed in the catalog
+func (p *HTTPPackSizeProvider) CompareAndSwap(ctx context.Context, sku, version string, packSizes []models.PackSize) error {
+	return services.ErrReadOnlyPackSizes
+}
+
+// AdjustStock returns ErrReadOnlyPackSizes, stock is kept by the catalog
+func (p *HTTPPackSizeProvider) AdjustStock(ctx context.Context, sku string, adjustments []models.StockAdjustment) error {
+	return services.ErrReadOnlyPackSizes
+}
+
+// AddPackSizeVersion returns ErrHistoryUnavailable, the catalog keeps no history
+func (p *HTTPPackSizeProvider) AddPackSizeVersion(ctx context.Context, version models.PackSizeSetVersion) (models.PackSizeSetVersion, error) {
+	return models.PackSizeSetVersion{}, services.ErrHistoryUnavailable
+}
+
+// PackSizeVersions returns ErrHistoryUnavailable, the catalog keeps no history
+func (p *HTTPPackSizeProvider) PackSizeVersions(ctx context.Context, sku string) ([]models.PackSizeSetVersion, error) {
+	return nil, services.ErrHistoryUnavailable
+}
+
+// PackSizeVersion returns ErrHistoryUnavailable, the catalog keeps no history
+func (p *HTTPPackSizeProvider) PackSizeVersion(ctx context.Context, sku string, id int) (models.PackSizeSetVersion, error) {
+	return models.PackSizeSetVersion{}, services.ErrHistoryUnavailable
+}
+
+// SchedulePackSizes returns ErrScheduleUnavailable, the catalog keeps no schedule
+func (p *HTTPPackSizeProvider) SchedulePackSizes(ctx context.Context, scheduled models.ScheduledPackSizes) (models.ScheduledPackSizes, error) {
+	return models.ScheduledPackSizes{}, services.ErrScheduleUnavailable
+}
+
+// ScheduledPackSizes returns ErrScheduleUnavailable, the catalog keeps no schedule
+func (p *HTTPPackSizeProvider) ScheduledPackSizes(ctx context.Context, sku string) ([]models.ScheduledPackSizes, error) {
+	return nil, services.ErrScheduleUnavailable
+}
+
+// UnschedulePackSizes returns ErrScheduleUnavailable, the catalog keeps no schedule
+func (p *HTTPPackSizeProvider) UnschedulePackSizes(ctx context.Context, sku string, id int) error {
+	return services.ErrScheduleUnavailable
+}
+
+// Close ends the requests to the catalog in flight, waits for them to return and closes the idle connections
+func (p *HTTPPackSizeProvider) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	p.cancel()
+	p.wg.Wait()
+	p.client.CloseIdleConnections()
+
+	return nil
+}
@@ -0,0 +1,464 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/providers"
+	"github.com/cybre/order-packing/internal/services"
+)
+
+// catalog is an httptest stand-in for the pack size catalog
+type catalog struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	packSizes map[string][]models.PackSize
+	failures  int
+	status    int
+	hits      atomic.Int64
+	// gate holds the responses back until it is closed, when it is set
+	gate     chan struct{}
+	canceled atomic.Int64
+}
+
+// newCatalog starts a catalog serving the pack sizes by SKU, the default pack sizes under the empty SKU
+func newCatalog(t *testing.T, packSizes map[string][]models.PackSize) *catalog {
+	c := &catalog{packSizes: packSizes}
+
+	mux := http.NewServeMux()
+	serve := func(w http.ResponseWriter, r *http.Request, sku string) {
+		c.hits.Add(1)
+
+		c.mu.Lock()
+		gate := c.gate
+		c.mu.Unlock()
+		if gate != nil {
+			select {
+			case <-gate:
+			case <-r.Context().Done():
+				c.canceled.Add(1)
+				return
+			}
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.failures > 0 {
+			c.failures--
+			w.WriteHeader(c.status)
+			return
+		}
+
+		packSizes, ok := c.packSizes[sku]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(packSizes)
+	}
+	mux.HandleFunc("/pack-sizes", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, "")
+	})
+	mux.HandleFunc("/products/", func(w http.ResponseWriter, r *http.Request) {
+		sku, _ := strings.CutPrefix(r.URL.Path, "/products/")
+		serve(w, r, strings.TrimSuffix(sku, "/pack-sizes"))
+	})
+
+	c.Server = httptest.NewServer(mux)
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+// fail makes the catalog respond with the status code to the next requests
+func (c *catalog) fail(requests, status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures, c.status = requests, status
+}
+
+// hold makes the catalog hold the responses back until the returned function is called
+func (c *catalog) hold() func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	gate := make(chan struct{})
+	c.gate = gate
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(gate) })
+	}
+}
+
+// set changes the pack sizes of the SKU in the catalog
+func (c *catalog) set(sku string, packSizes []models.PackSize) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.packSizes[sku] = packSizes
+}
+
+// clock is a time source that only moves when told to
+type clock struct {
+	offset atomic.Int64
+}
+
+func (c *clock) now() time.Time {
+	return time.Unix(0, 0).Add(time.Duration(c.offset.Load()))
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.offset.Add(int64(d))
+}
+
+func TestHTTPPackSizeProvider_GetPackSizes(t *testing.T) {
+	defaultPackSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}
+	productPackSizes := []models.PackSize{{MaxItems: 10, Cost: 1.5}}
+
+	testCases := []struct {
+		name              string
+		sku               string
+		expectedPackSizes []models.PackSize
+	}{
+		{name: "Default pack sizes", sku: "", expectedPackSizes: defaultPackSizes},
+		{name: "Product pack sizes", sku: "SKU 1", expectedPackSizes: productPackSizes},
+		{name: "Product without pack sizes", sku: "SKU-2", expectedPackSizes: defaultPackSizes},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			c := newCatalog(t, map[string][]models.PackSize{"": defaultPackSizes, "SKU 1": productPackSizes})
+			p := providers.NewHTTPPackSizeProvider(c.URL + "/")
+			defer p.Close()
+
+			// Act
+			var packSizes []models.PackSize
+			for i := 0; i < 3; i++ {
+				var err error
+				if packSizes, err = p.GetPackSizes(context.Background(), tc.sku); err != nil {
+					t.Fatalf("failed to get pack sizes: %v", err)
+				}
+			}
+			hits := c.hits.Load()
+
+			// Assert
+			if !reflect.DeepEqual(packSizes, tc.expectedPackSizes) {
+				t.Fatalf("unexpected pack sizes, got %+v, want %+v", packSizes, tc.expectedPackSizes)
+			}
+
+			// Products without pack sizes of their own take a second request for the default pack sizes
+			expectedHits := int64(1)
+			if tc.sku == "SKU-2" {
+				expectedHits = 2
+			}
+			if hits != expectedHits {
+				t.Fatalf("unexpected number of requests, got %d, want %d", hits, expectedHits)
+			}
+		})
+	}
+}
+
+func TestHTTPPackSizeProvider_CacheSize(t *testing.T) {
+	// Arrange
+	c := newCatalog(t, map[string][]models.PackSize{
+		"":      {{MaxItems: 250}},
+		"SKU-1": {{MaxItems: 6}},
+		"SKU-2": {{MaxItems: 12}},
+		"SKU-3": {{MaxItems: 24}},
+	})
+	p := providers.NewHTTPPackSizeProvider(c.URL, providers.WithHTTPCacheSize(3))
+	defer p.Close()
+
+	get := func(sku string) {
+		t.Helper()
+		if _, err := p.GetPackSizes(context.Background(), sku); err != nil {
+			t.Fatalf("failed to get pack sizes for %q: %v", sku, err)
+		}
+	}
+
+	// Act
+	// Products without pack sizes of their own share the default pack sizes, which are never dropped
+	for _, sku := range []string{"UNKNOWN-1", "UNKNOWN-2", "UNKNOWN-3", "UNKNOWN-4"} {
+		get(sku)
+	}
+	unknownHits := c.hits.Load()
+
+	for _, sku := range []string{"SKU-1", "SKU-2", "SKU-3", "SKU-3", ""} {
+		get(sku)
+	}
+	productHits := c.hits.Load() - unknownHits
+
+	get("SKU-1")
+	droppedHits := c.hits.Load() - unknownHits - productHits
+
+	// Assert
+	if unknownHits != 5 {
+		t.Fatalf("unexpected number of requests for products without pack sizes, got %d, want %d", unknownHits, 5)
+	}
+
+	if productHits != 3 {
+		t.Fatalf("unexpected number of requests for products, got %d, want %d", productHits, 3)
+	}
+
+	if droppedHits != 1 {
+		t.Fatalf("unexpected number of requests for the least recently used product, got %d, want %d", droppedHits, 1)
+	}
+}
+
+func TestHTTPPackSizeProvider_ConcurrentReads(t *testing.T) {
+	// Arrange
+	c := newCatalog(t, map[string][]models.PackSize{"": {{MaxItems: 250}}, "SKU-1": {{MaxItems: 6}}})
+	release := c.hold()
+	defer release()
+	p := providers.NewHTTPPackSizeProvider(c.URL)
+	defer p.Close()
+
+	// Act
+	const readers = 10
+	errs := make(chan error, readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			_, err := p.GetPackSizes(context.Background(), "SKU-1")
+			errs <- err
+		}()
+	}
+
+	// Let the reads pile up behind the first request before the catalog responds
+	deadline := time.Now().Add(5 * time.Second)
+	for c.hits.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	release()
+
+	// Assert
+	for i := 0; i < readers; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("failed to get pack sizes: %v", err)
+		}
+	}
+
+	if hits := c.hits.Load(); hits != 1 {
+		t.Fatalf("unexpected number of requests, got %d, want %d", hits, 1)
+	}
+}
+
+func TestHTTPPackSizeProvider_Close(t *testing.T) {
+	// Arrange
+	c := newCatalog(t, map[string][]models.PackSize{"": {{MaxItems: 250}}})
+	clk := &clock{}
+	p := providers.NewHTTPPackSizeProvider(c.URL,
+		providers.WithHTTPRetries(0, 0),
+		providers.WithHTTPFreshFor(time.Minute),
+		providers.WithHTTPClock(clk.now),
+	)
+
+	if _, err := p.GetPackSizes(context.Background(), ""); err != nil {
+		t.Fatalf("failed to get pack sizes: %v", err)
+	}
+
+	// The stale pack sizes are read again in the background, and the catalog does not respond
+	release := c.hold()
+	defer release()
+	clk.advance(2 * time.Minute)
+	if _, err := p.GetPackSizes(context.Background(), ""); err != nil {
+		t.Fatalf("failed to get stale pack sizes: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.hits.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Act
+	closed := make(chan error, 1)
+	go func() {
+		closed <- p.Close()
+	}()
+
+	// Assert
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("failed to close provider: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("closing the provider did not end the read in the background")
+	}
+
+	for c.canceled.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("the request of the read in the background was not canceled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := p.GetPackSizes(context.Background(), "SKU-1"); err == nil {
+		t.Fatalf("expected an error reading pack sizes after the provider was closed")
+	}
+}
+
+func TestHTTPPackSizeProvider_Retries(t *testing.T) {
+	testCases := []struct {
+		name         string
+		failures     int
+		status       int
+		expectErr    bool
+		expectedHits int64
+	}{
+		{name: "Recovers after server errors", failures: 2, status: http.StatusInternalServerError, expectedHits: 3},
+		{name: "Recovers after too many requests", failures: 1, status: http.StatusTooManyRequests, expectedHits: 2},
+		{name: "Gives up after the last retry", failures: 3, status: http.StatusServiceUnavailable, expectErr: true, expectedHits: 3},
+		{name: "Does not retry client errors", failures: 1, status: http.StatusBadRequest, expectErr: true, expectedHits: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			c := newCatalog(t, map[string][]models.PackSize{"": {{MaxItems: 250}}})
+			c.fail(tc.failures, tc.status)
+			p := providers.NewHTTPPackSizeProvider(c.URL, providers.WithHTTPRetries(2, time.Millisecond))
+			defer p.Close()
+
+			// Act
+			_, err := p.GetPackSizes(context.Background(), "")
+
+			// Assert
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("unexpected error, got %v, want error %t", err, tc.expectErr)
+			}
+
+			if hits := c.hits.Load(); hits != tc.expectedHits {
+				t.Fatalf("unexpected number of requests, got %d, want %d", hits, tc.expectedHits)
+			}
+		})
+	}
+}
+
+func TestHTTPPackSizeProvider_StaleWhileRevalidate(t *testing.T) {
+	// Arrange
+	c := newCatalog(t, map[string][]models.PackSize{"": {{MaxItems: 250}}})
+	clk := &clock{}
+	p := providers.NewHTTPPackSizeProvider(c.URL,
+		providers.WithHTTPRetries(0, 0),
+		providers.WithHTTPFreshFor(time.Minute),
+		providers.WithHTTPClock(clk.now),
+	)
+	defer p.Close()
+
+	if _, err := p.GetPackSizes(context.Background(), ""); err != nil {
+		t.Fatalf("failed to get pack sizes: %v", err)
+	}
+
+	// Act
+	// The catalog goes down and the pack sizes become stale
+	c.fail(1, http.StatusServiceUnavailable)
+	c.set("", []models.PackSize{{MaxItems: 1000}})
+	clk.advance(2 * time.Minute)
+
+	stalePackSizes, err := p.GetPackSizes(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to get pack sizes while the catalog is down: %v", err)
+	}
+
+	// Assert
+	expectedPackSizes := []models.PackSize{{MaxItems: 250}}
+	if !reflect.DeepEqual(stalePackSizes, expectedPackSizes) {
+		t.Fatalf("unexpected pack sizes while the catalog is down, got %+v, want %+v", stalePackSizes, expectedPackSizes)
+	}
+
+	// The catalog is back up and the changed pack sizes are read in the background
+	expectedPackSizes = []models.PackSize{{MaxItems: 1000}}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		packSizes, err := p.GetPackSizes(context.Background(), "")
+		if err != nil {
+			t.Fatalf("failed to get pack sizes: %v", err)
+		}
+		if reflect.DeepEqual(packSizes, expectedPackSizes) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pack sizes were not read again, got %+v, want %+v", packSizes, expectedPackSizes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHTTPPackSizeProvider_CircuitBreaker(t *testing.T) {
+	// Arrange
+	c := newCatalog(t, map[string][]models.PackSize{"": {{MaxItems: 250}}})
+	clk := &clock{}
+	p := providers.NewHTTPPackSizeProvider(c.URL,
+		providers.WithHTTPRetries(0, 0),
+		providers.WithHTTPCircuitBreaker(2, time.Minute),
+		providers.WithHTTPClock(clk.now),
+	)
+	defer p.Close()
+
+	c.fail(2, http.StatusInternalServerError)
+	for i := 0; i < 2; i++ {
+		if _, err := p.GetPackSizes(context.Background(), ""); err == nil {
+			t.Fatalf("expected an error while the catalog is down")
+		}
+	}
+
+	// Act
+	_, openErr := p.GetPackSizes(context.Background(), "")
+	openHits := c.hits.Load()
+
+	clk.advance(time.Minute)
+	packSizes, err := p.GetPackSizes(context.Background(), "")
+
+	// Assert
+	if !errors.Is(openErr, providers.ErrCatalogUnavailable) {
+		t.Fatalf("unexpected error while the catalog is left alone, got %v, want %v", openErr, providers.ErrCatalogUnavailable)
+	}
+
+	if openHits != 2 {
+		t.Fatalf("unexpected number of requests while the catalog is left alone, got %d, want %d", openHits, 2)
+	}
+
+	if err != nil {
+		t.Fatalf("failed to get pack sizes once the catalog is tried again: %v", err)
+	}
+
+	expectedPackSizes := []models.PackSize{{MaxItems: 250}}
+	if !reflect.DeepEqual(packSizes, expectedPackSizes) {
+		t.Fatalf("unexpected pack sizes, got %+v, want %+v", packSizes, expectedPackSizes)
+	}
+}
+
+func TestHTTPPackSizeProvider_ReadOnly(t *testing.T) {
+	// Arrange
+	c := newCatalog(t, map[string][]models.PackSize{"": {{MaxItems: 250}}})
+	p := providers.NewHTTPPackSizeProvider(c.URL)
+	defer p.Close()
+
+	// Act
+	err := p.Update(context.Background(), "", []models.PackSize{{MaxItems: 1000}})
+
+	// Assert
+	if !errors.Is(err, services.ErrReadOnlyPackSizes) {
+		t.Fatalf("unexpected error updating pack sizes, got %v, want %v", err, services.ErrReadOnlyPackSizes)
+	}
+
+	if hits := c.hits.Load(); hits != 0 {
+		t.Fatalf("unexpected number of requests, got %d, want %d", hits, 0)
+	}
+}
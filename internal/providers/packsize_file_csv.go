@@ -0,0 +1,188 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// csvColumns are the columns of pack sizes CSV files, rows without a SKU hold the default pack sizes
+var csvColumns = []string{"sku", "maxItems", "cost", "stock", "maxWeight", "maxVolume"}
+
+// csvFormat stores the pack sizes as CSV with a row for every pack size, so they can be maintained in
+// spreadsheets. The columns may come in any order and all but maxItems may be left out, empty cells leave
+// the attribute unset. A row with nothing but a SKU marks a product whose pack sizes are empty.
+// Lines starting with # are comments, those at the top of the file are kept.
+// CSV files cannot hold the history and schedule of the pack sizes.
+type csvFormat struct{}
+
+func (csvFormat) unmarshal(data []byte) (packSizeFile, error) {
+	file := packSizeFile{Version: packSizeFileVersion, PackSizes: []models.PackSize{}}
+
+	// Spreadsheets may save CSV files with a byte order mark
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return file, nil
+	}
+	if err != nil {
+		return file, fmt.Errorf("failed to unmarshal file: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["maxItems"]; !ok {
+		return file, fmt.Errorf("failed to unmarshal file: missing maxItems column")
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return file, nil
+		}
+		if err != nil {
+			return file, fmt.Errorf("failed to unmarshal file: %w", err)
+		}
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+
+			return ""
+		}
+
+		sku := cell("sku")
+		if sku != "" && !slices.ContainsFunc(csvColumns, func(name string) bool { return name != "sku" && cell(name) != "" }) {
+			if _, ok := file.Products[sku]; !ok {
+				file.setPackSizes(sku, []models.PackSize{})
+			}
+			continue
+		}
+
+		packSize, err := csvPackSize(cell)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return file, fmt.Errorf("failed to unmarshal file: line %d: %w", line, err)
+		}
+
+		if sku != "" {
+			file.setPackSizes(sku, append(file.Products[sku], packSize))
+		} else {
+			file.PackSizes = append(file.PackSizes, packSize)
+		}
+	}
+}
+
+// csvPackSize parses the pack size from the cells of a row
+func csvPackSize(cell func(name string) string) (models.PackSize, error) {
+	var packSize models.PackSize
+	var err error
+
+	if packSize.MaxItems, err = strconv.Atoi(cell("maxItems")); err != nil {
+		return packSize, fmt.Errorf("invalid maxItems: %w", err)
+	}
+
+	if value := cell("stock"); value != "" {
+		stock, err := strconv.Atoi(value)
+		if err != nil {
+			return packSize, fmt.Errorf("invalid stock: %w", err)
+		}
+		packSize.Stock = &stock
+	}
+
+	for name, attribute := range map[string]*float64{
+		"cost":      &packSize.Cost,
+		"maxWeight": &packSize.MaxWeight,
+		"maxVolume": &packSize.MaxVolume,
+	} {
+		if value := cell(name); value != "" {
+			if *attribute, err = strconv.ParseFloat(value, 64); err != nil {
+				return packSize, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+
+	return packSize, nil
+}
+
+func (csvFormat) marshal(file packSizeFile, previous []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// Comments at the top of the previous contents are kept
+	for _, line := range strings.SplitAfter(string(previous), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		buf.WriteString(strings.TrimRight(line, "\r\n") + "\n")
+	}
+
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(csvColumns); err != nil {
+		return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+	}
+
+	skus := make([]string, 0, len(file.Products))
+	for sku := range file.Products {
+		skus = append(skus, sku)
+	}
+	slices.Sort(skus)
+
+	for _, sku := range append([]string{""}, skus...) {
+		packSizes, _ := file.packSizes(sku)
+		if sku != "" && len(packSizes) == 0 {
+			// The product would otherwise disappear and use the default pack sizes
+			if err := writer.Write(append([]string{sku}, make([]string, len(csvColumns)-1)...)); err != nil {
+				return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+			}
+			continue
+		}
+
+		for _, packSize := range packSizes {
+			if err := writer.Write(csvRecord(sku, packSize)); err != nil {
+				return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (csvFormat) keepsHistory() bool {
+	return false
+}
+
+// csvRecord returns the row of the pack size of the SKU, attributes that are not set are left empty
+func csvRecord(sku string, packSize models.PackSize) []string {
+	formatFloat := func(value float64) string {
+		if value == 0 {
+			return ""
+		}
+
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	stock := ""
+	if packSize.Stock != nil {
+		stock = strconv.Itoa(*packSize.Stock)
+	}
+
+	return []string{sku, strconv.Itoa(packSize.MaxItems), formatFloat(packSize.Cost), stock, formatFloat(packSize.MaxWeight), formatFloat(packSize.MaxVolume)}
+}
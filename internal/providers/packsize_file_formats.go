@@ -0,0 +1,152 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// packSizeFileFormat reads and writes pack sizes files in one file format
+type packSizeFileFormat interface {
+	// unmarshal parses the contents of a file, upgrading older files to the current schema.
+	// An empty file has no pack sizes.
+	unmarshal(data []byte) (packSizeFile, error)
+	// marshal returns the contents of the file, keeping what it can of the comments and layout of the
+	// previous contents of the file
+	marshal(file packSizeFile, previous []byte) ([]byte, error)
+	// keepsHistory reports whether the format can hold the history and schedule of the pack sizes
+	keepsHistory() bool
+}
+
+// packSizeFileFormatFor returns the format of the file at the path by its extension, JSON for unknown extensions
+func packSizeFileFormatFor(filePath string) packSizeFileFormat {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return yamlFormat{}
+	case ".toml":
+		return tomlFormat{}
+	case ".csv":
+		return csvFormat{}
+	default:
+		return jsonFormat{}
+	}
+}
+
+// jsonFormat stores pack sizes files as JSON
+type jsonFormat struct{}
+
+func (jsonFormat) unmarshal(data []byte) (packSizeFile, error) {
+	return parsePackSizeFile(data)
+}
+
+func (jsonFormat) marshal(file packSizeFile, previous []byte) ([]byte, error) {
+	data, err := json.Marshal(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+func (jsonFormat) keepsHistory() bool {
+	return true
+}
+
+// parsePackSizeFile parses the contents of a JSON pack sizes file, an empty file has no pack sizes
+func parsePackSizeFile(data []byte) (packSizeFile, error) {
+	file := packSizeFile{Version: packSizeFileVersion, PackSizes: []models.PackSize{}}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return file, nil
+	}
+
+	// Version 1 files are a flat array of pack sizes
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &file.PackSizes); err != nil {
+			return file, fmt.Errorf("failed to unmarshal file: %w", err)
+		}
+
+		return file, nil
+	}
+
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("failed to unmarshal file: %w", err)
+	}
+
+	if file.Version > packSizeFileVersion {
+		return file, fmt.Errorf("unsupported file version %d", file.Version)
+	}
+
+	if file.PackSizes == nil {
+		file.PackSizes = []models.PackSize{}
+	}
+
+	return file, nil
+}
+
+// plainValue returns a copy of a value decoded from JSON with json.Number, keeping integers as int64 and other
+// numbers as float64 and leaving out nulls, which the YAML and TOML encoders write as they would native values
+func plainValue(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+
+		return f
+	case map[string]any:
+		plain := make(map[string]any, len(value))
+		for key, v := range value {
+			if v != nil {
+				plain[key] = plainValue(v)
+			}
+		}
+
+		return plain
+	case []any:
+		plain := make([]any, 0, len(value))
+		for _, v := range value {
+			if v != nil {
+				plain = append(plain, plainValue(v))
+			}
+		}
+
+		return plain
+	default:
+		return value
+	}
+}
+
+// plainPackSizeFile returns the pack sizes file as the maps and slices of its JSON representation
+func plainPackSizeFile(file packSizeFile) (map[string]any, error) {
+	data, err := json.Marshal(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value map[string]any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+	}
+
+	return plainValue(value).(map[string]any), nil
+}
+
+// packSizeFileFromPlain parses a pack sizes file from the maps and slices of its JSON representation
+func packSizeFileFromPlain(value any) (packSizeFile, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return packSizeFile{}, fmt.Errorf("failed to unmarshal file: %w", err)
+	}
+
+	return parsePackSizeFile(data)
+}
@@ -0,0 +1,216 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tomlFormat stores pack sizes files as TOML. Comments are kept with the table header or key they were written
+// on or above, pack sizes are matched by size and versions and scheduled pack sizes by ID. Comments at the top
+// of the file that are followed by a blank line stay at the top of the file, comments at the end stay at the end.
+type tomlFormat struct{}
+
+func (tomlFormat) unmarshal(data []byte) (packSizeFile, error) {
+	var value map[string]any
+	if _, err := toml.Decode(string(data), &value); err != nil {
+		return packSizeFile{}, fmt.Errorf("failed to unmarshal file: %w", err)
+	}
+
+	return packSizeFileFromPlain(value)
+}
+
+func (tomlFormat) marshal(file packSizeFile, previous []byte) ([]byte, error) {
+	value, err := plainPackSizeFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.Indent = ""
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+	}
+
+	return keepTOMLComments(buf.Bytes(), previous), nil
+}
+
+func (tomlFormat) keepsHistory() bool {
+	return true
+}
+
+// tomlComments holds the comments of TOML contents
+type tomlComments struct {
+	// header holds the comment lines at the top of the contents
+	header []string
+	// above holds the comment lines above every table header and key, by their identity
+	above map[string][]string
+	// inline holds the comment after every table header and key on the same line, by their identity
+	inline map[string]string
+	// footer holds the comment lines at the end of the contents
+	footer []string
+}
+
+// parseTOMLComments returns the comments of the TOML contents
+func parseTOMLComments(data []byte) tomlComments {
+	comments := tomlComments{above: map[string][]string{}, inline: map[string]string{}}
+
+	var pending []string
+	atTop := true
+	walkTOMLLines(data, func(line, identity string) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "#"):
+			pending = append(pending, trimmed)
+			return
+		case trimmed == "" && atTop && len(pending) > 0:
+			comments.header, pending = pending, nil
+		}
+		if trimmed != "" {
+			atTop = false
+		}
+
+		if identity == "" {
+			return
+		}
+
+		if len(pending) > 0 {
+			comments.above[identity], pending = pending, nil
+		}
+		if i := tomlIndexUnquoted(line, '#'); i != -1 {
+			comments.inline[identity] = strings.TrimSpace(line[i:])
+		}
+	})
+	comments.footer = pending
+
+	return comments
+}
+
+// keepTOMLComments returns the TOML contents with the comments of the previous contents
+func keepTOMLComments(data, previous []byte) []byte {
+	comments := parseTOMLComments(previous)
+
+	var buf bytes.Buffer
+	for _, comment := range comments.header {
+		buf.WriteString(comment + "\n")
+	}
+	if len(comments.header) > 0 {
+		buf.WriteString("\n")
+	}
+
+	walkTOMLLines(data, func(line, identity string) {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		for _, comment := range comments.above[identity] {
+			buf.WriteString(indent + comment + "\n")
+		}
+
+		buf.WriteString(line)
+		if comment, ok := comments.inline[identity]; ok && tomlIndexUnquoted(line, '#') == -1 {
+			buf.WriteString(" " + comment)
+		}
+		buf.WriteString("\n")
+	})
+
+	for _, comment := range comments.footer {
+		buf.WriteString(comment + "\n")
+	}
+
+	return buf.Bytes()
+}
+
+// walkTOMLLines calls the function with every line of the TOML contents and the identity of the table header or
+// key on it, which is empty for other lines. Keys are identified by their table, tables in arrays of tables by
+// the size of their pack or their ID, and by their position when they have neither.
+func walkTOMLLines(data []byte, fn func(line, identity string)) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	table := ""
+	occurrences := map[string]int{}
+	for i, line := range lines {
+		key, isTable := tomlKey(line)
+
+		identity := ""
+		switch {
+		case strings.HasPrefix(key, "[["):
+			// The keys of the table come before the next table header
+			table = key
+			for _, next := range lines[i+1:] {
+				nextKey, nextIsTable := tomlKey(next)
+				if nextIsTable {
+					break
+				}
+				if nextKey == "maxItems" || nextKey == "id" {
+					value := next[tomlIndexUnquoted(next, '=')+1:]
+					if j := tomlIndexUnquoted(value, '#'); j != -1 {
+						value = value[:j]
+					}
+					table = key + " " + nextKey + "=" + strings.TrimSpace(value)
+					break
+				}
+			}
+
+			// Tables with the same identity in different arrays, e.g. the same pack size in several
+			// versions of the pack sizes, are told apart by their order
+			occurrences[table]++
+			table += " " + strconv.Itoa(occurrences[table])
+			identity = table
+		case isTable:
+			table = key
+			identity = table
+		case key != "":
+			identity = table + " " + key
+		}
+
+		fn(line, identity)
+	}
+}
+
+// tomlKey returns the table header or key on the TOML line and whether it is a table header,
+// an empty key for other lines
+func tomlKey(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if i := tomlIndexUnquoted(trimmed, '#'); i != -1 {
+		trimmed = strings.TrimSpace(trimmed[:i])
+	}
+
+	if strings.HasPrefix(trimmed, "[") {
+		return trimmed, true
+	}
+
+	if i := tomlIndexUnquoted(trimmed, '='); i != -1 {
+		return strings.TrimSpace(trimmed[:i]), false
+	}
+
+	return "", false
+}
+
+// tomlIndexUnquoted returns the index of the first occurrence of the character outside of strings in the line,
+// -1 when there is none
+func tomlIndexUnquoted(line string, c byte) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch {
+		case quote == '"' && line[i] == '\\':
+			i++
+		case quote != 0:
+			if line[i] == quote {
+				quote = 0
+			}
+		case line[i] == '"' || line[i] == '\'':
+			quote = line[i]
+		case line[i] == c:
+			return i
+		}
+	}
+
+	return -1
+}
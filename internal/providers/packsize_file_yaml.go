@@ -0,0 +1,221 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlFormat stores pack sizes files as YAML. Comments on the keys and items of the previous contents are kept
+// on the same keys and items, pack sizes are matched by size and versions and scheduled pack sizes by ID.
+type yamlFormat struct{}
+
+func (yamlFormat) unmarshal(data []byte) (packSizeFile, error) {
+	var value any
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return packSizeFile{}, fmt.Errorf("failed to unmarshal file: %w", err)
+	}
+
+	return packSizeFileFromPlain(value)
+}
+
+func (yamlFormat) marshal(file packSizeFile, previous []byte) ([]byte, error) {
+	data, err := json.Marshal(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	node, err := yamlNodeFromJSON(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+	}
+
+	document := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{node}}
+
+	// Previous contents that cannot be parsed leave no comments to keep
+	var old yaml.Node
+	if err := yaml.Unmarshal(previous, &old); err == nil && old.Kind == yaml.DocumentNode {
+		copyYAMLComments(document, &old)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal pack sizes: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (yamlFormat) keepsHistory() bool {
+	return true
+}
+
+// yamlNodeFromJSON returns the next JSON value of the decoder as a YAML node, keeping the order of the keys
+// of objects and leaving out nulls, for which nil is returned
+func yamlNodeFromJSON(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if token == '[' {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+
+		for decoder.More() {
+			var key *yaml.Node
+			if node.Kind == yaml.MappingNode {
+				name, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				key = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name.(string)}
+			}
+
+			value, err := yamlNodeFromJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if value == nil {
+				continue
+			}
+
+			if key != nil {
+				node.Content = append(node.Content, key)
+			}
+			node.Content = append(node.Content, value)
+		}
+
+		// Consume the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+
+		return node, nil
+	case json.Number:
+		tag := "!!float"
+		if _, err := token.Int64(); err == nil {
+			tag = "!!int"
+		}
+
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: token.String()}, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(token)}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// copyYAMLComments copies the comments of the old node and the nodes within it to the matching nodes of the
+// new node. The keys of mappings keep the order they had in the old node, new keys follow them.
+func copyYAMLComments(node, old *yaml.Node) {
+	if node.Kind != old.Kind {
+		return
+	}
+
+	node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) != 1 || len(old.Content) != 1 {
+			return
+		}
+
+		// The comments of version 1 files, which are a sequence of the default pack sizes, go with the pack sizes
+		root, oldRoot := node.Content[0], old.Content[0]
+		if root.Kind == yaml.MappingNode && oldRoot.Kind == yaml.SequenceNode {
+			i := yamlMappingIndex(root, "packSizes")
+			root = root.Content[i+1]
+
+			// Comments at the top of the file are read as comments on the first pack size
+			if len(oldRoot.Content) > 0 {
+				node.HeadComment = strings.TrimSpace(old.HeadComment + "\n\n" + oldRoot.HeadComment + "\n\n" + oldRoot.Content[0].HeadComment)
+				oldRoot.HeadComment, oldRoot.Content[0].HeadComment = "", ""
+			}
+		}
+		copyYAMLComments(root, oldRoot)
+	case yaml.MappingNode:
+		content := make([]*yaml.Node, 0, len(node.Content))
+		used := make([]bool, len(node.Content)/2)
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			j := yamlMappingIndex(node, old.Content[i].Value)
+			if j == -1 || used[j/2] {
+				continue
+			}
+			used[j/2] = true
+
+			key, value := node.Content[j], node.Content[j+1]
+			key.HeadComment, key.LineComment, key.FootComment = old.Content[i].HeadComment, old.Content[i].LineComment, old.Content[i].FootComment
+			copyYAMLComments(value, old.Content[i+1])
+			content = append(content, key, value)
+		}
+		for j := 0; j < len(node.Content); j += 2 {
+			if !used[j/2] {
+				content = append(content, node.Content[j], node.Content[j+1])
+			}
+		}
+		node.Content = content
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if oldItem := yamlSequenceMatch(old, item, i); oldItem != nil {
+				copyYAMLComments(item, oldItem)
+			}
+		}
+	}
+}
+
+// yamlMappingIndex returns the index of the key in the mapping node, -1 when there is none
+func yamlMappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// yamlSequenceMatch returns the item of the old sequence node matching the item at the index, pack sizes are
+// matched by their size, versions and scheduled pack sizes by their ID and other items by their index
+func yamlSequenceMatch(old, item *yaml.Node, index int) *yaml.Node {
+	if item.Kind == yaml.MappingNode {
+		for _, identity := range []string{"maxItems", "id"} {
+			i := yamlMappingIndex(item, identity)
+			if i == -1 {
+				continue
+			}
+
+			for _, oldItem := range old.Content {
+				if oldItem.Kind != yaml.MappingNode {
+					continue
+				}
+				if j := yamlMappingIndex(oldItem, identity); j != -1 && oldItem.Content[j+1].Value == item.Content[i+1].Value {
+					return oldItem
+				}
+			}
+
+			return nil
+		}
+	}
+
+	if index < len(old.Content) {
+		return old.Content[index]
+	}
+
+	return nil
+}
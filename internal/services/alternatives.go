@@ -0,0 +1,226 @@
+package services
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"slices"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// MaxAlternatives is the maximum number of alternative packings an order can ask for
+const MaxAlternatives = 10
+
+// packSpace is a set of packings that use between min and max packs of every pack size (-1 for no maximum)
+type packSpace struct {
+	min, max []int
+}
+
+// packingNode is the best packing of a pack space
+type packingNode struct {
+	space     packSpace
+	counts    []int
+	candidate models.PackingCandidate
+}
+
+// packingQueue orders the best packings of the pack spaces by the objective, the best one first
+type packingQueue struct {
+	nodes        []packingNode
+	requestedQty int
+	objective    models.Objective
+}
+
+func (q packingQueue) Len() int { return len(q.nodes) }
+
+func (q packingQueue) Less(i, j int) bool {
+	return compareAlternatives(q.nodes[i].candidate, q.nodes[j].candidate, q.requestedQty, q.objective) < 0
+}
+
+func (q packingQueue) Swap(i, j int) { q.nodes[i], q.nodes[j] = q.nodes[j], q.nodes[i] }
+
+func (q *packingQueue) Push(x any) { q.nodes = append(q.nodes, x.(packingNode)) }
+
+func (q *packingQueue) Pop() any {
+	node := q.nodes[len(q.nodes)-1]
+	q.nodes = q.nodes[:len(q.nodes)-1]
+
+	return node
+}
+
+// compareAlternatives compares two packings by the rules of the objective, breaking ties by preferring
+// more of the larger packs so the ranking is deterministic
+func compareAlternatives(a, b models.PackingCandidate, requestedQty int, objective models.Objective) int {
+	if order, _ := compareCandidates(a, b, requestedQty, objective); order != 0 {
+		return order
+	}
+
+	return slices.CompareFunc(a.Packs, b.Packs, func(a, b models.PackLine) int {
+		if a.MaxItems != b.MaxItems {
+			return b.MaxItems - a.MaxItems
+		}
+
+		return b.Quantity - a.Quantity
+	})
+}
+
+// alternativePacks returns the k best packings of the target the packer finds, ranked by the objective. With an
+// exact packer they are the k best packings, the first one being the packing the packer chooses. Packings holding
+// a whole largest pack more than ordered always have a pack that can be dropped, so they are not considered and
+// fewer than k packings may be returned.
+//
+// The packings are found by repeatedly taking the best packing of a pack space and splitting the rest of the
+// space into disjoint spaces that each fix the counts of the larger pack sizes and move the count of the next
+// one below or above it (Lawler's method), so no packing is returned twice.
+func alternativePacks(packer Packer, packSizes []models.PackSize, target PackingTarget, objective models.Objective, k int) ([]models.PackingCandidate, error) {
+	packSizes = uniquePackSizes(packSizes)
+	target.MaxQty = min(target.MaxQty, target.OrderQty+packSizes[len(packSizes)-1].MaxItems-1)
+
+	root := packSpace{min: make([]int, len(packSizes)), max: make([]int, len(packSizes))}
+	for i, packSize := range packSizes {
+		root.max[i] = -1
+		if packSize.Stock != nil {
+			root.max[i] = *packSize.Stock
+		}
+	}
+
+	// Like minPacks, large orders are mostly filled with the dominant pack, which keeps the spaces small
+	if dominant, ok := dominantPackSize(packSizes, objective); ok {
+		if window := alternativesWindow(packSizes, dominant, objective, k); target.MinQty > window {
+			i := slices.IndexFunc(packSizes, func(packSize models.PackSize) bool {
+				return packSize.MaxItems == dominant.MaxItems
+			})
+			root.min[i] = (target.MinQty - window) / dominant.MaxItems
+		}
+	}
+
+	queue := &packingQueue{requestedQty: target.OrderQty, objective: objective}
+	node, ok, err := bestInSpace(packer, packSizes, target, objective, root)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInsufficientStock
+	}
+	heap.Push(queue, node)
+
+	alternatives := []models.PackingCandidate{}
+	for queue.Len() > 0 && len(alternatives) < k {
+		node := heap.Pop(queue).(packingNode)
+		alternatives = append(alternatives, node.candidate)
+
+		// Split the rest of the space, largest pack sizes first
+		for i := len(packSizes) - 1; i >= 0; i-- {
+			fixed := node.space.clone()
+			for j := len(packSizes) - 1; j > i; j-- {
+				fixed.min[j], fixed.max[j] = node.counts[j], node.counts[j]
+			}
+
+			spaces := []packSpace{}
+			if node.counts[i] > node.space.min[i] {
+				below := fixed.clone()
+				below.max[i] = node.counts[i] - 1
+				spaces = append(spaces, below)
+			}
+			if node.space.max[i] == -1 || node.counts[i] < node.space.max[i] {
+				above := fixed.clone()
+				above.min[i] = node.counts[i] + 1
+				spaces = append(spaces, above)
+			}
+
+			for _, space := range spaces {
+				child, ok, err := bestInSpace(packer, packSizes, target, objective, space)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					heap.Push(queue, child)
+				}
+			}
+		}
+	}
+
+	return alternatives, nil
+}
+
+// alternativesWindow returns an upper bound on the number of items the k best packings can hold in packs
+// other than the dominant one (D). As with residueWindow, a packing holding more than lcmBound items in other
+// packs uses some dominated pack c at least lcm(c, D)/c times, which can be swapped for D packs that are at least
+// as good. A packing holding more than lcmBound plus k-1 of the largest swaps can therefore be swapped k times,
+// giving k distinct packings at least as good that all hold more dominant packs.
+func alternativesWindow(packSizes []models.PackSize, dominant models.PackSize, objective models.Objective, k int) int {
+	lcmBound, largestSwap := 0, 0
+	for _, packSize := range packSizes {
+		if packSize.MaxItems == dominant.MaxItems {
+			continue
+		}
+
+		bound := math.MaxInt
+		if dominates(dominant, packSize, objective) {
+			swap := lcm(packSize.MaxItems, dominant.MaxItems)
+			bound = swap - packSize.MaxItems
+			largestSwap = max(largestSwap, swap)
+		}
+		if packSize.Stock != nil {
+			bound = min(bound, *packSize.Stock*packSize.MaxItems)
+		}
+
+		lcmBound += bound
+	}
+
+	return lcmBound + (k-1)*largestSwap
+}
+
+func (s packSpace) clone() packSpace {
+	return packSpace{min: slices.Clone(s.min), max: slices.Clone(s.max)}
+}
+
+// bestInSpace returns the best packing of the target in the pack space the packer finds, or false if it finds none
+func bestInSpace(packer Packer, packSizes []models.PackSize, target PackingTarget, objective models.Objective, space packSpace) (packingNode, bool, error) {
+	// Take the minimum packs up front and pack the rest with the packs left to choose from
+	counts := slices.Clone(space.min)
+	remainingSizes := []models.PackSize{}
+	for i, packSize := range packSizes {
+		target = target.without(packSize.MaxItems * space.min[i])
+
+		if space.max[i] == -1 {
+			remainingSizes = append(remainingSizes, models.PackSize{MaxItems: packSize.MaxItems, Cost: packSize.Cost})
+		} else if stock := space.max[i] - space.min[i]; stock > 0 {
+			remainingSizes = append(remainingSizes, models.PackSize{MaxItems: packSize.MaxItems, Cost: packSize.Cost, Stock: &stock})
+		}
+	}
+
+	if len(remainingSizes) == 0 {
+		if target.MinQty > 0 || target.MaxQty < 0 {
+			return packingNode{}, false, nil
+		}
+	}
+
+	packs := map[int]int{}
+	if len(remainingSizes) > 0 {
+		var err error
+		packs, err = packer.Pack(remainingSizes, target, objective)
+		if errors.Is(err, ErrInsufficientStock) {
+			return packingNode{}, false, nil
+		}
+		if err != nil {
+			return packingNode{}, false, err
+		}
+	}
+
+	node := packingNode{space: space, counts: counts}
+	for i, packSize := range packSizes {
+		counts[i] += packs[packSize.MaxItems]
+		if counts[i] == 0 {
+			continue
+		}
+
+		packs[packSize.MaxItems] = counts[i]
+		node.candidate.ItemsShipped += packSize.MaxItems * counts[i]
+		node.candidate.PackCount += counts[i]
+		node.candidate.TotalCost += packSize.Cost * float64(counts[i])
+	}
+	node.candidate.Packs = packLines(packs)
+
+	return node, true, nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// maxBatchEntries bounds the number of pack size sets and packing results a batch keeps
+const maxBatchEntries = 1024
+
+// BatchFunc packs a single order of a batch
+type BatchFunc func(ctx context.Context, order models.Order) (models.PackingResult, error)
+
+// batch packs the orders of a batch one after another. The pack sizes of every SKU are fetched once for the
+// whole batch and identical orders reuse the packing result of the first one, until an order reserves stock.
+type batch struct {
+	service PackingService
+	results map[string]models.PackingResult
+}
+
+// NewBatch returns a function packing the orders of a batch like CalculatePacks, sharing the pack sizes and
+// packing results between the orders of the batch. The function is not safe for concurrent use.
+func (s PackingService) NewBatch() BatchFunc {
+	provider := &batchPackSizeProvider{PackSizeProvider: s.packSizeProvider, packSizes: map[string][]models.PackSize{}}
+	service := s
+	service.packSizeProvider = provider
+
+	b := &batch{service: service, results: map[string]models.PackingResult{}}
+	provider.onAdjust = func() {
+		clear(b.results)
+	}
+
+	return b.calculatePacks
+}
+
+// calculatePacks returns the packs required to fulfill the specified order, see PackingService.CalculatePacks
+func (b *batch) calculatePacks(ctx context.Context, order models.Order) (models.PackingResult, error) {
+	if order.Reserve {
+		return b.service.CalculatePacks(ctx, order)
+	}
+
+	// Orders that marshal the same are the same order, marshalling plain orders cannot fail
+	data, _ := json.Marshal(order)
+	key := string(data)
+	if result, ok := b.results[key]; ok {
+		return result, nil
+	}
+
+	result, err := b.service.CalculatePacks(ctx, order)
+	if err != nil {
+		return models.PackingResult{}, err
+	}
+
+	if len(b.results) >= maxBatchEntries {
+		clear(b.results)
+	}
+	b.results[key] = result
+
+	return result, nil
+}
+
+// batchPackSizeProvider keeps the pack sizes it gets from the provider for the rest of the batch,
+// forgetting them when stock is adjusted
+type batchPackSizeProvider struct {
+	PackSizeProvider
+	packSizes map[string][]models.PackSize
+	onAdjust  func()
+}
+
+// GetPackSizes returns the pack sizes of the SKU, only getting them from the provider the first time
+func (p *batchPackSizeProvider) GetPackSizes(ctx context.Context, sku string) ([]models.PackSize, error) {
+	if packSizes, ok := p.packSizes[sku]; ok {
+		return packSizes, nil
+	}
+
+	packSizes, err := p.PackSizeProvider.GetPackSizes(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.packSizes) >= maxBatchEntries {
+		clear(p.packSizes)
+	}
+	p.packSizes[sku] = packSizes
+
+	return packSizes, nil
+}
+
+// AdjustStock adjusts the stock with the provider, the pack sizes and results of the batch are outdated afterwards
+func (p *batchPackSizeProvider) AdjustStock(ctx context.Context, sku string, adjustments []models.StockAdjustment) error {
+	clear(p.packSizes)
+	p.onAdjust()
+
+	return p.PackSizeProvider.AdjustStock(ctx, sku, adjustments)
+}
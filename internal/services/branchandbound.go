@@ -0,0 +1,148 @@
+package services
+
+import (
+	"slices"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// BranchAndBoundPacker finds the best packing by searching the number of packs of every pack size like an
+// integer program, starting with the packs that suit the objective best and pruning every branch whose lower
+// bound cannot beat the best packing found so far. It is exact and needs no more memory than the pack sizes,
+// but its running time depends on how well the bounds prune.
+type BranchAndBoundPacker struct{}
+
+// Pack returns the best packing of the target by the rules of the objective
+func (BranchAndBoundPacker) Pack(packSizes []models.PackSize, target PackingTarget, objective models.Objective) (map[int]int, error) {
+	packSizes = uniquePackSizes(packSizes)
+	largest := packSizes[len(packSizes)-1].MaxItems
+	slices.SortStableFunc(packSizes, func(a, b models.PackSize) int {
+		aDominates, bDominates := dominates(a, b, objective), dominates(b, a, objective)
+		switch {
+		case aDominates && !bDominates:
+			return -1
+		case bDominates && !aDominates:
+			return 1
+		default:
+			// Pack sizes dominating each other, or neither when costs cannot be compared, go from the largest
+			return b.MaxItems - a.MaxItems
+		}
+	})
+
+	s := bbSearch{
+		packSizes: packSizes,
+		objective: objective,
+		orderQty:  target.OrderQty,
+		minQty:    max(target.MinQty, 0),
+		// Packings holding a whole largest pack more than ordered can always drop a pack, see dpPacks
+		maxQty:    min(target.MaxQty, target.OrderQty+largest-1),
+		counts:    make([]int, len(packSizes)),
+		gcds:      make([]int, len(packSizes)),
+		largest:   make([]int, len(packSizes)),
+		itemCosts: make([]float64, len(packSizes)),
+	}
+	for i := len(packSizes) - 1; i >= 0; i-- {
+		s.gcds[i], s.largest[i] = packSizes[i].MaxItems, packSizes[i].MaxItems
+		s.itemCosts[i] = packSizes[i].Cost / float64(packSizes[i].MaxItems)
+		if i < len(packSizes)-1 {
+			s.gcds[i] = gcd(s.gcds[i], s.gcds[i+1])
+			s.largest[i] = max(s.largest[i], s.largest[i+1])
+			s.itemCosts[i] = min(s.itemCosts[i], s.itemCosts[i+1])
+		}
+	}
+
+	if s.maxQty < s.minQty {
+		return nil, ErrInsufficientStock
+	}
+
+	s.branch(0, models.PackingCandidate{})
+	if s.best == nil {
+		return nil, ErrInsufficientStock
+	}
+
+	packs := make(map[int]int)
+	for i, packQty := range s.best {
+		if packQty > 0 {
+			packs[packSizes[i].MaxItems] = packQty
+		}
+	}
+
+	return packs, nil
+}
+
+// bbSearch is the state of a branch and bound search. The gcds, largest pack sizes and lowest costs per item
+// are those of the pack sizes from every index on, which bound what the remaining packs can add.
+type bbSearch struct {
+	packSizes      []models.PackSize
+	objective      models.Objective
+	orderQty       int
+	minQty, maxQty int
+	counts         []int
+	gcds, largest  []int
+	itemCosts      []float64
+	best           []int
+	bestCandidate  models.PackingCandidate
+}
+
+// branch searches the packings that add packs from the pack size at index i on to the current packing
+func (s *bbSearch) branch(i int, current models.PackingCandidate) {
+	if current.ItemsShipped >= s.minQty {
+		if s.best == nil || s.compare(current, s.bestCandidate) < 0 {
+			s.best, s.bestCandidate = slices.Clone(s.counts), current
+		}
+
+		// Adding packs to a packing that ships everything ordered only makes it worse
+		if current.ItemsShipped >= s.orderQty {
+			return
+		}
+	}
+
+	if i == len(s.packSizes) || (s.best != nil && s.compare(s.bound(i, current), s.bestCandidate) >= 0) {
+		return
+	}
+
+	packSize := s.packSizes[i]
+	hi := (s.maxQty - current.ItemsShipped) / packSize.MaxItems
+	if packSize.Stock != nil {
+		hi = min(hi, *packSize.Stock)
+	}
+
+	for packQty := hi; packQty >= 0; packQty-- {
+		s.counts[i] = packQty
+		s.branch(i+1, models.PackingCandidate{
+			ItemsShipped: current.ItemsShipped + packQty*packSize.MaxItems,
+			PackCount:    current.PackCount + packQty,
+			TotalCost:    current.TotalCost + float64(packQty)*packSize.Cost,
+		})
+	}
+	s.counts[i] = 0
+}
+
+// bound returns a packing that is at least as good as every packing adding packs from the pack size
+// at index i on to the current packing, by every rule
+func (s *bbSearch) bound(i int, current models.PackingCandidate) models.PackingCandidate {
+	need := max(s.minQty-current.ItemsShipped, 0)
+
+	// The remaining packs add a multiple of their gcd, the closest such total to the order is the best possible
+	g := s.gcds[i]
+	items := current.ItemsShipped + (need+g-1)/g*g
+	if items < s.orderQty {
+		below := current.ItemsShipped + (s.orderQty-current.ItemsShipped)/g*g
+		items = below
+		if below != s.orderQty && below+g-s.orderQty <= s.orderQty-below {
+			items = below + g
+		}
+	}
+
+	// The cost is lowered by a rounding margin so a packing costing exactly the bound is never pruned
+	return models.PackingCandidate{
+		ItemsShipped: items,
+		PackCount:    current.PackCount + (need+s.largest[i]-1)/s.largest[i],
+		TotalCost:    (current.TotalCost + float64(need)*s.itemCosts[i]) * (1 - 1e-9),
+	}
+}
+
+func (s *bbSearch) compare(a, b models.PackingCandidate) int {
+	order, _ := compareCandidates(a, b, s.orderQty, s.objective)
+	return order
+}
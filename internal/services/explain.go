@@ -0,0 +1,195 @@
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// maxRunnersUp is the number of runner-up packings an explanation lists
+const maxRunnersUp = 3
+
+// dpAlternative is a combination the dynamic programming table considered for an amount but did not keep.
+// Its packs are the item (if any) on top of the combination backtracked from the amount and layer.
+type dpAlternative struct {
+	entry  dpEntry
+	item   int
+	amount int
+	layer  int
+}
+
+// explainer collects the explanation of a packing while the dynamic programming table is built
+type explainer struct {
+	explanation *models.PackingExplanation
+	prefill     models.PackSize
+	prefillQty  int
+	// alternatives holds the best combination that lost for every candidate total
+	alternatives map[int]dpAlternative
+}
+
+func newExplainer(explanation *models.PackingExplanation, prefill models.PackSize, prefillQty int) *explainer {
+	return &explainer{
+		explanation:  explanation,
+		prefill:      prefill,
+		prefillQty:   prefillQty,
+		alternatives: make(map[int]dpAlternative),
+	}
+}
+
+// reject records a combination that lost for the amount, keeping the best one
+func (e *explainer) reject(amount int, alternative dpAlternative, objective models.Objective) {
+	if current, ok := e.alternatives[amount]; !ok || betterPacking(alternative.entry, current.entry, objective) {
+		e.alternatives[amount] = alternative
+	}
+}
+
+// explainCandidate is a candidate packing along with the way to backtrack its packs
+type explainCandidate struct {
+	models.PackingCandidate
+	alternative dpAlternative
+}
+
+// candidate returns the candidate of the amount, including the prefilled packs
+func (e *explainer) candidate(amount int, alternative dpAlternative) explainCandidate {
+	return explainCandidate{
+		PackingCandidate: models.PackingCandidate{
+			ItemsShipped: amount + e.prefill.MaxItems*e.prefillQty,
+			PackCount:    alternative.entry.packs + e.prefillQty,
+			TotalCost:    alternative.entry.cost + e.prefill.Cost*float64(e.prefillQty),
+		},
+		alternative: alternative,
+	}
+}
+
+// packs backtracks the packs of the candidate, including the prefilled packs
+func (e *explainer) packs(t dpTable, c explainCandidate) []models.PackLine {
+	packs := map[int]int{}
+	if c.alternative.amount > 0 {
+		packs = t.backtrack(c.alternative.amount, c.alternative.layer)
+	}
+	if c.alternative.item >= 0 {
+		packs[t.items[c.alternative.item].packSize] += t.items[c.alternative.item].packs
+	}
+	if e.prefillQty > 0 {
+		packs[e.prefill.MaxItems] += e.prefillQty
+	}
+
+	return packLines(packs)
+}
+
+// explain writes the explanation of the choice of the best amount among the candidate totals of the target
+func (e *explainer) explain(t dpTable, target PackingTarget, maxAmount, bestAmount int, objective models.Objective) {
+	x := e.explanation
+	x.Objective = objective
+	x.Candidates = []models.PackingCandidate{}
+	x.RunnersUp = []models.PackingCandidate{}
+	if e.prefillQty > 0 {
+		x.PrefilledPacks = &models.PackLine{MaxItems: e.prefill.MaxItems, Quantity: e.prefillQty}
+	}
+
+	lastLayer := len(t.items) - 1
+	winner := e.candidate(bestAmount, dpAlternative{entry: t.entries[bestAmount], item: -1, amount: bestAmount, layer: lastLayer})
+	orderQty := target.OrderQty
+	requestedQty := orderQty + e.prefill.MaxItems*e.prefillQty
+
+	x.ExactMatch = orderQty <= maxAmount && t.entries[orderQty].reachable()
+	if x.ExactMatch {
+		x.Reason = fmt.Sprintf("the available packs add up to exactly %d items", requestedQty)
+	} else {
+		x.Reason = fmt.Sprintf("no combination of the available packs adds up to exactly %d items", requestedQty)
+	}
+
+	// The runners-up are the best packings of every other total and the best losing packing of the chosen total
+	runnersUp := []explainCandidate{}
+	for i := max(target.MinQty, 0); i <= maxAmount; i++ {
+		if !t.entries[i].reachable() {
+			continue
+		}
+
+		c := e.candidate(i, dpAlternative{entry: t.entries[i], item: -1, amount: i, layer: lastLayer})
+		x.Candidates = append(x.Candidates, c.PackingCandidate)
+		if i != bestAmount {
+			runnersUp = append(runnersUp, c)
+		}
+	}
+	if alternative, ok := e.alternatives[bestAmount]; ok {
+		runnersUp = append(runnersUp, e.candidate(bestAmount, alternative))
+	}
+
+	slices.SortStableFunc(runnersUp, func(a, b explainCandidate) int {
+		order, _ := compareCandidates(a.PackingCandidate, b.PackingCandidate, requestedQty, objective)
+		return order
+	})
+	for _, c := range runnersUp[:min(maxRunnersUp, len(runnersUp))] {
+		c.Packs = e.packs(t, c)
+		x.RunnersUp = append(x.RunnersUp, c.PackingCandidate)
+	}
+
+	if len(runnersUp) == 0 {
+		x.Summary = fmt.Sprintf("%d items in %d packs is the only packing that fulfills the order", winner.ItemsShipped, winner.PackCount)
+		return
+	}
+
+	runnerUp := runnersUp[0]
+	_, x.DecidingRule = compareCandidates(winner.PackingCandidate, runnerUp.PackingCandidate, requestedQty, objective)
+	if x.DecidingRule == "" {
+		x.Summary = fmt.Sprintf(
+			"%d items in %d packs is tied with the runner-up on every rule, the combination found first was kept",
+			winner.ItemsShipped, winner.PackCount,
+		)
+		return
+	}
+
+	x.Summary = fmt.Sprintf(
+		"%d items in %d packs was chosen over %d items in %d packs by the %s rule",
+		winner.ItemsShipped, winner.PackCount, runnerUp.ItemsShipped, runnerUp.PackCount, x.DecidingRule,
+	)
+}
+
+// rules returns the rules the objective compares packings by, in order
+func rules(objective models.Objective) []models.Objective {
+	switch objective {
+	case models.ObjectiveFewestPacks:
+		return []models.Objective{models.ObjectiveFewestPacks, models.ObjectiveFewestItems, models.ObjectiveLowestCost}
+	case models.ObjectiveLowestCost:
+		return []models.Objective{models.ObjectiveLowestCost, models.ObjectiveFewestItems, models.ObjectiveFewestPacks}
+	default:
+		return []models.Objective{models.ObjectiveFewestItems, models.ObjectiveFewestPacks, models.ObjectiveLowestCost}
+	}
+}
+
+// compareCandidates compares two packings of the requested quantity by the rules of the objective, returning
+// the rule that decided. Packings that ship fewer items than requested are only possible when underfilling is
+// allowed, so the items rule prefers the packing closest to the requested quantity, and the larger one on ties.
+func compareCandidates(a, b models.PackingCandidate, requestedQty int, objective models.Objective) (int, models.Objective) {
+	for _, rule := range rules(objective) {
+		var order int
+		switch rule {
+		case models.ObjectiveFewestItems:
+			order = cmp.Compare(distance(a.ItemsShipped, requestedQty), distance(b.ItemsShipped, requestedQty))
+			if order == 0 {
+				order = cmp.Compare(b.ItemsShipped, a.ItemsShipped)
+			}
+		case models.ObjectiveFewestPacks:
+			order = cmp.Compare(a.PackCount, b.PackCount)
+		case models.ObjectiveLowestCost:
+			order = cmp.Compare(a.TotalCost, b.TotalCost)
+		}
+
+		if order != 0 {
+			return order, rule
+		}
+	}
+
+	return 0, ""
+}
+
+func distance(a, b int) int {
+	if a < b {
+		return b - a
+	}
+
+	return a - b
+}
@@ -0,0 +1,33 @@
+package services
+
+import "github.com/cybre/order-packing/internal/models"
+
+// TableCache exposes the packing table cache to tests
+type TableCache struct {
+	cache *dpTableCache
+}
+
+// NewTableCache returns a packing table cache keeping up to maxSize bytes of tables
+func NewTableCache(maxSize int) TableCache {
+	return TableCache{cache: newDPTableCache(maxSize)}
+}
+
+// TableSize returns the number of bytes the table of the pack sizes covering the amounts up to maxAmount takes
+func TableSize(packSizes []models.PackSize, maxAmount int) int {
+	return newDPTable(dpItems(packSizes), maxAmount, 0, models.ObjectiveFewestItems, nil).size()
+}
+
+// Table gets the table of the pack sizes covering the amounts up to maxAmount from the cache
+func (c TableCache) Table(packSizes []models.PackSize, maxAmount int) {
+	c.cache.table(packSizes, dpItems(packSizes), models.ObjectiveFewestItems, maxAmount)
+}
+
+// Cached reports whether the cache keeps a table of the pack sizes
+func (c TableCache) Cached(packSizes []models.PackSize) bool {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	_, ok := c.cache.tables[dpTableKey(packSizes, models.ObjectiveFewestItems)]
+
+	return ok
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"slices"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// GreedyPacker fills the order with as many of the largest packs as fit and covers the rest with the smallest
+// pack that holds it. It is fast and never needs more memory than the pack sizes, but it ignores the objective
+// and can miss packings that would fit the target, so it is only meant for comparisons.
+type GreedyPacker struct{}
+
+// Pack returns a packing of the target, or ErrInsufficientStock if the greedy packing does not fit it.
+// The objective is ignored.
+func (GreedyPacker) Pack(packSizes []models.PackSize, target PackingTarget, _ models.Objective) (map[int]int, error) {
+	packSizes = uniquePackSizes(packSizes)
+	slices.Reverse(packSizes)
+
+	// Packs of each size left in stock, -1 for unlimited stock
+	left := make([]int, len(packSizes))
+	for i, packSize := range packSizes {
+		left[i] = -1
+		if packSize.Stock != nil {
+			left[i] = *packSize.Stock
+		}
+	}
+	take := func(i, packQty int) {
+		if left[i] != -1 {
+			left[i] -= packQty
+		}
+	}
+
+	packs := make(map[int]int)
+	remaining := target.OrderQty
+	for i, packSize := range packSizes {
+		packQty := remaining / packSize.MaxItems
+		if left[i] != -1 {
+			packQty = min(packQty, left[i])
+		}
+
+		if packQty > 0 {
+			packs[packSize.MaxItems] += packQty
+			remaining -= packQty * packSize.MaxItems
+			take(i, packQty)
+		}
+	}
+
+	// Cover the rest with the smallest pack holding it, or the largest one left until one does,
+	// unless what is left may be underfilled
+	for remaining > target.OrderQty-target.MinQty {
+		cover := -1
+		for i := range packSizes {
+			if left[i] == 0 {
+				continue
+			}
+
+			if cover == -1 || packSizes[i].MaxItems >= remaining {
+				cover = i
+			}
+		}
+
+		if cover == -1 {
+			return nil, ErrInsufficientStock
+		}
+
+		packs[packSizes[cover].MaxItems]++
+		remaining -= packSizes[cover].MaxItems
+		take(cover, 1)
+	}
+
+	if shipped := target.OrderQty - remaining; shipped > target.MaxQty {
+		return nil, ErrInsufficientStock
+	}
+
+	return packs, nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+var (
+	// ErrHistoryUnavailable is returned when the versions of the pack sizes are asked for but no history is kept
+	ErrHistoryUnavailable = fmt.Errorf("pack size history is not kept")
+
+	// ErrUnknownVersion is returned when a pack size version does not exist
+	ErrUnknownVersion = fmt.Errorf("unknown pack size version")
+)
+
+// PackSizeHistory describes a type that keeps the versions of the pack sizes per product
+type PackSizeHistory interface {
+	// AddPackSizeVersion saves the version of the pack sizes of its SKU and returns it with its ID set
+	AddPackSizeVersion(ctx context.Context, version models.PackSizeSetVersion) (models.PackSizeSetVersion, error)
+	// PackSizeVersions returns the saved versions of the pack sizes for the specified SKU, the newest first.
+	// ErrHistoryUnavailable is returned when no history is kept where the pack sizes are stored.
+	PackSizeVersions(ctx context.Context, sku string) ([]models.PackSizeSetVersion, error)
+	// PackSizeVersion returns a saved version of the pack sizes for the specified SKU, ErrUnknownVersion if there is none
+	PackSizeVersion(ctx context.Context, sku string, id int) (models.PackSizeSetVersion, error)
+}
+
+// WithPackSizeHistory keeps a version of the pack sizes in the history every time they are updated
+func WithPackSizeHistory(history PackSizeHistory) Option {
+	return func(s *PackingService) {
+		s.history = history
+	}
+}
+
+type authorKey struct{}
+
+// ContextWithAuthor returns a copy of the context naming who makes the changes done with it
+func ContextWithAuthor(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, authorKey{}, author)
+}
+
+// AuthorFromContext returns who makes the changes done with the context, empty when unknown
+func AuthorFromContext(ctx context.Context) string {
+	author, _ := ctx.Value(authorKey{}).(string)
+
+	return author
+}
+
+// PackSizeVersions returns the saved versions of the pack sizes for the specified SKU, the newest first
+func (s PackingService) PackSizeVersions(ctx context.Context, sku string) ([]models.PackSizeSetVersion, error) {
+	if s.history == nil {
+		return nil, ErrHistoryUnavailable
+	}
+
+	return s.history.PackSizeVersions(ctx, sku)
+}
+
+// PackSizeVersion returns a saved version of the pack sizes for the specified SKU
+func (s PackingService) PackSizeVersion(ctx context.Context, sku string, id int) (models.PackSizeSetVersion, error) {
+	if s.history == nil {
+		return models.PackSizeSetVersion{}, ErrHistoryUnavailable
+	}
+
+	return s.history.PackSizeVersion(ctx, sku, id)
+}
+
+// RollbackPackSizes updates the pack sizes for the specified SKU to the pack sizes of a saved version, stock
+// included, and returns the version the rollback is saved as
+func (s PackingService) RollbackPackSizes(ctx context.Context, sku string, id int) (models.PackSizeSetVersion, error) {
+	version, err := s.PackSizeVersion(ctx, sku, id)
+	if err != nil {
+		return models.PackSizeSetVersion{}, err
+	}
+
+	return s.updatePackSizes(ctx, sku, "", version.PackSizes, id)
+}
+
+// recordVersion saves the version of the pack sizes replacing the previous ones in the history. The pack sizes
+// from before the history was kept are saved first, so the first update can be rolled back as well.
+func (s PackingService) recordVersion(ctx context.Context, sku string, previous, packSizes []models.PackSize, restoredFrom int) (models.PackSizeSetVersion, error) {
+	versions, err := s.history.PackSizeVersions(ctx, sku)
+	if errors.Is(err, ErrHistoryUnavailable) {
+		// The provider does not keep a history where the pack sizes are stored
+		return models.PackSizeSetVersion{SKU: sku, PackSizes: packSizes}, nil
+	}
+	if err != nil {
+		return models.PackSizeSetVersion{}, fmt.Errorf("failed to get pack size versions: %w", err)
+	}
+
+	now := s.now().UTC()
+	if len(versions) == 0 && len(previous) > 0 {
+		initial := models.PackSizeSetVersion{SKU: sku, CreatedAt: now, PackSizes: previous, Changes: models.DiffPackSizes(nil, previous)}
+		if _, err := s.history.AddPackSizeVersion(ctx, initial); err != nil {
+			return models.PackSizeSetVersion{}, fmt.Errorf("failed to save pack size version: %w", err)
+		}
+	}
+
+	version, err := s.history.AddPackSizeVersion(ctx, models.PackSizeSetVersion{
+		SKU:          sku,
+		Author:       AuthorFromContext(ctx),
+		CreatedAt:    now,
+		PackSizes:    packSizes,
+		Changes:      models.DiffPackSizes(previous, packSizes),
+		RestoredFrom: restoredFrom,
+	})
+	if err != nil {
+		return models.PackSizeSetVersion{}, fmt.Errorf("failed to save pack size version: %w", err)
+	}
+
+	return version, nil
+}
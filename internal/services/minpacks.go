@@ -0,0 +1,396 @@
+package services
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// maxDPAmount bounds the amounts a dynamic programming table covers. Nearly coprime large pack sizes have residue
+// windows of billions of items, whose tables would take more memory than the process has.
+const maxDPAmount = 1 << 22
+
+// largeDPAmount is the size above which a table is only built and used while one of largeDPTables is free.
+// A table of maxDPAmount takes about 64 MB and another 512 KB per pack size, so orders needing large tables
+// are packed a few at a time however many arrive at once.
+const largeDPAmount = 1 << 18
+
+// largeDPTables limits how many tables larger than largeDPAmount are held at once
+var largeDPTables = make(chan struct{}, 2)
+
+// minPacks finds the best pack size combination for the target. The explanation of the choice
+// is written to the explanation if it is not nil.
+// The tables of pack size sets packed before are reused when there is a cache of them.
+func minPacks(packSizes []models.PackSize, target PackingTarget, objective models.Objective, explanation *models.PackingExplanation, tables *dpTableCache) (map[int]int, error) {
+	return boundedPacks(uniquePackSizes(packSizes), target, objective, explanation, tables)
+}
+
+// uniquePackSizes returns the pack sizes sorted in ascending order, keeping only the cheapest pack of each size
+func uniquePackSizes(packSizes []models.PackSize) []models.PackSize {
+	packSizes = slices.Clone(packSizes)
+	slices.SortFunc(packSizes, func(a, b models.PackSize) int {
+		if a.MaxItems != b.MaxItems {
+			return a.MaxItems - b.MaxItems
+		}
+
+		return cmp.Compare(a.Cost, b.Cost)
+	})
+
+	return slices.CompactFunc(packSizes, func(a, b models.PackSize) bool {
+		return a.MaxItems == b.MaxItems
+	})
+}
+
+// boundedPacks finds the best pack size combination for the target.
+// Packs sizes must be unique and sorted in ascending order. The explanation and the table cache are optional.
+func boundedPacks(packSizes []models.PackSize, target PackingTarget, objective models.Objective, explanation *models.PackingExplanation, tables *dpTableCache) (map[int]int, error) {
+	// Fill everything above the residue window with the dominant pack up front, so the dynamic
+	// programming table only ever has to cover the window instead of the whole order quantity.
+	// Swapping packs for dominant ones keeps the total, so the range of totals does not change that.
+	dominant, ok := dominantPackSize(packSizes, objective)
+	dominantPacks := 0
+	if ok {
+		if window := residueWindow(packSizes, dominant, objective); target.MinQty > window {
+			dominantPacks = (target.MinQty - window) / dominant.MaxItems
+			target = target.without(dominantPacks * dominant.MaxItems)
+		}
+	}
+
+	var ex *explainer
+	if explanation != nil {
+		ex = newExplainer(explanation, dominant, dominantPacks)
+	}
+
+	packSizeCombination, err := dpPacks(packSizes, target, objective, ex, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	if dominantPacks > 0 {
+		packSizeCombination[dominant.MaxItems] += dominantPacks
+	}
+
+	return packSizeCombination, nil
+}
+
+// dominates reports whether filling an order with pack a is at least as good as filling it with pack b.
+// Larger packs dominate when the objective minimises the number of packs, while the pack with the
+// lowest cost per item (the larger one on ties) dominates when it minimises cost.
+func dominates(a, b models.PackSize, objective models.Objective) bool {
+	if objective == models.ObjectiveLowestCost {
+		aCost, bCost := a.Cost*float64(b.MaxItems), b.Cost*float64(a.MaxItems)
+		if aCost != bCost {
+			return aCost < bCost
+		}
+	}
+
+	return a.MaxItems >= b.MaxItems
+}
+
+// dominantPackSize returns the pack size with unlimited stock that every large enough optimal
+// combination is built from, or false if every pack size has limited stock
+func dominantPackSize(packSizes []models.PackSize, objective models.Objective) (models.PackSize, bool) {
+	var dominant models.PackSize
+	found := false
+	for _, packSize := range packSizes {
+		if packSize.Stock == nil && (!found || dominates(packSize, dominant, objective)) {
+			dominant, found = packSize, true
+		}
+	}
+
+	return dominant, found
+}
+
+// residueWindow returns an upper bound on the number of items an optimal combination can hold in
+// packs other than the dominant one (D). Using a dominated pack c at least lcm(c, D)/c times can always
+// be swapped for D packs that are at least as good, and any D dominated packs contain a subset summing
+// to a multiple of D, so both bounds hold and the tighter one is used. Packs that are not dominated only
+// have limited stock, which bounds them instead. Past the window every optimal combination for an
+// amount contains a D pack, which is what makes filling with D packs up front exact.
+func residueWindow(packSizes []models.PackSize, dominant models.PackSize, objective models.Objective) int {
+	lcmBound, largestOther, allDominated := 0, 0, true
+	for _, packSize := range packSizes {
+		if packSize.MaxItems == dominant.MaxItems {
+			continue
+		}
+
+		bound := math.MaxInt
+		if dominates(dominant, packSize, objective) {
+			bound = lcm(packSize.MaxItems, dominant.MaxItems) - packSize.MaxItems
+		} else {
+			allDominated = false
+		}
+		if packSize.Stock != nil {
+			bound = min(bound, *packSize.Stock*packSize.MaxItems)
+		}
+
+		lcmBound += bound
+		largestOther = max(largestOther, packSize.MaxItems)
+	}
+
+	if !allDominated {
+		return lcmBound
+	}
+
+	return min(lcmBound, (dominant.MaxItems-1)*largestOther)
+}
+
+// fitsDPTable reports whether the tables of orders packed with the pack sizes for the objective stay within
+// maxDPAmount. Orders are filled with the dominant pack (D) down to at most the residue window plus D items, and
+// their tables cover up to the largest pack size more. Orders that underfill or are packed with limited stock
+// only may still need larger tables.
+func fitsDPTable(packSizes []models.PackSize, objective models.Objective) bool {
+	packSizes = uniquePackSizes(packSizes)
+	dominant, ok := dominantPackSize(packSizes, objective)
+	if !ok {
+		return true
+	}
+
+	// Checked first so the residue window of huge pack sizes is never computed, it could overflow
+	largest := packSizes[len(packSizes)-1].MaxItems
+	if dominant.MaxItems+largest > maxDPAmount {
+		return false
+	}
+
+	return residueWindow(packSizes, dominant, objective) <= maxDPAmount-dominant.MaxItems-largest
+}
+
+// dpItem is a group of packs of a single size the dynamic programming table can add in one step.
+// Pack sizes with unlimited stock are a single pack that can be added any number of times, while
+// limited stock is split into groups of 1, 2, 4, ... packs that can each be added at most once.
+type dpItem struct {
+	packSize  int
+	packs     int
+	cost      float64
+	unbounded bool
+}
+
+func dpItems(packSizes []models.PackSize) []dpItem {
+	items := []dpItem{}
+	for _, packSize := range packSizes {
+		if packSize.Stock == nil {
+			items = append(items, dpItem{packSize: packSize.MaxItems, packs: 1, cost: packSize.Cost, unbounded: true})
+			continue
+		}
+
+		for remaining, packs := *packSize.Stock, 1; remaining > 0; remaining, packs = remaining-packs, packs*2 {
+			packs = min(packs, remaining)
+			items = append(items, dpItem{packSize: packSize.MaxItems, packs: packs, cost: packSize.Cost * float64(packs)})
+		}
+	}
+
+	return items
+}
+
+// dpEntry is the best known way to pack a single amount in the dynamic programming table
+type dpEntry struct {
+	packs int
+	cost  float64
+}
+
+func (e dpEntry) reachable() bool {
+	return e.packs != math.MaxInt32
+}
+
+// candidate returns the packing of the entry as a candidate for the amount, without its packs
+func (e dpEntry) candidate(amount int) models.PackingCandidate {
+	return models.PackingCandidate{ItemsShipped: amount, PackCount: e.packs, TotalCost: e.cost}
+}
+
+// betterPacking reports whether a packs the same amount better than b for the objective
+func betterPacking(a, b dpEntry, objective models.Objective) bool {
+	if objective == models.ObjectiveLowestCost && a.cost != b.cost {
+		return a.cost < b.cost
+	}
+
+	if a.packs != b.packs {
+		return a.packs < b.packs
+	}
+
+	return a.cost < b.cost
+}
+
+// dpTable is the dynamic programming table holding the best packing of every amount up to its size
+type dpTable struct {
+	items   []dpItem
+	entries []dpEntry
+	// used keeps track of the amounts each item improved (for backtracking)
+	used [][]uint64
+}
+
+// backtrack returns the pack size combination of the amount using the items up to the specified layer,
+// walking the items from last to first
+func (t dpTable) backtrack(amount, layer int) map[int]int {
+	packSizeCombination := make(map[int]int)
+	for i, j := amount, layer; i > 0; {
+		if t.used[j][i/64]&(1<<(i%64)) == 0 {
+			j--
+			continue
+		}
+
+		packSizeCombination[t.items[j].packSize] += t.items[j].packs
+		i -= t.items[j].packSize * t.items[j].packs
+		if !t.items[j].unbounded {
+			j--
+		}
+	}
+
+	return packSizeCombination
+}
+
+// newDPTable builds the table of the best packing of every amount up to maxAmount with the items.
+// The explainer is optional and sees the losing combinations of the amounts from minQty on.
+func newDPTable(items []dpItem, maxAmount, minQty int, objective models.Objective, ex *explainer) dpTable {
+	// Initialize the dynamic programming table (for memoization)
+	table := dpTable{
+		items:   items,
+		entries: make([]dpEntry, maxAmount+1),
+		used:    make([][]uint64, len(items)),
+	}
+	dp := table.entries
+	for i := range dp {
+		dp[i] = dpEntry{packs: math.MaxInt32} // Initialize with a large value
+	}
+
+	// Base case: 0 packs are needed for 0 items
+	dp[0] = dpEntry{}
+
+	// Calculate the best packing for each amount from the item size to maxAmount. Unbounded items are added
+	// in ascending order so they can build on themselves, the others in descending order so they are added once.
+	for j, item := range items {
+		table.used[j] = make([]uint64, maxAmount/64+1)
+		itemSize := item.packSize * item.packs
+
+		for k := itemSize; k <= maxAmount; k++ {
+			i := k
+			if !item.unbounded {
+				i = maxAmount + itemSize - k
+			}
+
+			previous := dp[i-itemSize]
+			if !previous.reachable() {
+				continue
+			}
+
+			candidate := dpEntry{packs: previous.packs + item.packs, cost: previous.cost + item.cost}
+			better := !dp[i].reachable() || betterPacking(candidate, dp[i], objective)
+
+			// Let the explainer see the combinations of the candidate totals that lose
+			if ex != nil && i >= minQty {
+				switch {
+				case !better && item.unbounded:
+					ex.reject(i, dpAlternative{entry: candidate, item: j, amount: i - itemSize, layer: j}, objective)
+				case !better:
+					ex.reject(i, dpAlternative{entry: candidate, item: j, amount: i - itemSize, layer: j - 1}, objective)
+				case dp[i].reachable():
+					ex.reject(i, dpAlternative{entry: dp[i], item: -1, amount: i, layer: j - 1}, objective)
+				}
+			}
+
+			if better {
+				dp[i] = candidate
+				table.used[j][i/64] |= 1 << (i % 64)
+			}
+		}
+	}
+
+	return table
+}
+
+// dpPacks finds the pack size combination for the target using dynamic programming.
+// Packs sizes must be unique and sorted in ascending order. The explainer and the table cache are optional,
+// the table is not taken from the cache when explaining since the explainer watches it being built.
+func dpPacks(packSizes []models.PackSize, target PackingTarget, objective models.Objective, ex *explainer, tables *dpTableCache) (map[int]int, error) {
+	orderQty, minQty := target.OrderQty, max(target.MinQty, 0)
+
+	items := dpItems(packSizes)
+	if len(items) == 0 {
+		if minQty == 0 {
+			return map[int]int{}, nil
+		}
+
+		return nil, ErrInsufficientStock
+	}
+
+	// Calculate the maximum amount to consider, including possible overshoots. Shipping the smallest
+	// pack size on top of the order is never needed for the fewest items when stock is unlimited, while
+	// any combination that overshoots by the largest pack size or more could drop a pack and still be better.
+	maxAmount := orderQty + packSizes[len(packSizes)-1].MaxItems - 1
+	capacity, unlimited := 0, true
+	for _, packSize := range packSizes {
+		if packSize.Stock == nil {
+			capacity = math.MaxInt
+			continue
+		}
+
+		unlimited = false
+		if capacity != math.MaxInt {
+			capacity += *packSize.Stock * packSize.MaxItems
+		}
+	}
+	if objective == models.ObjectiveFewestItems && unlimited {
+		maxAmount = orderQty + packSizes[0].MaxItems
+	}
+	if capacity < minQty || target.MaxQty < minQty {
+		return nil, ErrInsufficientStock
+	}
+	maxAmount = max(min(maxAmount, capacity, target.MaxQty), minQty)
+	if maxAmount > maxDPAmount {
+		return nil, ErrPackingTooLarge
+	}
+
+	if maxAmount > largeDPAmount {
+		largeDPTables <- struct{}{}
+		defer func() { <-largeDPTables }()
+	}
+
+	var table dpTable
+	if tables != nil && ex == nil && unlimited {
+		table = tables.table(packSizes, items, objective, maxAmount)
+	} else {
+		table = newDPTable(items, maxAmount, minQty, objective, ex)
+	}
+	dp := table.entries
+
+	// Pick the best of the candidate amounts by the rules of the objective
+	bestAmount := -1
+	for i := minQty; i <= maxAmount; i++ {
+		if !dp[i].reachable() {
+			continue
+		}
+
+		if bestAmount == -1 {
+			bestAmount = i
+			continue
+		}
+
+		if order, _ := compareCandidates(dp[i].candidate(i), dp[bestAmount].candidate(bestAmount), orderQty, objective); order < 0 {
+			bestAmount = i
+		}
+	}
+
+	if bestAmount == -1 {
+		return nil, ErrInsufficientStock
+	}
+
+	if ex != nil {
+		ex.explain(table, target, maxAmount, bestAmount, objective)
+	}
+
+	// Backtrack to find the pack size combination for the best amount
+	return table.backtrack(bestAmount, len(items)-1), nil
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+func lcm(a, b int) int {
+	return a / gcd(a, b) * b
+}
@@ -0,0 +1,83 @@
+package services
+
+import "github.com/cybre/order-packing/internal/models"
+
+// packLevel is the level name of the packs in a packaging plan
+const packLevel = "pack"
+
+// packagingPlan packs the pack lines level by level into the packaging hierarchy. Every level is filled in order,
+// largest packs first, so only the last unit of a level can be partially filled. Identical units are grouped,
+// which keeps the plan as small as the pack lines no matter how many packs there are.
+func packagingPlan(hierarchy models.PackagingHierarchy, packs []models.PackLine) *models.PackagingPlan {
+	units := make([]models.PackagingUnit, 0, len(packs))
+	packCount := 0
+	for _, line := range packs {
+		units = append(units, models.PackagingUnit{Level: packLevel, Quantity: line.Quantity, Items: line.MaxItems})
+		packCount += line.Quantity
+	}
+
+	plan := &models.PackagingPlan{Levels: []models.PackagingLevelCount{{Level: packLevel, Count: packCount}}}
+	for _, level := range hierarchy {
+		units = packLevelUnits(level, units)
+
+		count := models.PackagingLevelCount{Level: level.Name}
+		for _, unit := range units {
+			count.Count += unit.Quantity
+			if unit.Partial {
+				count.Partial += unit.Quantity
+			}
+		}
+		plan.Levels = append(plan.Levels, count)
+	}
+	plan.Units = units
+
+	return plan
+}
+
+// packLevelUnits fills the units of the level with the groups of units of the level below, in order
+func packLevelUnits(level models.PackagingLevel, lower []models.PackagingUnit) []models.PackagingUnit {
+	units := []models.PackagingUnit{}
+	current := models.PackagingUnit{Level: level.Name, Quantity: 1}
+	filled := 0
+	for _, group := range lower {
+		remaining := group.Quantity
+		for remaining > 0 {
+			// Units holding nothing but this group are all the same, they are added at once
+			if filled == 0 && remaining >= level.Holds {
+				full := remaining / level.Holds
+				content := group
+				content.Quantity = level.Holds
+				units = append(units, models.PackagingUnit{
+					Level:    level.Name,
+					Quantity: full,
+					Items:    content.Items * level.Holds,
+					Contents: []models.PackagingUnit{content},
+				})
+				remaining -= full * level.Holds
+
+				continue
+			}
+
+			content := group
+			content.Quantity = min(level.Holds-filled, remaining)
+			current.Contents = append(current.Contents, content)
+			current.Items += content.Items * content.Quantity
+			filled += content.Quantity
+			remaining -= content.Quantity
+
+			if filled == level.Holds {
+				units = append(units, current)
+				current = models.PackagingUnit{Level: level.Name, Quantity: 1}
+				filled = 0
+			}
+		}
+	}
+
+	// The units left over go into a last, partially filled unit
+	if filled > 0 {
+		current.Partial = true
+		units = append(units, current)
+	}
+
+	return units
+}
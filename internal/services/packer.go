@@ -0,0 +1,128 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// DefaultPacker is the name of the packing strategy used when none is configured
+const DefaultPacker = "dp"
+
+var (
+	// ErrUnknownPacker is returned when a packing strategy is not registered
+	ErrUnknownPacker = fmt.Errorf("unknown packing strategy")
+
+	// ErrExplainUnsupported is returned when an order asks for an explanation the packing strategy cannot give
+	ErrExplainUnsupported = fmt.Errorf("packing strategy cannot explain its packings")
+)
+
+// PackingTarget is the ordered quantity along with the range of items a packing may ship for it
+type PackingTarget struct {
+	// OrderQty is the number of items ordered
+	OrderQty int
+	// MinQty is the fewest items that may be shipped
+	MinQty int
+	// MaxQty is the most items that may be shipped, math.MaxInt when overshooting is not limited
+	MaxQty int
+}
+
+// NewPackingTarget returns the target of an order that may be overshot without limit but not underfilled
+func NewPackingTarget(orderQty int) PackingTarget {
+	return PackingTarget{OrderQty: orderQty, MinQty: orderQty, MaxQty: math.MaxInt}
+}
+
+// without returns the target of what is left to pack once the specified number of items is packed
+func (t PackingTarget) without(items int) PackingTarget {
+	t.OrderQty -= items
+	t.MinQty -= items
+	if t.MaxQty != math.MaxInt {
+		t.MaxQty -= items
+	}
+
+	return t
+}
+
+// Packer is a packing strategy that finds the packs to ship for an order
+type Packer interface {
+	// Pack returns the number of packs of every pack size to ship for the target, packing it as well as the
+	// strategy can by the rules of the objective. The pack sizes are never empty but may repeat a size, in which
+	// case the cheapest pack is used. Limited stock must be respected and ErrInsufficientStock is returned when
+	// the strategy finds no packing within the target.
+	Pack(packSizes []models.PackSize, target PackingTarget, objective models.Objective) (map[int]int, error)
+}
+
+// ExplainingPacker is a Packer that can explain why it chose a packing
+type ExplainingPacker interface {
+	Packer
+	// PackExplained packs the target like Pack and writes the explanation of the choice to the explanation
+	PackExplained(packSizes []models.PackSize, target PackingTarget, objective models.Objective, explanation *models.PackingExplanation) (map[int]int, error)
+}
+
+var (
+	packersMu sync.RWMutex
+	packers   = map[string]Packer{
+		DefaultPacker:      DPPacker{},
+		"greedy":           GreedyPacker{},
+		"branch-and-bound": BranchAndBoundPacker{},
+	}
+)
+
+// RegisterPacker registers the packing strategy under the name, replacing any strategy registered under it
+func RegisterPacker(name string, packer Packer) {
+	packersMu.Lock()
+	defer packersMu.Unlock()
+
+	packers[name] = packer
+}
+
+// LookupPacker returns the packing strategy registered under the name, the default one when the name is empty
+func LookupPacker(name string) (Packer, error) {
+	if name == "" {
+		name = DefaultPacker
+	}
+
+	packersMu.RLock()
+	defer packersMu.RUnlock()
+
+	packer, ok := packers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPacker, name)
+	}
+
+	return packer, nil
+}
+
+// PackerNames returns the names of the registered packing strategies in alphabetical order
+func PackerNames() []string {
+	packersMu.RLock()
+	defer packersMu.RUnlock()
+
+	names := make([]string, 0, len(packers))
+	for name := range packers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// DPPacker finds the best packing with dynamic programming over a window of the order quantity
+// bounded by the pack sizes, see minPacks. A PackingService gives its DPPacker a cache of the tables
+// of the pack size sets it packed before.
+type DPPacker struct {
+	tables *dpTableCache
+}
+
+// Pack returns the best packing of the target by the rules of the objective
+func (p DPPacker) Pack(packSizes []models.PackSize, target PackingTarget, objective models.Objective) (map[int]int, error) {
+	return minPacks(packSizes, target, objective, nil, p.tables)
+}
+
+// PackExplained returns the best packing of the target by the rules of the objective along with its explanation
+func (p DPPacker) PackExplained(packSizes []models.PackSize, target PackingTarget, objective models.Objective, explanation *models.PackingExplanation) (map[int]int, error) {
+	return minPacks(packSizes, target, objective, explanation, p.tables)
+}
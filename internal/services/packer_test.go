@@ -0,0 +1,314 @@
+package services_test
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
+)
+
+// exactPackers are the packing strategies that must always find the best packing
+var exactPackers = map[string]bool{
+	services.DefaultPacker: true,
+	"branch-and-bound":     true,
+}
+
+type conformanceCase struct {
+	name      string
+	packSizes []models.PackSize
+	target    services.PackingTarget
+	objective models.Objective
+	// expected is the best packing of the cases too large for the reference to enumerate
+	expected map[int]int
+}
+
+func conformanceCases() []conformanceCase {
+	one, two := 1, 2
+	maxOvershoot := func(orderQty, overshoot int) services.PackingTarget {
+		return services.PackingTarget{OrderQty: orderQty, MinQty: orderQty, MaxQty: orderQty + overshoot}
+	}
+	underfill := func(orderQty, underfill int) services.PackingTarget {
+		return services.PackingTarget{OrderQty: orderQty, MinQty: orderQty - underfill, MaxQty: math.MaxInt}
+	}
+	defaultPackSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}, {MaxItems: 2000}, {MaxItems: 5000}}
+
+	testCases := []conformanceCase{
+		{name: "Exact match", packSizes: defaultPackSizes, target: services.NewPackingTarget(750)},
+		{name: "Overshoot", packSizes: defaultPackSizes, target: services.NewPackingTarget(501)},
+		{name: "Large order", packSizes: defaultPackSizes, target: services.NewPackingTarget(12001)},
+		{name: "Very large order", packSizes: defaultPackSizes, target: services.NewPackingTarget(2_000_000_001), expected: map[int]int{250: 1, 5000: 400000}},
+		{name: "Coprime pack sizes", packSizes: []models.PackSize{{MaxItems: 23}, {MaxItems: 31}, {MaxItems: 53}}, target: services.NewPackingTarget(500_000), expected: map[int]int{23: 2, 31: 7, 53: 9429}},
+		{name: "Repeated pack size", packSizes: []models.PackSize{{MaxItems: 250, Cost: 3}, {MaxItems: 250, Cost: 2}}, target: services.NewPackingTarget(251)},
+		{
+			name:      "Lowest cost",
+			packSizes: []models.PackSize{{MaxItems: 250, Cost: 1}, {MaxItems: 500, Cost: 3}, {MaxItems: 1000, Cost: 5}},
+			target:    services.NewPackingTarget(1001),
+			objective: models.ObjectiveLowestCost,
+		},
+		{
+			name:      "Fewest packs",
+			packSizes: []models.PackSize{{MaxItems: 3}, {MaxItems: 5}, {MaxItems: 12}},
+			target:    services.NewPackingTarget(14),
+			objective: models.ObjectiveFewestPacks,
+		},
+		{
+			name:      "Limited stock",
+			packSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500, Stock: &one}, {MaxItems: 5000, Stock: &two}},
+			target:    services.NewPackingTarget(12000),
+		},
+		{
+			name:      "Insufficient stock",
+			packSizes: []models.PackSize{{MaxItems: 250, Stock: &one}, {MaxItems: 500, Stock: &one}},
+			target:    services.NewPackingTarget(751),
+		},
+		{name: "Overshoot limit", packSizes: []models.PackSize{{MaxItems: 3}, {MaxItems: 5}}, target: maxOvershoot(7, 1)},
+		{name: "Overshoot limit not met", packSizes: []models.PackSize{{MaxItems: 250}}, target: maxOvershoot(251, 100)},
+		{name: "Underfill", packSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}, target: underfill(260, 10)},
+	}
+
+	// Random small cases the reference can enumerate
+	r := rand.New(rand.NewSource(1))
+	objectives := []models.Objective{models.ObjectiveFewestItems, models.ObjectiveFewestPacks, models.ObjectiveLowestCost}
+	for i := 0; i < 300; i++ {
+		packSizes := make([]models.PackSize, 1+r.Intn(4))
+		for j := range packSizes {
+			packSizes[j] = models.PackSize{MaxItems: 1 + r.Intn(25), Cost: float64(r.Intn(5))}
+			if r.Intn(3) == 0 {
+				stock := r.Intn(6)
+				packSizes[j].Stock = &stock
+			}
+		}
+
+		orderQty := 1 + r.Intn(300)
+		target := services.NewPackingTarget(orderQty)
+		if r.Intn(3) == 0 {
+			target.MaxQty = orderQty + r.Intn(15)
+		}
+		if r.Intn(3) == 0 {
+			target.MinQty = orderQty - r.Intn(orderQty)
+		}
+
+		testCases = append(testCases, conformanceCase{
+			name:      "Random",
+			packSizes: packSizes,
+			target:    target,
+			objective: objectives[r.Intn(len(objectives))],
+		})
+	}
+
+	return testCases
+}
+
+// TestPackers_Conformance runs every registered packing strategy through the same cases. Every strategy has to
+// return valid packings, the exact ones also have to find the best packing whenever there is one.
+func TestPackers_Conformance(t *testing.T) {
+	for _, name := range services.PackerNames() {
+		packer, err := services.LookupPacker(name)
+		if err != nil {
+			t.Fatalf("failed to look up packer %q: %v", name, err)
+		}
+
+		t.Run(name, func(t *testing.T) {
+			for _, tc := range conformanceCases() {
+				objective := tc.objective
+				if objective == "" {
+					objective = models.ObjectiveFewestItems
+				}
+
+				// Act
+				packs, err := packer.Pack(tc.packSizes, tc.target, objective)
+
+				// Assert
+				expected, feasible := referencePacking(tc.packSizes, tc.target, objective)
+				if tc.expected != nil {
+					expected, _ = checkPacking(tc.packSizes, tc.target, tc.expected)
+				}
+				if err != nil {
+					if !errors.Is(err, services.ErrInsufficientStock) {
+						t.Fatalf("%s: expected error to be %v, but got %v", tc.name, services.ErrInsufficientStock, err)
+					}
+
+					if feasible && exactPackers[name] {
+						t.Errorf("%s: expected packing %+v of %v for %+v, but got no packing", tc.name, expected, tc.packSizes, tc.target)
+					}
+
+					continue
+				}
+
+				if !feasible {
+					t.Errorf("%s: expected no packing of %v for %+v, but got %v", tc.name, tc.packSizes, tc.target, packs)
+					continue
+				}
+
+				packing, err := checkPacking(tc.packSizes, tc.target, packs)
+				if err != nil {
+					t.Errorf("%s: invalid packing %v of %v for %+v: %v", tc.name, packs, tc.packSizes, tc.target, err)
+					continue
+				}
+
+				if exactPackers[name] && compareKeys(packing, expected, tc.target.OrderQty, objective) != 0 {
+					t.Errorf("%s: expected packing %+v of %v for %+v, but got %+v (%v)", tc.name, expected, tc.packSizes, tc.target, packing, packs)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupPacker_Unknown_ReturnError(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	_, err := services.LookupPacker("unknown")
+
+	// Assert
+	if !errors.Is(err, services.ErrUnknownPacker) {
+		t.Errorf("expected error to be %v, but got %v", services.ErrUnknownPacker, err)
+	}
+}
+
+func TestLookupPacker_Default(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	packer, err := services.LookupPacker("")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to look up the default packer: %v", err)
+	}
+
+	if _, ok := packer.(services.DPPacker); !ok {
+		t.Errorf("expected the default packer to be %T, but got %T", services.DPPacker{}, packer)
+	}
+}
+
+// checkPacking checks the packs only use the pack sizes within their stock and fit the target
+func checkPacking(packSizes []models.PackSize, target services.PackingTarget, packs map[int]int) (models.PackingCandidate, error) {
+	packing := models.PackingCandidate{}
+	for packSize, packQty := range packs {
+		if packQty <= 0 {
+			return packing, errors.New("non-positive pack quantity")
+		}
+
+		found, stock, cost := false, -1, math.Inf(1)
+		for _, ps := range packSizes {
+			if ps.MaxItems != packSize {
+				continue
+			}
+
+			found = true
+			if ps.Cost < cost {
+				cost = ps.Cost
+				stock = -1
+				if ps.Stock != nil {
+					stock = *ps.Stock
+				}
+			}
+		}
+
+		if !found {
+			return packing, errors.New("unknown pack size")
+		}
+
+		if stock != -1 && packQty > stock {
+			return packing, errors.New("stock exceeded")
+		}
+
+		packing.ItemsShipped += packSize * packQty
+		packing.PackCount += packQty
+		packing.TotalCost += cost * float64(packQty)
+	}
+
+	if packing.ItemsShipped < target.MinQty || packing.ItemsShipped > target.MaxQty {
+		return packing, errors.New("items shipped outside the target")
+	}
+
+	return packing, nil
+}
+
+// referencePacking finds the best packing by enumerating every packing that could be the best one
+func referencePacking(packSizes []models.PackSize, target services.PackingTarget, objective models.Objective) (models.PackingCandidate, bool) {
+	// Keep the cheapest pack of each size, like the packers do
+	unique := map[int]models.PackSize{}
+	largest := 0
+	for _, ps := range packSizes {
+		if current, ok := unique[ps.MaxItems]; !ok || ps.Cost < current.Cost {
+			unique[ps.MaxItems] = ps
+		}
+		largest = max(largest, ps.MaxItems)
+	}
+
+	sizes := make([]models.PackSize, 0, len(unique))
+	for _, ps := range unique {
+		sizes = append(sizes, ps)
+	}
+
+	// Too large to enumerate, these cases give the expected packing
+	maxQty := min(target.MaxQty, target.OrderQty+largest-1)
+	if maxQty > 20_000 {
+		return models.PackingCandidate{}, true
+	}
+
+	var best models.PackingCandidate
+	found := false
+	var enumerate func(i int, current models.PackingCandidate)
+	enumerate = func(i int, current models.PackingCandidate) {
+		if i == len(sizes) {
+			if current.ItemsShipped >= target.MinQty && (!found || compareKeys(current, best, target.OrderQty, objective) < 0) {
+				best, found = current, true
+			}
+			return
+		}
+
+		for packQty := 0; current.ItemsShipped+packQty*sizes[i].MaxItems <= maxQty; packQty++ {
+			if sizes[i].Stock != nil && packQty > *sizes[i].Stock {
+				break
+			}
+
+			enumerate(i+1, models.PackingCandidate{
+				ItemsShipped: current.ItemsShipped + packQty*sizes[i].MaxItems,
+				PackCount:    current.PackCount + packQty,
+				TotalCost:    current.TotalCost + float64(packQty)*sizes[i].Cost,
+			})
+		}
+	}
+	enumerate(0, models.PackingCandidate{})
+
+	return best, found
+}
+
+// compareKeys compares two packings of the ordered quantity by the rules of the objective. Shipping closer
+// to the ordered quantity (more rather than less on ties) is better for the items rule.
+func compareKeys(a, b models.PackingCandidate, orderQty int, objective models.Objective) int {
+	items := func(c models.PackingCandidate) int {
+		if c.ItemsShipped < orderQty {
+			return 2*(orderQty-c.ItemsShipped) + 1
+		}
+
+		return 2 * (c.ItemsShipped - orderQty)
+	}
+	costs := cmp.Compare(math.Round(a.TotalCost*1e6), math.Round(b.TotalCost*1e6))
+
+	switch objective {
+	case models.ObjectiveFewestPacks:
+		return firstNonZero(cmp.Compare(a.PackCount, b.PackCount), cmp.Compare(items(a), items(b)), costs)
+	case models.ObjectiveLowestCost:
+		return firstNonZero(costs, cmp.Compare(items(a), items(b)), cmp.Compare(a.PackCount, b.PackCount))
+	default:
+		return firstNonZero(cmp.Compare(items(a), items(b)), cmp.Compare(a.PackCount, b.PackCount), costs)
+	}
+}
+
+func firstNonZero(orders ...int) int {
+	for _, order := range orders {
+		if order != 0 {
+			return order
+		}
+	}
+
+	return 0
+}
@@ -2,9 +2,11 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"slices"
+	"time"
 
 	"github.com/cybre/order-packing/internal/models"
 )
@@ -15,114 +17,498 @@ var (
 
 	// ErrOrderQuantity is returned when the order quantity is less than or equal to 0
 	ErrOrderQuantity = fmt.Errorf("order quantity must be greater than 0")
+
+	// ErrUnknownObjective is returned when the order asks for an objective that is not supported
+	ErrUnknownObjective = fmt.Errorf("unknown packing objective")
+
+	// ErrNoOrderLines is returned when a multi-line order has no lines
+	ErrNoOrderLines = fmt.Errorf("order must have at least one line")
+
+	// ErrInsufficientStock is returned when the packs in stock cannot fulfill an order or a stock adjustment
+	ErrInsufficientStock = fmt.Errorf("insufficient pack stock")
+
+	// ErrUnknownPackSize is returned when a stock adjustment or change refers to a pack size that does not exist
+	ErrUnknownPackSize = fmt.Errorf("unknown pack size")
+
+	// ErrPackSizeExists is returned when a pack size is added to pack sizes that already have its size
+	ErrPackSizeExists = fmt.Errorf("pack size already exists")
+
+	// ErrReadOnlyPackSizes is returned when pack sizes or their stock are changed but are only read from elsewhere
+	ErrReadOnlyPackSizes = fmt.Errorf("pack sizes are read-only")
+
+	// ErrUnlimitedStock is returned when a stock adjustment refers to a pack size with unlimited stock
+	ErrUnlimitedStock = fmt.Errorf("pack size has unlimited stock")
+
+	// ErrInvalidPolicy is returned when the order asks for a packing policy with negative limits or an unknown mode
+	ErrInvalidPolicy = fmt.Errorf("invalid packing policy")
+
+	// ErrPolicyNotSatisfied is returned when the order cannot be packed within the limits of its packing policy
+	ErrPolicyNotSatisfied = fmt.Errorf("packing policy cannot be satisfied")
+
+	// ErrAlternatives is returned when the order asks for a negative or too large number of alternative packings
+	ErrAlternatives = fmt.Errorf("alternatives must be between 0 and %d", MaxAlternatives)
+
+	// ErrItemMeasures is returned when the order has a negative item weight or volume
+	ErrItemMeasures = fmt.Errorf("item weight and volume cannot be negative")
+
+	// ErrInvalidHierarchy is returned when a level of the packaging hierarchy has no name or holds no units
+	ErrInvalidHierarchy = fmt.Errorf("packaging levels must have a name and hold at least one unit")
+
+	// ErrInvalidShipmentLimits is returned when the shipment limits are negative
+	ErrInvalidShipmentLimits = fmt.Errorf("shipment limits cannot be negative")
+
+	// ErrShipmentLimitsExceeded is returned when every pack size holds more items than a shipment may hold
+	ErrShipmentLimitsExceeded = fmt.Errorf("no pack size fits within the shipment limits")
+
+	// ErrVersionConflict is returned when pack sizes are updated on the condition of a version they no longer have
+	ErrVersionConflict = fmt.Errorf("pack sizes were changed since the version was read")
+
+	// ErrPackingTooLarge is returned when packing the order would take more amounts than a packing table may cover
+	ErrPackingTooLarge = fmt.Errorf("order needs too large a packing table for the pack sizes")
+
+	// ErrPackLimitsExceeded is returned when no pack size can hold the ordered items within its weight and volume limits
+	ErrPackLimitsExceeded = fmt.Errorf("no pack size holds the items within its weight and volume limits")
 )
 
-// PackSizeProvider describes a type that can provide pack sizes
+// OrderLinesError is returned when one or more lines of a multi-line order cannot be packed
+type OrderLinesError struct {
+	// Lines describes every line that cannot be packed
+	Lines []models.OrderLineError
+}
+
+// Error returns the error message
+func (e *OrderLinesError) Error() string {
+	return fmt.Sprintf("%d order line(s) cannot be packed", len(e.Lines))
+}
+
+// PackSizeProvider describes a type that can provide pack sizes per product.
+// An empty SKU refers to the default pack sizes, which also apply to every product without its own.
+// Updating or swapping the pack sizes of a product without its own gives the product its own pack sizes.
 type PackSizeProvider interface {
-	// GetPackSizes returns the available pack sizes
-	GetPackSizes(ctx context.Context) ([]models.PackSize, error)
-	// Update updates the pack sizes
-	Update(ctx context.Context, packSizes []models.PackSize) error
+	// GetPackSizes returns the available pack sizes for the specified SKU
+	GetPackSizes(ctx context.Context, sku string) ([]models.PackSize, error)
+	// Update updates the pack sizes for the specified SKU
+	Update(ctx context.Context, sku string, packSizes []models.PackSize) error
+	// CompareAndSwap atomically updates the pack sizes for the specified SKU if the pack sizes GetPackSizes
+	// returns for the SKU have the version (see models.PackSizesVersion), ErrVersionConflict is returned otherwise
+	CompareAndSwap(ctx context.Context, sku, version string, packSizes []models.PackSize) error
+	// AdjustStock atomically applies the adjustments to the stock of the pack sizes used for the specified SKU.
+	// No adjustment is applied if any of them fails, ErrInsufficientStock is returned if stock would become negative.
+	AdjustStock(ctx context.Context, sku string, adjustments []models.StockAdjustment) error
 }
 
 // PackingService is a service that can calculate the number of packs required to fulfill an order
 type PackingService struct {
 	packSizeProvider PackSizeProvider
+	packer           Packer
+	policy           models.PackingPolicy
+	hierarchy        models.PackagingHierarchy
+	tables           *dpTableCache
+	rules            PackSizeRules
+	history          PackSizeHistory
+	schedule         PackSizeSchedule
+	now              func() time.Time
 }
 
-// NewPackingService returns a new PackingService with the specified pack size provider
-func NewPackingService(packSizeProvider PackSizeProvider) *PackingService {
-	return &PackingService{packSizeProvider}
+// Option configures a PackingService
+type Option func(*PackingService)
+
+// WithPacker sets the packing strategy, DPPacker is used by default
+func WithPacker(packer Packer) Option {
+	return func(s *PackingService) {
+		s.packer = packer
+	}
 }
 
-// UpdatePackSizes updates the available pack sizes
-func (s PackingService) UpdatePackSizes(ctx context.Context, packSizes []models.PackSize) error {
-	return s.packSizeProvider.Update(ctx, packSizes)
+// WithPackingPolicy sets the packing policy of orders that do not specify their own
+func WithPackingPolicy(policy models.PackingPolicy) Option {
+	return func(s *PackingService) {
+		s.policy = policy
+	}
 }
 
-// GetPackSizes returns the available pack sizes
-func (s PackingService) GetPackSizes(ctx context.Context) ([]models.PackSize, error) {
-	return s.packSizeProvider.GetPackSizes(ctx)
+// WithPackagingHierarchy sets the packaging hierarchy packs are packed into for orders that do not specify their own
+func WithPackagingHierarchy(hierarchy models.PackagingHierarchy) Option {
+	return func(s *PackingService) {
+		s.hierarchy = hierarchy
+	}
 }
 
-// CalculatePacks returns the number of packs required to fulfill the specified order
-func (s PackingService) CalculatePacks(ctx context.Context, order models.Order) (map[int]int, error) {
-	if order.ItemQty <= 0 {
-		return nil, ErrOrderQuantity
+// WithPackSizeRules sets the rules pack sizes have to follow to be saved, every pack size has to hold
+// at least one item and at most DefaultMaxPackSizeItems by default
+func WithPackSizeRules(rules PackSizeRules) Option {
+	return func(s *PackingService) {
+		s.rules = rules
+	}
+}
+
+// WithTableCache sets the number of bytes of packing tables kept for later orders, DefaultTableCacheSize
+// by default. The least recently used tables are dropped first. The cache is disabled when the number is not positive.
+func WithTableCache(maxSize int) Option {
+	return func(s *PackingService) {
+		s.tables = newDPTableCache(maxSize)
+	}
+}
+
+// NewPackingService returns a new PackingService with the specified pack size provider and options
+func NewPackingService(packSizeProvider PackSizeProvider, opts ...Option) *PackingService {
+	s := &PackingService{
+		packSizeProvider: packSizeProvider,
+		packer:           DPPacker{},
+		tables:           newDPTableCache(DefaultTableCacheSize),
+		rules:            PackSizeRules{MaxItems: DefaultMaxPackSizeItems},
+		now:              time.Now,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// The dynamic programming packer reuses the tables of the service
+	if packer, ok := s.packer.(DPPacker); ok && packer.tables == nil {
+		packer.tables = s.tables
+		s.packer = packer
+	}
+
+	return s
+}
+
+// UpdatePackSizes updates the available pack sizes for the specified SKU (the default pack sizes when empty)
+// and drops the cached packing tables. The pack sizes are saved sorted from the smallest to the largest without
+// repeated sizes, a *PackSizesError reporting every invalid field is returned if they break the pack size rules.
+// A version of the pack sizes is kept in the history if there is one. Scheduled pack sizes that took effect
+// are applied first, so they do not replace the pack sizes later.
+func (s PackingService) UpdatePackSizes(ctx context.Context, sku string, packSizes []models.PackSize) error {
+	_, err := s.updatePackSizes(ctx, sku, "", packSizes, 0)
 
-	// Get the available pack sizes
-	packSizes, err := s.packSizeProvider.GetPackSizes(ctx)
+	return err
+}
+
+// UpdatePackSizesIfMatch updates the pack sizes for the specified SKU like UpdatePackSizes, but only if the
+// pack sizes used for the SKU still have the version (see models.PackSizesVersion). ErrVersionConflict is
+// returned when they were changed since, an empty version matches any pack sizes. It returns the version
+// of the saved pack sizes.
+func (s PackingService) UpdatePackSizesIfMatch(ctx context.Context, sku, version string, packSizes []models.PackSize) (string, error) {
+	saved, err := s.updatePackSizes(ctx, sku, version, packSizes, 0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pack sizes: %w", err)
+		return "", err
 	}
 
-	if len(packSizes) == 0 {
-		return nil, ErrNoPackSizesAvailable
+	return models.PackSizesVersion(saved.PackSizes), nil
+}
+
+// updatePackSizes applies the scheduled pack sizes that took effect and saves the pack sizes
+func (s PackingService) updatePackSizes(ctx context.Context, sku, version string, packSizes []models.PackSize, restoredFrom int) (models.PackSizeSetVersion, error) {
+	if err := s.applySchedules(ctx, sku); err != nil {
+		return models.PackSizeSetVersion{}, err
 	}
 
-	return minPacks(packSizes, order.ItemQty), nil
+	return s.savePackSizes(ctx, sku, version, packSizes, restoredFrom)
 }
 
-func minPacks(packSizes []models.PackSize, orderQty int) map[int]int {
-	// Ensure the pack sizes are sorted in ascending order
-	slices.SortFunc(packSizes, func(a, b models.PackSize) int {
-		return a.MaxItems - b.MaxItems
+// savePackSizes updates the pack sizes if they have the version (any version when empty) and returns the
+// version of the history they are saved as, restoredFrom is the version they are rolled back to (0 when they are not)
+func (s PackingService) savePackSizes(ctx context.Context, sku, version string, packSizes []models.PackSize, restoredFrom int) (models.PackSizeSetVersion, error) {
+	packSizes, err := s.rules.normalize(packSizes)
+	if err != nil {
+		return models.PackSizeSetVersion{}, err
+	}
+
+	previous, packSizes, err := s.swapPackSizes(ctx, sku, func(current []models.PackSize) ([]models.PackSize, error) {
+		if version != "" && models.PackSizesVersion(current) != version {
+			return nil, ErrVersionConflict
+		}
+
+		return packSizes, nil
 	})
+	if err != nil {
+		return models.PackSizeSetVersion{}, err
+	}
 
-	// Calculate the maximum amount to consider, including possible overshoots (orderQty + smallest pack size)
-	maxAmount := orderQty + packSizes[0].MaxItems
+	s.tables.clear()
 
-	// Initialize the dynamic programming table (for memoization)
-	dp := make([]int, maxAmount+1)
-	for i := range dp {
-		dp[i] = math.MaxInt32 // Initialize with a large value
+	if s.history == nil {
+		return models.PackSizeSetVersion{SKU: sku, PackSizes: packSizes}, nil
 	}
 
-	// Keep track of the pack sizes used for each amount (for backtracking)
-	packSizesUsed := make([]int, maxAmount+1)
+	return s.recordVersion(ctx, sku, previous, packSizes, restoredFrom)
+}
 
-	// Base case: 0 packs are needed for 0 items
-	dp[0] = 0
+// GetPackSizes returns the available pack sizes for the specified SKU (the default pack sizes when empty)
+// that are in effect now
+func (s PackingService) GetPackSizes(ctx context.Context, sku string) ([]models.PackSize, error) {
+	return s.GetPackSizesAt(ctx, sku, s.now())
+}
 
-	// Calculate the minimum number of packs needed for each amount from packSize.MaxItems to maxAmount
-	for _, packSize := range packSizes {
-		for i := packSize.MaxItems; i <= maxAmount; i++ {
-			if dp[i-packSize.MaxItems]+1 < dp[i] {
-				dp[i] = dp[i-packSize.MaxItems] + 1
-				packSizesUsed[i] = packSize.MaxItems
+// AdjustStock applies the adjustments to the stock of the pack sizes used for the specified SKU
+func (s PackingService) AdjustStock(ctx context.Context, sku string, adjustments []models.StockAdjustment) error {
+	if err := s.applySchedules(ctx, sku); err != nil {
+		return err
+	}
+
+	return s.packSizeProvider.AdjustStock(ctx, sku, adjustments)
+}
+
+// CalculatePacks returns the packs required to fulfill the specified order.
+// Packs with limited stock are taken out of the stock if the order asks for a reservation,
+// the result explains why the packs were chosen if the order asks for an explanation
+// and ranks the best packings if the order asks for alternatives.
+// The packs are packed into the levels of the packaging hierarchy if there is one.
+// Pack sizes that would exceed their weight or volume limits when full of the ordered items are not used.
+// ErrPolicyNotSatisfied is returned if the packing policy rejects every packing the stock allows.
+// Orders packed at a later time are quoted with the pack sizes scheduled to be in effect then.
+func (s PackingService) CalculatePacks(ctx context.Context, order models.Order) (models.PackingResult, error) {
+	return s.calculatePacks(ctx, order, 0)
+}
+
+// calculatePacks packs the order with the pack sizes holding at most maxPackItems items (any pack size when 0)
+func (s PackingService) calculatePacks(ctx context.Context, order models.Order, maxPackItems int) (models.PackingResult, error) {
+	if order.ItemQty <= 0 {
+		return models.PackingResult{}, ErrOrderQuantity
+	}
+
+	if !order.Objective.IsValid() {
+		return models.PackingResult{}, ErrUnknownObjective
+	}
+
+	if order.Alternatives < 0 || order.Alternatives > MaxAlternatives {
+		return models.PackingResult{}, ErrAlternatives
+	}
+
+	if order.ItemWeight < 0 || order.ItemVolume < 0 {
+		return models.PackingResult{}, ErrItemMeasures
+	}
+
+	at := s.now()
+	if order.AsOf != nil && order.AsOf.After(at) {
+		if order.Reserve {
+			return models.PackingResult{}, ErrReserveAsOf
+		}
+
+		at = *order.AsOf
+	}
+
+	hierarchy := s.hierarchy
+	if order.Hierarchy != nil {
+		hierarchy = order.Hierarchy
+	}
+
+	if !hierarchy.IsValid() {
+		return models.PackingResult{}, ErrInvalidHierarchy
+	}
+
+	policy := s.policy
+	if order.Policy != nil {
+		policy = *order.Policy
+	}
+
+	if !policy.IsValid() {
+		return models.PackingResult{}, ErrInvalidPolicy
+	}
+
+	objective := order.Objective
+	if objective == "" {
+		objective = models.ObjectiveFewestItems
+	}
+
+	// Get the available pack sizes for the ordered product
+	packSizes, err := s.GetPackSizesAt(ctx, order.SKU, at)
+	if err != nil {
+		return models.PackingResult{}, fmt.Errorf("failed to get pack sizes: %w", err)
+	}
+
+	if len(packSizes) == 0 {
+		return models.PackingResult{}, ErrNoPackSizesAvailable
+	}
+
+	// Packs that would exceed their weight or volume limits when full cannot be used, smaller packs are used instead
+	version := models.PackSizesVersion(packSizes)
+	packSizes = slices.DeleteFunc(slices.Clone(packSizes), func(packSize models.PackSize) bool {
+		return !packSize.Holds(order.ItemWeight, order.ItemVolume)
+	})
+	if len(packSizes) == 0 {
+		return models.PackingResult{}, ErrPackLimitsExceeded
+	}
+
+	if maxPackItems > 0 {
+		packSizes = slices.DeleteFunc(packSizes, func(packSize models.PackSize) bool {
+			return packSize.MaxItems > maxPackItems
+		})
+		if len(packSizes) == 0 {
+			return models.PackingResult{}, ErrShipmentLimitsExceeded
+		}
+	}
+
+	pack := s.packer.Pack
+	var explanation *models.PackingExplanation
+	if order.Explain {
+		explainingPacker, ok := s.packer.(ExplainingPacker)
+		if !ok {
+			return models.PackingResult{}, ErrExplainUnsupported
+		}
+
+		explanation = &models.PackingExplanation{}
+		pack = func(packSizes []models.PackSize, target PackingTarget, objective models.Objective) (map[int]int, error) {
+			return explainingPacker.PackExplained(packSizes, target, objective, explanation)
+		}
+	}
+
+	target := policyTarget(policy, order.ItemQty)
+	packs, err := pack(packSizes, target, objective)
+	policyViolated := false
+	if errors.Is(err, ErrInsufficientStock) && target.MaxQty != math.MaxInt {
+		// Tell orders the policy rules out apart from orders the stock cannot fulfill at all
+		relaxed := target
+		relaxed.MaxQty = math.MaxInt
+		if packs, err = pack(packSizes, relaxed, objective); err == nil {
+			if policy.Mode != models.PolicyModeBestEffort {
+				return models.PackingResult{}, fmt.Errorf("%w: no packing ships between %d and %d items", ErrPolicyNotSatisfied, target.MinQty, target.MaxQty)
 			}
+
+			target, policyViolated = relaxed, true
 		}
 	}
+	if err != nil {
+		return models.PackingResult{}, err
+	}
 
-	// Check for an exact match first
-	if dp[orderQty] != math.MaxInt32 {
-		bestAmount := orderQty
+	var alternatives []models.PackingCandidate
+	if order.Alternatives > 0 {
+		if alternatives, err = alternativePacks(s.packer, packSizes, target, objective, order.Alternatives); err != nil {
+			return models.PackingResult{}, err
+		}
+	}
 
-		// Backtrack to find the pack size combination for the exact amount
-		packSizeCombination := make(map[int]int)
-		for i := bestAmount; i > 0; i -= packSizesUsed[i] {
-			packSizeCombination[packSizesUsed[i]]++
+	if order.Reserve {
+		if err := s.reserveStock(ctx, order.SKU, packSizes, packs); err != nil {
+			return models.PackingResult{}, err
 		}
+	}
 
-		return packSizeCombination
+	result := newPackingResult(order.ItemQty, packSizes, packs)
+	result.PackSizesVersion = version
+	result.Explanation = explanation
+	result.Alternatives = alternatives
+	result.PolicyViolated = policyViolated
+	if len(hierarchy) > 0 {
+		result.Packaging = packagingPlan(hierarchy, result.Packs)
 	}
 
-	// If no exact match, find the combination that minimizes the number of items sent out
-	minItemsSent := math.MaxInt32
-	bestAmount := 0
-	for i := orderQty; i <= maxAmount; i++ {
-		if dp[i] < math.MaxInt32 && i < minItemsSent {
-			minItemsSent = i
-			bestAmount = i
+	return result, nil
+}
+
+// newPackingResult builds the packing result of an order from the pack sizes it was packed with
+func newPackingResult(requestedQty int, packSizes []models.PackSize, packs map[int]int) models.PackingResult {
+	// The cheapest pack of each size is the one that is used
+	costs := make(map[int]float64, len(packSizes))
+	for _, packSize := range packSizes {
+		if cost, ok := costs[packSize.MaxItems]; !ok || packSize.Cost < cost {
+			costs[packSize.MaxItems] = packSize.Cost
+		}
+	}
+
+	result := models.PackingResult{
+		Packs:            packLines(packs),
+		RequestedQty:     requestedQty,
+		PackSizesVersion: models.PackSizesVersion(packSizes),
+	}
+	for packSize, packQty := range packs {
+		result.ItemsShipped += packSize * packQty
+		result.PackCount += packQty
+		result.TotalCost += costs[packSize] * float64(packQty)
+	}
+	result.Overshoot = max(result.ItemsShipped-requestedQty, 0)
+	result.Underfill = max(requestedQty-result.ItemsShipped, 0)
+
+	return result
+}
+
+// packLines returns the pack size combination as pack lines ordered from the largest pack size to the smallest
+func packLines(packs map[int]int) []models.PackLine {
+	lines := make([]models.PackLine, 0, len(packs))
+	for packSize, packQty := range packs {
+		lines = append(lines, models.PackLine{MaxItems: packSize, Quantity: packQty})
+	}
+
+	slices.SortFunc(lines, func(a, b models.PackLine) int {
+		return b.MaxItems - a.MaxItems
+	})
+
+	return lines
+}
+
+// reserveStock takes the packs with limited stock out of the stock of the specified SKU
+func (s PackingService) reserveStock(ctx context.Context, sku string, packSizes []models.PackSize, packs map[int]int) error {
+	adjustments := []models.StockAdjustment{}
+	for _, packSize := range packSizes {
+		if packQty := packs[packSize.MaxItems]; packQty > 0 && packSize.Stock != nil {
+			adjustments = append(adjustments, models.StockAdjustment{MaxItems: packSize.MaxItems, Delta: -packQty})
 		}
 	}
 
-	// Backtrack to find the pack size combination for the best amount
-	packSizeCombination := make(map[int]int)
-	for i := bestAmount; i > 0; i -= packSizesUsed[i] {
-		packSizeCombination[packSizesUsed[i]]++
+	if len(adjustments) == 0 {
+		return nil
+	}
+
+	if err := s.packSizeProvider.AdjustStock(ctx, sku, adjustments); err != nil {
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+
+	return nil
+}
+
+// CalculateOrderPacks packs every line of the specified multi-line order.
+// The order is packed as a whole: if any line is invalid an *OrderLinesError reporting every invalid line is returned.
+func (s PackingService) CalculateOrderPacks(ctx context.Context, order models.MultiLineOrder) (models.MultiLinePacking, error) {
+	if len(order.Lines) == 0 {
+		return models.MultiLinePacking{}, ErrNoOrderLines
+	}
+
+	if !order.Objective.IsValid() {
+		return models.MultiLinePacking{}, ErrUnknownObjective
+	}
+
+	if order.Policy != nil && !order.Policy.IsValid() {
+		return models.MultiLinePacking{}, ErrInvalidPolicy
+	}
+
+	if !order.Hierarchy.IsValid() {
+		return models.MultiLinePacking{}, ErrInvalidHierarchy
+	}
+
+	result := models.MultiLinePacking{Lines: make([]models.LinePacking, 0, len(order.Lines))}
+	lineErrs := []models.OrderLineError{}
+	for i, line := range order.Lines {
+		packing, err := s.CalculatePacks(ctx, models.Order{
+			ItemQty:    line.ItemQty,
+			SKU:        line.SKU,
+			Objective:  order.Objective,
+			ItemWeight: line.ItemWeight,
+			ItemVolume: line.ItemVolume,
+			Policy:     order.Policy,
+			Hierarchy:  order.Hierarchy,
+		})
+		if errors.Is(err, ErrOrderQuantity) || errors.Is(err, ErrNoPackSizesAvailable) ||
+			errors.Is(err, ErrInsufficientStock) || errors.Is(err, ErrPolicyNotSatisfied) ||
+			errors.Is(err, ErrItemMeasures) || errors.Is(err, ErrPackLimitsExceeded) ||
+			errors.Is(err, ErrPackingTooLarge) {
+			lineErrs = append(lineErrs, models.OrderLineError{Line: i, SKU: line.SKU, Error: err.Error()})
+			continue
+		}
+		if err != nil {
+			return models.MultiLinePacking{}, fmt.Errorf("failed to pack line %d: %w", i, err)
+		}
+
+		result.Lines = append(result.Lines, models.LinePacking{SKU: line.SKU, PackingResult: packing})
+		result.PackCount += packing.PackCount
+		result.ItemsShipped += packing.ItemsShipped
+		result.Overshoot += packing.Overshoot
+		result.Underfill += packing.Underfill
+		result.TotalCost += packing.TotalCost
+	}
+
+	if len(lineErrs) > 0 {
+		return models.MultiLinePacking{}, &OrderLinesError{Lines: lineErrs}
 	}
 
-	return packSizeCombination
+	return result, nil
 }
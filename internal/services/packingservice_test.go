@@ -3,8 +3,12 @@ package services_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/cybre/order-packing/internal/models"
 	"github.com/cybre/order-packing/internal/services"
@@ -129,6 +133,50 @@ func TestCalculatePacks(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name:      "Fewest packs objective",
+			packSizes: defaultPackSizes,
+			order:     models.Order{ItemQty: 501, Objective: models.ObjectiveFewestPacks},
+			expectedPacks: map[int]int{
+				1000: 1,
+			},
+			expectedErr: nil,
+		},
+		{
+			name:      "Lowest cost objective",
+			packSizes: []models.PackSize{{MaxItems: 250, Cost: 7}, {MaxItems: 500, Cost: 6}, {MaxItems: 1000, Cost: 20}},
+			order:     models.Order{ItemQty: 501, Objective: models.ObjectiveLowestCost},
+			expectedPacks: map[int]int{
+				500: 2,
+			},
+			expectedErr: nil,
+		},
+		{
+			name:      "Lowest cost objective prefers fewer items on equal cost",
+			packSizes: []models.PackSize{{MaxItems: 500, Cost: 10}, {MaxItems: 300, Cost: 10}},
+			order:     models.Order{ItemQty: 251, Objective: models.ObjectiveLowestCost},
+			expectedPacks: map[int]int{
+				300: 1,
+			},
+			expectedErr: nil,
+		},
+		{
+			name:      "Fewest items objective breaks ties on cost",
+			packSizes: []models.PackSize{{MaxItems: 2, Cost: 1}, {MaxItems: 3, Cost: 5}, {MaxItems: 4, Cost: 1}},
+			order:     models.Order{ItemQty: 6, Objective: models.ObjectiveFewestItems},
+			expectedPacks: map[int]int{
+				2: 1,
+				4: 1,
+			},
+			expectedErr: nil,
+		},
+		{
+			name:          "Unknown objective",
+			packSizes:     defaultPackSizes,
+			order:         models.Order{ItemQty: 10, Objective: "cheapest"},
+			expectedPacks: nil,
+			expectedErr:   services.ErrUnknownObjective,
+		},
 		{
 			name:          "Order quantity is 0",
 			packSizes:     defaultPackSizes,
@@ -155,7 +203,8 @@ func TestCalculatePacks(t *testing.T) {
 			ctx, cancel := context.WithDeadline(context.Background(), deadline)
 			defer cancel()
 
-			packs, err := service.CalculatePacks(ctx, tc.order)
+			result, err := service.CalculatePacks(ctx, tc.order)
+			packs := result.PackMap()
 
 			if !reflect.DeepEqual(packs, tc.expectedPacks) {
 				t.Errorf("expected packs to be %v, but got %v", tc.expectedPacks, packs)
@@ -168,67 +217,1850 @@ func TestCalculatePacks(t *testing.T) {
 	}
 }
 
-func TestUpdatePackSizes_ProviderError_ReturnError(t *testing.T) {
+func TestCalculatePacks_PackingResult(t *testing.T) {
 	t.Parallel()
 
 	// Arrange
-	expectedErr := errors.New("provider error")
-	service := services.NewPackingService(&testdata.MockPackSizeProvider{Error: expectedErr})
-	packSizes := []models.PackSize{{MaxItems: 250}}
+	packSizes := []models.PackSize{{MaxItems: 250, Cost: 2}, {MaxItems: 500, Cost: 3}, {MaxItems: 5000, Cost: 20}}
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: packSizes})
+	order := models.Order{ItemQty: 10501}
 
 	// Act
-	err := service.UpdatePackSizes(context.Background(), packSizes)
+	result, err := service.CalculatePacks(context.Background(), order)
 
 	// Assert
-	if err == nil {
-		t.Error("expected an error, but got nil")
+	if err != nil {
+		t.Fatalf("failed to calculate packs: %v", err)
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to be %v, but got %v", expectedErr, err)
+
+	expectedResult := models.PackingResult{
+		Packs: []models.PackLine{
+			{MaxItems: 5000, Quantity: 2},
+			{MaxItems: 500, Quantity: 1},
+			{MaxItems: 250, Quantity: 1},
+		},
+		RequestedQty:     10501,
+		ItemsShipped:     10750,
+		Overshoot:        249,
+		PackCount:        4,
+		TotalCost:        45,
+		PackSizesVersion: models.PackSizesVersion(packSizes),
+	}
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Errorf("expected result to be %+v, but got %+v", expectedResult, result)
 	}
 }
 
-func TestGetPackSizes_ProviderError_ReturnError(t *testing.T) {
+func TestCalculatePacks_Explain(t *testing.T) {
 	t.Parallel()
 
 	// Arrange
-	expectedErr := errors.New("provider error")
-	service := services.NewPackingService(&testdata.MockPackSizeProvider{Error: expectedErr})
+	packSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}, {MaxItems: 2000}, {MaxItems: 5000}}
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: packSizes})
+	order := models.Order{ItemQty: 501, Explain: true}
 
 	// Act
-	_, err := service.GetPackSizes(context.Background())
+	result, err := service.CalculatePacks(context.Background(), order)
 
 	// Assert
-	if err == nil {
-		t.Error("expected an error, but got nil")
+	if err != nil {
+		t.Fatalf("failed to calculate packs: %v", err)
 	}
-	if !errors.Is(err, expectedErr) {
-		t.Errorf("expected error to be %v, but got %v", expectedErr, err)
+
+	explanation := result.Explanation
+	if explanation == nil {
+		t.Fatalf("expected an explanation, but got none")
+	}
+
+	if explanation.ExactMatch {
+		t.Errorf("expected no exact match for 501 items")
+	}
+
+	if explanation.DecidingRule != models.ObjectiveFewestPacks {
+		t.Errorf("expected the deciding rule to be %q, but got %q", models.ObjectiveFewestPacks, explanation.DecidingRule)
+	}
+
+	if len(explanation.RunnersUp) == 0 {
+		t.Fatalf("expected runners-up, but got none")
+	}
+
+	expectedRunnerUp := models.PackingCandidate{
+		ItemsShipped: 750,
+		PackCount:    3,
+		Packs:        []models.PackLine{{MaxItems: 250, Quantity: 3}},
+	}
+	if !reflect.DeepEqual(explanation.RunnersUp[0], expectedRunnerUp) {
+		t.Errorf("expected the runner-up to be %+v, but got %+v", expectedRunnerUp, explanation.RunnersUp[0])
 	}
 }
 
-func TestGetPackSizes(t *testing.T) {
+func TestCalculatePacks_WithoutExplain_NoExplanation(t *testing.T) {
 	t.Parallel()
 
 	// Arrange
-	expectedPackSizes := []models.PackSize{
-		{MaxItems: 250},
-		{MaxItems: 500},
-		{MaxItems: 1000},
-		{MaxItems: 2000},
-		{MaxItems: 5000},
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}}})
+
+	// Act
+	result, err := service.CalculatePacks(context.Background(), models.Order{ItemQty: 251})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to calculate packs: %v", err)
 	}
-	service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: expectedPackSizes})
+
+	if result.Explanation != nil {
+		t.Errorf("expected no explanation, but got %+v", result.Explanation)
+	}
+}
+
+func TestCalculatePacks_ExplainUnsupported_ReturnError(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	service := services.NewPackingService(
+		&testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}}},
+		services.WithPacker(services.GreedyPacker{}),
+	)
+
+	// Act
+	_, err := service.CalculatePacks(context.Background(), models.Order{ItemQty: 251, Explain: true})
+
+	// Assert
+	if !errors.Is(err, services.ErrExplainUnsupported) {
+		t.Errorf("expected error to be %v, but got %v", services.ErrExplainUnsupported, err)
+	}
+}
+
+func TestCalculatePacks_WithPacker(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	service := services.NewPackingService(
+		&testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 3}, {MaxItems: 5}}},
+		services.WithPacker(services.GreedyPacker{}),
+	)
 
 	// Act
-	packSizes, err := service.GetPackSizes(context.Background())
+	result, err := service.CalculatePacks(context.Background(), models.Order{ItemQty: 6})
 
 	// Assert
 	if err != nil {
-		t.Fatalf("failed to get pack sizes: %v", err)
+		t.Fatalf("failed to calculate packs: %v", err)
 	}
 
-	if !reflect.DeepEqual(packSizes, expectedPackSizes) {
-		t.Errorf("expected pack sizes to be %v, but got %v", expectedPackSizes, packSizes)
+	// The greedy packer takes the largest pack first, where the default packer would ship two packs of 3
+	expectedPacks := map[int]int{5: 1, 3: 1}
+	if packs := result.PackMap(); !reflect.DeepEqual(packs, expectedPacks) {
+		t.Errorf("expected packs to be %v, but got %v", expectedPacks, packs)
+	}
+}
+
+func TestCalculatePacks_Alternatives(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	packSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}, {MaxItems: 2000}, {MaxItems: 5000}}
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: packSizes})
+	order := models.Order{ItemQty: 501, Alternatives: 4}
+
+	// Act
+	result, err := service.CalculatePacks(context.Background(), order)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to calculate packs: %v", err)
+	}
+
+	expectedAlternatives := []models.PackingCandidate{
+		{ItemsShipped: 750, PackCount: 2, Packs: []models.PackLine{{MaxItems: 500, Quantity: 1}, {MaxItems: 250, Quantity: 1}}},
+		{ItemsShipped: 750, PackCount: 3, Packs: []models.PackLine{{MaxItems: 250, Quantity: 3}}},
+		{ItemsShipped: 1000, PackCount: 1, Packs: []models.PackLine{{MaxItems: 1000, Quantity: 1}}},
+		{ItemsShipped: 1000, PackCount: 2, Packs: []models.PackLine{{MaxItems: 500, Quantity: 2}}},
+	}
+	if !reflect.DeepEqual(result.Alternatives, expectedAlternatives) {
+		t.Errorf("expected alternatives to be %+v, but got %+v", expectedAlternatives, result.Alternatives)
+	}
+
+	if !reflect.DeepEqual(result.Packs, result.Alternatives[0].Packs) {
+		t.Errorf("expected the first alternative to be the chosen packing %+v, but got %+v", result.Packs, result.Alternatives[0].Packs)
+	}
+}
+
+func TestCalculatePacks_Alternatives_FewerThanRequested(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}}})
+	order := models.Order{ItemQty: 251, Alternatives: 3}
+
+	// Act
+	result, err := service.CalculatePacks(context.Background(), order)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to calculate packs: %v", err)
+	}
+
+	// Every other packing has a pack that can be dropped
+	expectedAlternatives := []models.PackingCandidate{
+		{ItemsShipped: 500, PackCount: 2, Packs: []models.PackLine{{MaxItems: 250, Quantity: 2}}},
+	}
+	if !reflect.DeepEqual(result.Alternatives, expectedAlternatives) {
+		t.Errorf("expected alternatives to be %+v, but got %+v", expectedAlternatives, result.Alternatives)
+	}
+}
+
+func TestCalculatePacks_InvalidAlternatives_ReturnError(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}}})
+
+	for _, alternatives := range []int{-1, services.MaxAlternatives + 1} {
+		// Act
+		_, err := service.CalculatePacks(context.Background(), models.Order{ItemQty: 251, Alternatives: alternatives})
+
+		// Assert
+		if !errors.Is(err, services.ErrAlternatives) {
+			t.Errorf("expected error to be %v for %d alternatives, but got %v", services.ErrAlternatives, alternatives, err)
+		}
+	}
+}
+
+func TestCalculatePacks_Policy(t *testing.T) {
+	t.Parallel()
+
+	intPtr := func(i int) *int { return &i }
+	floatPtr := func(f float64) *float64 { return &f }
+
+	packSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}, {MaxItems: 2000}, {MaxItems: 5000}}
+	testCases := []struct {
+		name           string
+		itemQty        int
+		globalPolicy   models.PackingPolicy
+		policy         *models.PackingPolicy
+		expectedPacks  map[int]int
+		expectedResult func(models.PackingResult) bool
+		expectedErr    error
+	}{
+		{
+			name:          "Overshoot within the limit",
+			itemQty:       251,
+			policy:        &models.PackingPolicy{MaxOvershoot: intPtr(249)},
+			expectedPacks: map[int]int{500: 1},
+		},
+		{
+			name:        "Overshoot above the limit is rejected",
+			itemQty:     251,
+			policy:      &models.PackingPolicy{MaxOvershoot: intPtr(100)},
+			expectedErr: services.ErrPolicyNotSatisfied,
+		},
+		{
+			name:        "Overshoot above the percentage limit is rejected",
+			itemQty:     251,
+			policy:      &models.PackingPolicy{MaxOvershootPercent: floatPtr(50)},
+			expectedErr: services.ErrPolicyNotSatisfied,
+		},
+		{
+			name:          "Best effort ships the packing without the limit",
+			itemQty:       251,
+			policy:        &models.PackingPolicy{MaxOvershoot: intPtr(100), Mode: models.PolicyModeBestEffort},
+			expectedPacks: map[int]int{500: 1},
+			expectedResult: func(result models.PackingResult) bool {
+				return result.PolicyViolated
+			},
+		},
+		{
+			name:          "Underfill within the tolerance",
+			itemQty:       251,
+			policy:        &models.PackingPolicy{MaxUnderfill: intPtr(1)},
+			expectedPacks: map[int]int{250: 1},
+			expectedResult: func(result models.PackingResult) bool {
+				return result.Underfill == 1 && result.Overshoot == 0
+			},
+		},
+		{
+			name:          "Underfill within the percentage tolerance",
+			itemQty:       251,
+			policy:        &models.PackingPolicy{MaxUnderfillPercent: floatPtr(1)},
+			expectedPacks: map[int]int{250: 1},
+		},
+		{
+			name:          "Exact match preferred over underfill",
+			itemQty:       750,
+			policy:        &models.PackingPolicy{MaxUnderfill: intPtr(500)},
+			expectedPacks: map[int]int{500: 1, 250: 1},
+		},
+		{
+			name:          "Underfill outside the tolerance",
+			itemQty:       260,
+			policy:        &models.PackingPolicy{MaxUnderfill: intPtr(5)},
+			expectedPacks: map[int]int{500: 1},
+		},
+		{
+			name:         "Global policy",
+			itemQty:      251,
+			globalPolicy: models.PackingPolicy{MaxOvershoot: intPtr(0)},
+			expectedErr:  services.ErrPolicyNotSatisfied,
+		},
+		{
+			name:          "Order policy replaces the global policy",
+			itemQty:       251,
+			globalPolicy:  models.PackingPolicy{MaxOvershoot: intPtr(0)},
+			policy:        &models.PackingPolicy{},
+			expectedPacks: map[int]int{500: 1},
+		},
+		{
+			name:        "Invalid policy",
+			itemQty:     251,
+			policy:      &models.PackingPolicy{MaxUnderfill: intPtr(-1)},
+			expectedErr: services.ErrInvalidPolicy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			service := services.NewPackingService(
+				&testdata.MockPackSizeProvider{PackSizes: packSizes},
+				services.WithPackingPolicy(tc.globalPolicy),
+			)
+			order := models.Order{ItemQty: tc.itemQty, Policy: tc.policy}
+
+			// Act
+			result, err := service.CalculatePacks(context.Background(), order)
+
+			// Assert
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error to be %v, but got %v", tc.expectedErr, err)
+			}
+
+			if tc.expectedErr != nil {
+				return
+			}
+
+			if packs := result.PackMap(); !reflect.DeepEqual(packs, tc.expectedPacks) {
+				t.Errorf("expected packs to be %v, but got %v", tc.expectedPacks, packs)
+			}
+
+			if tc.expectedResult != nil && !tc.expectedResult(result) {
+				t.Errorf("unexpected result %+v", result)
+			}
+		})
+	}
+}
+
+func TestCalculatePacks_ProductPackSizes(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{
+		PackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}},
+		ProductPackSizes: map[string][]models.PackSize{
+			"SKU-1": {{MaxItems: 6}, {MaxItems: 12}},
+		},
+	})
+	order := models.Order{ItemQty: 20, SKU: "SKU-1"}
+
+	// Act
+	result, err := service.CalculatePacks(context.Background(), order)
+	packs := result.PackMap()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to calculate packs: %v", err)
+	}
+
+	expectedPacks := map[int]int{12: 2}
+	if !reflect.DeepEqual(packs, expectedPacks) {
+		t.Errorf("expected packs to be %v, but got %v", expectedPacks, packs)
+	}
+}
+
+func TestCalculatePacks_LimitedStock(t *testing.T) {
+	t.Parallel()
+
+	one, none := 1, 0
+	testCases := []struct {
+		name          string
+		packSizes     []models.PackSize
+		order         models.Order
+		expectedPacks map[int]int
+		expectedErr   error
+	}{
+		{
+			name:          "Falls back to smaller packs",
+			packSizes:     []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 5000, Stock: &one}},
+			order:         models.Order{ItemQty: 10500},
+			expectedPacks: map[int]int{5000: 1, 500: 11},
+		},
+		{
+			name:          "Out of stock pack is skipped",
+			packSizes:     []models.PackSize{{MaxItems: 250}, {MaxItems: 500, Stock: &none}},
+			order:         models.Order{ItemQty: 501},
+			expectedPacks: map[int]int{250: 3},
+		},
+		{
+			name:          "Overshoots when the exact packs are out of stock",
+			packSizes:     []models.PackSize{{MaxItems: 250, Stock: &one}, {MaxItems: 1000}},
+			order:         models.Order{ItemQty: 400},
+			expectedPacks: map[int]int{1000: 1},
+		},
+		{
+			name:        "Not enough stock",
+			packSizes:   []models.PackSize{{MaxItems: 250, Stock: &one}, {MaxItems: 500, Stock: &one}},
+			order:       models.Order{ItemQty: 751},
+			expectedErr: services.ErrInsufficientStock,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: tc.packSizes})
+
+			result, err := service.CalculatePacks(context.Background(), tc.order)
+			packs := result.PackMap()
+
+			if !reflect.DeepEqual(packs, tc.expectedPacks) {
+				t.Errorf("expected packs to be %v, but got %v", tc.expectedPacks, packs)
+			}
+
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error to be %v, but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCalculatePacks_WeightAndVolumeLimits(t *testing.T) {
+	t.Parallel()
+
+	packSizes := []models.PackSize{
+		{MaxItems: 250, MaxWeight: 500, MaxVolume: 100},
+		{MaxItems: 500, MaxWeight: 500},
+		{MaxItems: 5000, MaxWeight: 1000},
+	}
+	testCases := []struct {
+		name          string
+		order         models.Order
+		expectedPacks map[int]int
+		expectedErr   error
+	}{
+		{
+			name:          "Light items use every pack",
+			order:         models.Order{ItemQty: 5250, ItemWeight: 0.1},
+			expectedPacks: map[int]int{5000: 1, 250: 1},
+		},
+		{
+			name:          "Heavy items fall back to smaller packs",
+			order:         models.Order{ItemQty: 5250, ItemWeight: 1},
+			expectedPacks: map[int]int{500: 10, 250: 1},
+		},
+		{
+			name:          "Pack filled exactly to its limit",
+			order:         models.Order{ItemQty: 5250, ItemWeight: 0.2},
+			expectedPacks: map[int]int{5000: 1, 250: 1},
+		},
+		{
+			name:          "Bulky items fall back to packs without a volume limit",
+			order:         models.Order{ItemQty: 250, ItemWeight: 0.1, ItemVolume: 1},
+			expectedPacks: map[int]int{500: 1},
+		},
+		{
+			name:        "No pack holds the items",
+			order:       models.Order{ItemQty: 250, ItemWeight: 3},
+			expectedErr: services.ErrPackLimitsExceeded,
+		},
+		{
+			name:        "Negative item weight",
+			order:       models.Order{ItemQty: 250, ItemWeight: -1},
+			expectedErr: services.ErrItemMeasures,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: packSizes})
+
+			result, err := service.CalculatePacks(context.Background(), tc.order)
+			packs := result.PackMap()
+
+			if !reflect.DeepEqual(packs, tc.expectedPacks) {
+				t.Errorf("expected packs to be %v, but got %v", tc.expectedPacks, packs)
+			}
+
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error to be %v, but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCalculatePacks_PackagingHierarchy(t *testing.T) {
+	t.Parallel()
+
+	packSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}, {MaxItems: 2000}, {MaxItems: 5000}}
+	testCases := []struct {
+		name          string
+		order         models.Order
+		expectedPlan  *models.PackagingPlan
+		expectedError error
+	}{
+		{
+			name: "Partial carton on a full pallet",
+			order: models.Order{
+				ItemQty:   12001,
+				Hierarchy: models.PackagingHierarchy{{Name: "carton", Holds: 2}, {Name: "pallet", Holds: 2}},
+			},
+			expectedPlan: &models.PackagingPlan{
+				Levels: []models.PackagingLevelCount{
+					{Level: "pack", Count: 4},
+					{Level: "carton", Count: 2},
+					{Level: "pallet", Count: 1},
+				},
+				Units: []models.PackagingUnit{{
+					Level:    "pallet",
+					Quantity: 1,
+					Items:    12250,
+					Contents: []models.PackagingUnit{
+						{Level: "carton", Quantity: 1, Items: 10000, Contents: []models.PackagingUnit{{Level: "pack", Quantity: 2, Items: 5000}}},
+						{Level: "carton", Quantity: 1, Items: 2250, Contents: []models.PackagingUnit{
+							{Level: "pack", Quantity: 1, Items: 2000},
+							{Level: "pack", Quantity: 1, Items: 250},
+						}},
+					},
+				}},
+			},
+		},
+		{
+			name: "Identical units are grouped",
+			order: models.Order{
+				ItemQty:   2_000_000_001,
+				Hierarchy: models.PackagingHierarchy{{Name: "carton", Holds: 4}, {Name: "pallet", Holds: 20}},
+			},
+			expectedPlan: &models.PackagingPlan{
+				Levels: []models.PackagingLevelCount{
+					{Level: "pack", Count: 400001},
+					{Level: "carton", Count: 100001, Partial: 1},
+					{Level: "pallet", Count: 5001, Partial: 1},
+				},
+				Units: []models.PackagingUnit{
+					{Level: "pallet", Quantity: 5000, Items: 400000, Contents: []models.PackagingUnit{
+						{Level: "carton", Quantity: 20, Items: 20000, Contents: []models.PackagingUnit{{Level: "pack", Quantity: 4, Items: 5000}}},
+					}},
+					{Level: "pallet", Quantity: 1, Items: 250, Partial: true, Contents: []models.PackagingUnit{
+						{Level: "carton", Quantity: 1, Items: 250, Partial: true, Contents: []models.PackagingUnit{{Level: "pack", Quantity: 1, Items: 250}}},
+					}},
+				},
+			},
+		},
+		{
+			name:  "No hierarchy",
+			order: models.Order{ItemQty: 12001},
+		},
+		{
+			name:          "Invalid hierarchy",
+			order:         models.Order{ItemQty: 12001, Hierarchy: models.PackagingHierarchy{{Name: "carton", Holds: 0}}},
+			expectedError: services.ErrInvalidHierarchy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: packSizes})
+
+			result, err := service.CalculatePacks(context.Background(), tc.order)
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error to be %v, but got %v", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(result.Packaging, tc.expectedPlan) {
+				t.Errorf("expected packaging plan to be %+v, but got %+v", tc.expectedPlan, result.Packaging)
+			}
+		})
+	}
+}
+
+func TestCalculatePacks_GlobalPackagingHierarchy(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	service := services.NewPackingService(
+		&testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}}},
+		services.WithPackagingHierarchy(models.PackagingHierarchy{{Name: "carton", Holds: 4}}),
+	)
+
+	// Act
+	result, err := service.CalculatePacks(context.Background(), models.Order{ItemQty: 1001})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to calculate packs: %v", err)
+	}
+
+	expectedLevels := []models.PackagingLevelCount{{Level: "pack", Count: 5}, {Level: "carton", Count: 2, Partial: 1}}
+	if result.Packaging == nil || !reflect.DeepEqual(result.Packaging.Levels, expectedLevels) {
+		t.Errorf("expected packaging levels to be %+v, but got %+v", expectedLevels, result.Packaging)
+	}
+}
+
+func TestCalculatePacks_Reserve_AdjustsStock(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	stock := 10
+	provider := &testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500, Stock: &stock}}}
+	service := services.NewPackingService(provider)
+	order := models.Order{ItemQty: 1250, Reserve: true}
+
+	// Act
+	_, err := service.CalculatePacks(context.Background(), order)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to calculate packs: %v", err)
+	}
+
+	expectedAdjustments := []models.StockAdjustment{{MaxItems: 500, Delta: -2}}
+	if !reflect.DeepEqual(provider.StockAdjustments, expectedAdjustments) {
+		t.Errorf("expected stock adjustments to be %v, but got %v", expectedAdjustments, provider.StockAdjustments)
+	}
+}
+
+func TestCalculatePacks_LargeOrderQuantity(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: []models.PackSize{
+		{MaxItems: 250},
+		{MaxItems: 500},
+		{MaxItems: 1000},
+		{MaxItems: 2000},
+		{MaxItems: 5000},
+	}})
+	order := models.Order{ItemQty: 2_000_000_001}
+
+	// Act
+	result, err := service.CalculatePacks(context.Background(), order)
+	packs := result.PackMap()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to calculate packs: %v", err)
+	}
+
+	expectedPacks := map[int]int{5000: 400_000, 250: 1}
+	if !reflect.DeepEqual(packs, expectedPacks) {
+		t.Errorf("expected packs to be %v, but got %v", expectedPacks, packs)
+	}
+}
+
+func TestCalculatePacks_NearlyCoprimeLargePackSizes_ReturnError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		packSizes []models.PackSize
+	}{
+		{name: "Five digit pack sizes", packSizes: []models.PackSize{{MaxItems: 9973}, {MaxItems: 10000}}},
+		{name: "Six digit pack sizes", packSizes: []models.PackSize{{MaxItems: 99991}, {MaxItems: 100000}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: tc.packSizes})
+			order := models.Order{ItemQty: 2_000_000_001}
+
+			// Act
+			_, err := service.CalculatePacks(context.Background(), order)
+
+			// Assert
+			if !errors.Is(err, services.ErrPackingTooLarge) {
+				t.Fatalf("expected error %v, but got %v", services.ErrPackingTooLarge, err)
+			}
+		})
+	}
+}
+
+func TestCalculatePacks_MatchesFullTable(t *testing.T) {
+	t.Parallel()
+
+	packSizeSets := [][]int{
+		{250, 500, 1000, 2000, 5000},
+		{10, 20, 50, 100, 200},
+		{23, 31, 53},
+		{6, 9, 20},
+		{3, 7},
+		{42},
+	}
+
+	for _, sizes := range packSizeSets {
+		// Cover the test table quantities and a sweep well past the residue window of every set
+		quantities := []int{1, 125, 250, 251, 501, 1251, 12001, 15001}
+		for qty := 1; qty <= 40_000; qty += 61 {
+			quantities = append(quantities, qty)
+		}
+
+		for _, qty := range quantities {
+			packSizes := make([]models.PackSize, len(sizes))
+			for i, size := range sizes {
+				packSizes[i] = models.PackSize{MaxItems: size}
+			}
+
+			service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: packSizes})
+			result, err := service.CalculatePacks(context.Background(), models.Order{ItemQty: qty})
+			packs := result.PackMap()
+			if err != nil {
+				t.Fatalf("failed to calculate packs for %d with %v: %v", qty, sizes, err)
+			}
+
+			expectedPacks := fullTablePacks(sizes, qty)
+			if !reflect.DeepEqual(packs, expectedPacks) {
+				t.Fatalf("expected packs for %d with %v to be %v, but got %v", qty, sizes, expectedPacks, packs)
+			}
+		}
+	}
+}
+
+// fullTablePacks is the reference implementation that builds the dynamic programming table
+// for the whole order quantity
+func fullTablePacks(packSizes []int, orderQty int) map[int]int {
+	maxAmount := orderQty + packSizes[0]
+
+	dp := make([]int, maxAmount+1)
+	for i := range dp {
+		dp[i] = math.MaxInt32
+	}
+	packSizesUsed := make([]int, maxAmount+1)
+	dp[0] = 0
+
+	for _, packSize := range packSizes {
+		for i := packSize; i <= maxAmount; i++ {
+			if dp[i-packSize]+1 < dp[i] {
+				dp[i] = dp[i-packSize] + 1
+				packSizesUsed[i] = packSize
+			}
+		}
+	}
+
+	bestAmount := 0
+	for i := orderQty; i <= maxAmount; i++ {
+		if dp[i] < math.MaxInt32 {
+			bestAmount = i
+			break
+		}
+	}
+
+	packSizeCombination := make(map[int]int)
+	for i := bestAmount; i > 0; i -= packSizesUsed[i] {
+		packSizeCombination[packSizesUsed[i]]++
+	}
+
+	return packSizeCombination
+}
+
+func TestCalculatePacks_TableCache_MatchesUncached(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	packSizes := []models.PackSize{
+		{MaxItems: 23, Cost: 3},
+		{MaxItems: 31, Cost: 4},
+		{MaxItems: 53, Cost: 6.5},
+		{MaxItems: 250, Cost: 20},
+	}
+	provider := &testdata.MockPackSizeProvider{PackSizes: packSizes}
+	cached := services.NewPackingService(provider)
+	uncached := services.NewPackingService(provider, services.WithTableCache(0))
+	objectives := []models.Objective{models.ObjectiveFewestItems, models.ObjectiveFewestPacks, models.ObjectiveLowestCost}
+
+	// Order quantities growing from small to large make the cached tables grow along
+	orders := []models.Order{}
+	for qty := 1; qty <= 20_000; qty += 37 {
+		for _, objective := range objectives {
+			orders = append(orders, models.Order{ItemQty: qty, Objective: objective})
+		}
+	}
+
+	expectedResults := make([]models.PackingResult, len(orders))
+	for i, order := range orders {
+		result, err := uncached.CalculatePacks(context.Background(), order)
+		if err != nil {
+			t.Fatalf("failed to calculate packs for %+v: %v", order, err)
+		}
+		expectedResults[i] = result
+	}
+
+	// Act
+	results := make([]models.PackingResult, len(orders))
+	errs := make([]error, len(orders))
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := worker; i < len(orders); i += 4 {
+				results[i], errs[i] = cached.CalculatePacks(context.Background(), orders[i])
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	// Assert
+	for i, order := range orders {
+		if errs[i] != nil {
+			t.Fatalf("failed to calculate packs for %+v: %v", order, errs[i])
+		}
+		if !reflect.DeepEqual(results[i], expectedResults[i]) {
+			t.Fatalf("expected the result for %+v to be %+v, but got %+v", order, expectedResults[i], results[i])
+		}
+	}
+}
+
+func TestTableCache_DropsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sets := [][]models.PackSize{
+		{{MaxItems: 23}, {MaxItems: 31}},
+		{{MaxItems: 250}, {MaxItems: 500}},
+		{{MaxItems: 6}, {MaxItems: 9}},
+	}
+	const maxAmount = 1000
+	cache := services.NewTableCache(2 * services.TableSize(sets[0], maxAmount))
+
+	// Act
+	cache.Table(sets[0], maxAmount)
+	cache.Table(sets[1], maxAmount)
+	cache.Table(sets[0], maxAmount)
+	cache.Table(sets[2], maxAmount)
+
+	// Assert
+	for i, expected := range []bool{true, false, true} {
+		if cached := cache.Cached(sets[i]); cached != expected {
+			t.Fatalf("unexpected caching of the table of %+v, got %t, want %t", sets[i], cached, expected)
+		}
+	}
+}
+
+func BenchmarkCalculatePacks(b *testing.B) {
+	packSizeSets := []struct {
+		name      string
+		packSizes []models.PackSize
+	}{
+		{
+			name:      "default pack sizes",
+			packSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}, {MaxItems: 2000}, {MaxItems: 5000}},
+		},
+		{
+			name:      "coprime pack sizes",
+			packSizes: []models.PackSize{{MaxItems: 23}, {MaxItems: 31}, {MaxItems: 53}},
+		},
+		{
+			name:      "wide residue window",
+			packSizes: []models.PackSize{{MaxItems: 997}, {MaxItems: 1499}, {MaxItems: 2003}},
+		},
+	}
+
+	cacheSizes := []struct {
+		name    string
+		maxSize int
+	}{
+		{name: "uncached", maxSize: 0},
+		{name: "cached", maxSize: services.DefaultTableCacheSize},
+	}
+
+	for _, set := range packSizeSets {
+		for _, cacheSize := range cacheSizes {
+			b.Run(set.name+"/"+cacheSize.name, func(b *testing.B) {
+				service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: set.packSizes}, services.WithTableCache(cacheSize.maxSize))
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					order := models.Order{ItemQty: 1_000_000 + i%10_000}
+					if _, err := service.CalculatePacks(context.Background(), order); err != nil {
+						b.Fatalf("failed to calculate packs: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCalculateShipments(t *testing.T) {
+	t.Parallel()
+
+	packSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}, {MaxItems: 2000}, {MaxItems: 5000}}
+	testCases := []struct {
+		name              string
+		order             models.ShipmentOrder
+		expectedShipments []models.Shipment
+		expectedErr       error
+	}{
+		{
+			name:  "No limits",
+			order: models.ShipmentOrder{Order: models.Order{ItemQty: 12001}},
+			expectedShipments: []models.Shipment{
+				{Quantity: 1, Packs: []models.PackLine{{MaxItems: 5000, Quantity: 2}, {MaxItems: 2000, Quantity: 1}, {MaxItems: 250, Quantity: 1}}, PackCount: 4, ItemsShipped: 12250},
+			},
+		},
+		{
+			name:  "Pack limit",
+			order: models.ShipmentOrder{Order: models.Order{ItemQty: 12001}, Limits: models.ShipmentLimits{MaxPacks: 2}},
+			expectedShipments: []models.Shipment{
+				{Quantity: 1, Packs: []models.PackLine{{MaxItems: 5000, Quantity: 1}, {MaxItems: 2000, Quantity: 1}}, PackCount: 2, ItemsShipped: 7000},
+				{Quantity: 1, Packs: []models.PackLine{{MaxItems: 5000, Quantity: 1}, {MaxItems: 250, Quantity: 1}}, PackCount: 2, ItemsShipped: 5250},
+			},
+		},
+		{
+			name:  "Item limit falls back to smaller packs",
+			order: models.ShipmentOrder{Order: models.Order{ItemQty: 12001}, Limits: models.ShipmentLimits{MaxItems: 2000}},
+			expectedShipments: []models.Shipment{
+				{Quantity: 6, Packs: []models.PackLine{{MaxItems: 2000, Quantity: 1}}, PackCount: 1, ItemsShipped: 2000},
+				{Quantity: 1, Packs: []models.PackLine{{MaxItems: 250, Quantity: 1}}, PackCount: 1, ItemsShipped: 250},
+			},
+		},
+		{
+			name:  "Identical shipments are grouped",
+			order: models.ShipmentOrder{Order: models.Order{ItemQty: 2_000_000_001}, Limits: models.ShipmentLimits{MaxPacks: 100}},
+			expectedShipments: []models.Shipment{
+				{Quantity: 3901, Packs: []models.PackLine{{MaxItems: 5000, Quantity: 100}}, PackCount: 100, ItemsShipped: 500000},
+				{Quantity: 1, Packs: []models.PackLine{{MaxItems: 5000, Quantity: 99}, {MaxItems: 250, Quantity: 1}}, PackCount: 100, ItemsShipped: 495250},
+				{Quantity: 99, Packs: []models.PackLine{{MaxItems: 5000, Quantity: 99}}, PackCount: 99, ItemsShipped: 495000},
+			},
+		},
+		{
+			name:        "No pack fits a shipment",
+			order:       models.ShipmentOrder{Order: models.Order{ItemQty: 12001}, Limits: models.ShipmentLimits{MaxItems: 100}},
+			expectedErr: services.ErrShipmentLimitsExceeded,
+		},
+		{
+			name:        "Negative limits",
+			order:       models.ShipmentOrder{Order: models.Order{ItemQty: 12001}, Limits: models.ShipmentLimits{MaxPacks: -1}},
+			expectedErr: services.ErrInvalidShipmentLimits,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: packSizes})
+
+			plan, err := service.CalculateShipments(context.Background(), tc.order)
+
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error to be %v, but got %v", tc.expectedErr, err)
+			}
+
+			if !reflect.DeepEqual(plan.Shipments, tc.expectedShipments) {
+				t.Errorf("expected shipments to be %+v, but got %+v", tc.expectedShipments, plan.Shipments)
+			}
+
+			shipmentCount := 0
+			for _, shipment := range tc.expectedShipments {
+				shipmentCount += shipment.Quantity
+			}
+			if plan.ShipmentCount != shipmentCount {
+				t.Errorf("expected %d shipments, but got %d", shipmentCount, plan.ShipmentCount)
+			}
+		})
+	}
+}
+
+func TestNewBatch(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	provider := &testdata.MockPackSizeProvider{
+		PackSizes:        []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}, {MaxItems: 2000}, {MaxItems: 5000}},
+		ProductPackSizes: map[string][]models.PackSize{"SKU-1": {{MaxItems: 5}, {MaxItems: 12}}},
+	}
+	pack := services.NewPackingService(provider).NewBatch()
+	orders := []models.Order{{ItemQty: 251}, {ItemQty: 12001}, {ItemQty: 20, SKU: "SKU-1"}, {ItemQty: 251}, {ItemQty: 0}}
+
+	// Act
+	packs := []map[int]int{}
+	errs := []error{}
+	for _, order := range orders {
+		result, err := pack(context.Background(), order)
+		packs = append(packs, result.PackMap())
+		errs = append(errs, err)
+	}
+
+	// Assert
+	expectedPacks := []map[int]int{{500: 1}, {5000: 2, 2000: 1, 250: 1}, {5: 4}, {500: 1}, nil}
+	if !reflect.DeepEqual(packs, expectedPacks) {
+		t.Errorf("expected packs to be %v, but got %v", expectedPacks, packs)
+	}
+
+	expectedErrs := []error{nil, nil, nil, nil, services.ErrOrderQuantity}
+	for i, err := range errs {
+		if !errors.Is(err, expectedErrs[i]) {
+			t.Errorf("expected error of order %d to be %v, but got %v", i, expectedErrs[i], err)
+		}
+	}
+
+	if provider.Fetches.Load() != 2 {
+		t.Errorf("expected the pack sizes to be fetched once per SKU, but they were fetched %d times", provider.Fetches.Load())
+	}
+}
+
+func TestNewBatch_Reserve_FetchesPackSizesAgain(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	stock := 10
+	provider := &testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500, Stock: &stock}}}
+	pack := services.NewPackingService(provider).NewBatch()
+
+	// Act
+	for _, order := range []models.Order{{ItemQty: 500, Reserve: true}, {ItemQty: 500}} {
+		if _, err := pack(context.Background(), order); err != nil {
+			t.Fatalf("failed to calculate packs: %v", err)
+		}
+	}
+
+	// Assert
+	if provider.Fetches.Load() != 2 {
+		t.Errorf("expected the pack sizes to be fetched again after reserving stock, but they were fetched %d times", provider.Fetches.Load())
+	}
+}
+
+func TestCalculateOrderPacks(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{
+		PackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}},
+		ProductPackSizes: map[string][]models.PackSize{
+			"SKU-1": {{MaxItems: 6}, {MaxItems: 12}},
+		},
+	})
+	order := models.MultiLineOrder{Lines: []models.OrderLine{
+		{ItemQty: 501},
+		{SKU: "SKU-1", ItemQty: 13},
+	}}
+
+	// Act
+	result, err := service.CalculateOrderPacks(context.Background(), order)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to calculate order packs: %v", err)
+	}
+
+	version := models.PackSizesVersion([]models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}})
+	productVersion := models.PackSizesVersion([]models.PackSize{{MaxItems: 6}, {MaxItems: 12}})
+	expectedResult := models.MultiLinePacking{
+		Lines: []models.LinePacking{
+			{PackingResult: models.PackingResult{
+				Packs:            []models.PackLine{{MaxItems: 500, Quantity: 1}, {MaxItems: 250, Quantity: 1}},
+				RequestedQty:     501,
+				ItemsShipped:     750,
+				Overshoot:        249,
+				PackCount:        2,
+				PackSizesVersion: version,
+			}},
+			{SKU: "SKU-1", PackingResult: models.PackingResult{
+				Packs:            []models.PackLine{{MaxItems: 12, Quantity: 1}, {MaxItems: 6, Quantity: 1}},
+				RequestedQty:     13,
+				ItemsShipped:     18,
+				Overshoot:        5,
+				PackCount:        2,
+				PackSizesVersion: productVersion,
+			}},
+		},
+		PackCount:    4,
+		ItemsShipped: 768,
+		Overshoot:    254,
+	}
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Errorf("expected result to be %+v, but got %+v", expectedResult, result)
+	}
+}
+
+func TestCalculateOrderPacks_InvalidLines_ReturnLinesError(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{
+		PackSizes: []models.PackSize{{MaxItems: 250}},
+		ProductPackSizes: map[string][]models.PackSize{
+			"SKU-1": {},
+		},
+	})
+	order := models.MultiLineOrder{Lines: []models.OrderLine{
+		{ItemQty: 0},
+		{ItemQty: 10},
+		{SKU: "SKU-1", ItemQty: 10},
+	}}
+
+	// Act
+	result, err := service.CalculateOrderPacks(context.Background(), order)
+
+	// Assert
+	var linesErr *services.OrderLinesError
+	if !errors.As(err, &linesErr) {
+		t.Fatalf("expected an order lines error, but got %v", err)
+	}
+
+	expectedLines := []models.OrderLineError{
+		{Line: 0, Error: services.ErrOrderQuantity.Error()},
+		{Line: 2, SKU: "SKU-1", Error: services.ErrNoPackSizesAvailable.Error()},
+	}
+	if !reflect.DeepEqual(linesErr.Lines, expectedLines) {
+		t.Errorf("expected line errors to be %+v, but got %+v", expectedLines, linesErr.Lines)
+	}
+
+	if result.Lines != nil {
+		t.Errorf("expected no partial result, but got %+v", result)
+	}
+}
+
+func TestCalculateOrderPacks_ProviderError_ReturnError(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	expectedErr := errors.New("provider error")
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{Error: expectedErr})
+	order := models.MultiLineOrder{Lines: []models.OrderLine{{ItemQty: 10}}}
+
+	// Act
+	_, err := service.CalculateOrderPacks(context.Background(), order)
+
+	// Assert
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to be %v, but got %v", expectedErr, err)
+	}
+}
+
+func TestUpdatePackSizes_ProviderError_ReturnError(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	expectedErr := errors.New("provider error")
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{Error: expectedErr})
+	packSizes := []models.PackSize{{MaxItems: 250}}
+
+	// Act
+	err := service.UpdatePackSizes(context.Background(), "", packSizes)
+
+	// Assert
+	if err == nil {
+		t.Error("expected an error, but got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to be %v, but got %v", expectedErr, err)
+	}
+}
+
+func TestUpdatePackSizes_Normalizes(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	provider := &testdata.MockPackSizeProvider{}
+	service := services.NewPackingService(provider)
+	stock := 10
+	packSizes := []models.PackSize{
+		{MaxItems: 500, Cost: 2},
+		{MaxItems: 250, Cost: 1, Stock: &stock},
+		{MaxItems: 500, Cost: 2},
+		{MaxItems: 250, Cost: 1, Stock: &stock},
+	}
+
+	// Act
+	err := service.UpdatePackSizes(context.Background(), "", packSizes)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to update pack sizes: %v", err)
+	}
+
+	expectedPackSizes := []models.PackSize{
+		{MaxItems: 250, Cost: 1, Stock: &stock},
+		{MaxItems: 500, Cost: 2},
+	}
+	if !reflect.DeepEqual(provider.Updated, expectedPackSizes) {
+		t.Errorf("expected the pack sizes to be saved as %v, but got %v", expectedPackSizes, provider.Updated)
+	}
+}
+
+func TestUpdatePackSizes_InvalidPackSizes_ReturnFieldErrors(t *testing.T) {
+	t.Parallel()
+
+	stock, negative := 10, -1
+
+	testCases := []struct {
+		name           string
+		rules          services.PackSizeRules
+		packSizes      []models.PackSize
+		expectedFields []models.FieldError
+	}{
+		{
+			name:           "No pack sizes",
+			packSizes:      []models.PackSize{},
+			expectedFields: []models.FieldError{{Field: "packSizes", Message: "pack sizes cannot be empty"}},
+		},
+		{
+			name:      "Non-positive sizes",
+			packSizes: []models.PackSize{{MaxItems: 0}, {MaxItems: 250}, {MaxItems: -5}},
+			expectedFields: []models.FieldError{
+				{Field: "packSizes[0].maxItems", Message: "pack size must hold at least 1 item(s)"},
+				{Field: "packSizes[2].maxItems", Message: "pack size must hold at least 1 item(s)"},
+			},
+		},
+		{
+			name:      "Negative cost, stock and limits",
+			packSizes: []models.PackSize{{MaxItems: 250, Cost: -1, Stock: &negative, MaxWeight: -1, MaxVolume: -1}},
+			expectedFields: []models.FieldError{
+				{Field: "packSizes[0].cost", Message: "pack cost cannot be negative"},
+				{Field: "packSizes[0].stock", Message: "pack stock cannot be negative"},
+				{Field: "packSizes[0].maxWeight", Message: "pack weight limit cannot be negative"},
+				{Field: "packSizes[0].maxVolume", Message: "pack volume limit cannot be negative"},
+			},
+		},
+		{
+			name:      "Conflicting duplicates",
+			packSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 250, Stock: &stock}},
+			expectedFields: []models.FieldError{
+				{Field: "packSizes[2].maxItems", Message: "pack size 250 is repeated with a different cost, stock or limits"},
+			},
+		},
+		{
+			name:      "Sizes out of bounds",
+			rules:     services.PackSizeRules{MinItems: 10, MaxItems: 1000},
+			packSizes: []models.PackSize{{MaxItems: 5}, {MaxItems: 250}, {MaxItems: 5000}},
+			expectedFields: []models.FieldError{
+				{Field: "packSizes[0].maxItems", Message: "pack size must hold at least 10 item(s)"},
+				{Field: "packSizes[2].maxItems", Message: "pack size cannot hold more than 1000 items"},
+			},
+		},
+		{
+			name:      "Too many pack sizes",
+			rules:     services.PackSizeRules{MaxCount: 2},
+			packSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 500}, {MaxItems: 1000}},
+			expectedFields: []models.FieldError{
+				{Field: "packSizes", Message: "at most 2 pack sizes are allowed"},
+			},
+		},
+		{
+			name:      "Nearly coprime large sizes",
+			packSizes: []models.PackSize{{MaxItems: 9973}, {MaxItems: 10000}},
+			expectedFields: []models.FieldError{
+				{Field: "packSizes", Message: "pack sizes are too large and share too few common divisors to pack orders with"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			provider := &testdata.MockPackSizeProvider{}
+			service := services.NewPackingService(provider, services.WithPackSizeRules(tc.rules))
+
+			// Act
+			err := service.UpdatePackSizes(context.Background(), "", tc.packSizes)
+
+			// Assert
+			var packSizesErr *services.PackSizesError
+			if !errors.As(err, &packSizesErr) {
+				t.Fatalf("expected a pack sizes error, but got %v", err)
+			}
+			if !reflect.DeepEqual(packSizesErr.Fields, tc.expectedFields) {
+				t.Errorf("expected field errors %v, but got %v", tc.expectedFields, packSizesErr.Fields)
+			}
+			if provider.Updated != nil {
+				t.Errorf("expected the pack sizes not to be saved, but got %v", provider.Updated)
+			}
+		})
+	}
+}
+
+func TestPackSizeRules_Default(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	provider := &testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 9973}}}
+	service := services.NewPackingService(provider)
+
+	testCases := []struct {
+		name           string
+		change         func() error
+		expectedFields []models.FieldError
+	}{
+		{
+			name: "Update beyond the default size limit",
+			change: func() error {
+				return service.UpdatePackSizes(context.Background(), "", []models.PackSize{{MaxItems: services.DefaultMaxPackSizeItems + 1}})
+			},
+			expectedFields: []models.FieldError{
+				{Field: "packSizes[0].maxItems", Message: fmt.Sprintf("pack size cannot hold more than %d items", services.DefaultMaxPackSizeItems)},
+			},
+		},
+		{
+			name: "Add a nearly coprime large size",
+			change: func() error {
+				_, err := service.AddPackSize(context.Background(), "", models.PackSize{MaxItems: 10000})
+				return err
+			},
+			expectedFields: []models.FieldError{
+				{Field: "packSizes", Message: "pack sizes are too large and share too few common divisors to pack orders with"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// Act
+		err := tc.change()
+
+		// Assert
+		var packSizesErr *services.PackSizesError
+		if !errors.As(err, &packSizesErr) {
+			t.Fatalf("%s: expected a *services.PackSizesError, but got %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(packSizesErr.Fields, tc.expectedFields) {
+			t.Errorf("%s: expected field errors to be %+v, but got %+v", tc.name, tc.expectedFields, packSizesErr.Fields)
+		}
+	}
+
+	if provider.Updated != nil {
+		t.Errorf("expected invalid changes not to update the pack sizes, but got %+v", provider.Updated)
+	}
+}
+
+func TestUpdatePackSizesIfMatch(t *testing.T) {
+	t.Parallel()
+
+	currentPackSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}
+	updatedPackSizes := []models.PackSize{{MaxItems: 1000}, {MaxItems: 250}}
+
+	testCases := []struct {
+		name              string
+		version           string
+		expectedPackSizes []models.PackSize
+		expectedVersion   string
+		expectedErr       error
+	}{
+		{
+			name:              "Current version",
+			version:           models.PackSizesVersion(currentPackSizes),
+			expectedPackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 1000}},
+			expectedVersion:   models.PackSizesVersion(updatedPackSizes),
+		},
+		{
+			name:              "Outdated version",
+			version:           models.PackSizesVersion(updatedPackSizes),
+			expectedPackSizes: currentPackSizes,
+			expectedErr:       services.ErrVersionConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			provider := &testdata.MockPackSizeProvider{PackSizes: currentPackSizes}
+			service := services.NewPackingService(provider)
+
+			// Act
+			version, err := service.UpdatePackSizesIfMatch(context.Background(), "", tc.version, updatedPackSizes)
+
+			// Assert
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error to be %v, but got %v", tc.expectedErr, err)
+			}
+			if version != tc.expectedVersion {
+				t.Errorf("expected version to be %q, but got %q", tc.expectedVersion, version)
+			}
+			if !reflect.DeepEqual(provider.PackSizes, tc.expectedPackSizes) {
+				t.Errorf("expected pack sizes to be %v, but got %v", tc.expectedPackSizes, provider.PackSizes)
+			}
+		})
+	}
+}
+
+func TestUpdatePackSizes_History(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	provider := &testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}}
+	history := &testdata.MockPackSizeHistory{}
+	service := services.NewPackingService(provider, services.WithPackSizeHistory(history))
+	ctx := services.ContextWithAuthor(context.Background(), "alice")
+
+	// Act
+	if err := service.UpdatePackSizes(ctx, "", []models.PackSize{{MaxItems: 1000}, {MaxItems: 250, Cost: 2}}); err != nil {
+		t.Fatalf("failed to update pack sizes: %v", err)
+	}
+	rollback, err := service.RollbackPackSizes(ctx, "", 1)
+	if err != nil {
+		t.Fatalf("failed to roll back pack sizes: %v", err)
+	}
+	versions, err := service.PackSizeVersions(ctx, "")
+	if err != nil {
+		t.Fatalf("failed to get pack size versions: %v", err)
+	}
+
+	// Assert
+	expectedPackSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}
+	if !reflect.DeepEqual(provider.PackSizes, expectedPackSizes) {
+		t.Errorf("expected the pack sizes to be rolled back to %v, but got %v", expectedPackSizes, provider.PackSizes)
+	}
+
+	if len(versions) != 3 || versions[0].ID != rollback.ID || rollback.RestoredFrom != 1 {
+		t.Fatalf("expected the rollback of version 1 to be the newest of 3 versions, but got %+v", versions)
+	}
+
+	expectedChanges := [][]models.PackSizeChange{
+		{
+			{MaxItems: 250, Type: models.PackSizeChanged, Before: &models.PackSize{MaxItems: 250, Cost: 2}, After: &models.PackSize{MaxItems: 250}},
+			{MaxItems: 500, Type: models.PackSizeAdded, After: &models.PackSize{MaxItems: 500}},
+			{MaxItems: 1000, Type: models.PackSizeRemoved, Before: &models.PackSize{MaxItems: 1000}},
+		},
+		{
+			{MaxItems: 250, Type: models.PackSizeChanged, Before: &models.PackSize{MaxItems: 250}, After: &models.PackSize{MaxItems: 250, Cost: 2}},
+			{MaxItems: 500, Type: models.PackSizeRemoved, Before: &models.PackSize{MaxItems: 500}},
+			{MaxItems: 1000, Type: models.PackSizeAdded, After: &models.PackSize{MaxItems: 1000}},
+		},
+		{
+			{MaxItems: 250, Type: models.PackSizeAdded, After: &models.PackSize{MaxItems: 250}},
+			{MaxItems: 500, Type: models.PackSizeAdded, After: &models.PackSize{MaxItems: 500}},
+		},
+	}
+	expectedAuthors := []string{"alice", "alice", ""}
+	for i, version := range versions {
+		if !reflect.DeepEqual(version.Changes, expectedChanges[i]) {
+			t.Errorf("expected the changes of version %d to be %+v, but got %+v", version.ID, expectedChanges[i], version.Changes)
+		}
+		if version.Author != expectedAuthors[i] {
+			t.Errorf("expected the author of version %d to be %q, but got %q", version.ID, expectedAuthors[i], version.Author)
+		}
+	}
+}
+
+func TestPackSizeVersions_Errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		opts        []services.Option
+		id          int
+		expectedErr error
+	}{
+		{name: "No history", expectedErr: services.ErrHistoryUnavailable},
+		{name: "Unknown version", opts: []services.Option{services.WithPackSizeHistory(&testdata.MockPackSizeHistory{})}, id: 7, expectedErr: services.ErrUnknownVersion},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			service := services.NewPackingService(&testdata.MockPackSizeProvider{}, tc.opts...)
+
+			// Act
+			_, versionErr := service.PackSizeVersion(context.Background(), "", tc.id)
+			_, rollbackErr := service.RollbackPackSizes(context.Background(), "", tc.id)
+
+			// Assert
+			if !errors.Is(versionErr, tc.expectedErr) {
+				t.Errorf("expected error to be %v, but got %v", tc.expectedErr, versionErr)
+			}
+			if !errors.Is(rollbackErr, tc.expectedErr) {
+				t.Errorf("expected error to be %v, but got %v", tc.expectedErr, rollbackErr)
+			}
+		})
+	}
+}
+
+func TestUpdatePackSizes_HistoryAndScheduleNotKept(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	provider := &testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}}}
+	service := services.NewPackingService(
+		provider,
+		services.WithPackSizeHistory(&testdata.MockPackSizeHistory{Error: services.ErrHistoryUnavailable}),
+		services.WithPackSizeSchedule(&testdata.MockPackSizeSchedule{Error: services.ErrScheduleUnavailable}),
+	)
+	expectedPackSizes := []models.PackSize{{MaxItems: 500}}
+
+	// Act
+	err := service.UpdatePackSizes(context.Background(), "", expectedPackSizes)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to update pack sizes: %v", err)
+	}
+
+	packSizes, err := service.GetPackSizes(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to get pack sizes: %v", err)
+	}
+	if !reflect.DeepEqual(packSizes, expectedPackSizes) {
+		t.Errorf("expected pack sizes to be %v, but got %v", expectedPackSizes, packSizes)
+	}
+
+	if _, err := service.PackSizeVersions(context.Background(), ""); !errors.Is(err, services.ErrHistoryUnavailable) {
+		t.Errorf("expected error to be %v, but got %v", services.ErrHistoryUnavailable, err)
+	}
+}
+
+func TestGetPackSizes_ProviderError_ReturnError(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	expectedErr := errors.New("provider error")
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{Error: expectedErr})
+
+	// Act
+	_, err := service.GetPackSizes(context.Background(), "")
+
+	// Assert
+	if err == nil {
+		t.Error("expected an error, but got nil")
+	}
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected error to be %v, but got %v", expectedErr, err)
+	}
+}
+
+func TestGetPackSizes(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	expectedPackSizes := []models.PackSize{
+		{MaxItems: 250},
+		{MaxItems: 500},
+		{MaxItems: 1000},
+		{MaxItems: 2000},
+		{MaxItems: 5000},
+	}
+	service := services.NewPackingService(&testdata.MockPackSizeProvider{PackSizes: expectedPackSizes})
+
+	// Act
+	packSizes, err := service.GetPackSizes(context.Background(), "")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to get pack sizes: %v", err)
+	}
+
+	if !reflect.DeepEqual(packSizes, expectedPackSizes) {
+		t.Errorf("expected pack sizes to be %v, but got %v", expectedPackSizes, packSizes)
+	}
+}
+
+func TestPackSizeSchedule(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	provider := &testdata.MockPackSizeProvider{
+		PackSizes:        []models.PackSize{{MaxItems: 250}, {MaxItems: 500}},
+		ProductPackSizes: map[string][]models.PackSize{"SKU-1": {{MaxItems: 6}, {MaxItems: 12}}},
+	}
+	history := &testdata.MockPackSizeHistory{}
+	schedule := &testdata.MockPackSizeSchedule{}
+	service := services.NewPackingService(provider,
+		services.WithPackSizeHistory(history),
+		services.WithPackSizeSchedule(schedule),
+		services.WithClock(func() time.Time { return now }),
+	)
+	ctx := services.ContextWithAuthor(context.Background(), "alice")
+
+	if _, err := service.SchedulePackSizes(ctx, "", now.Add(time.Hour), []models.PackSize{{MaxItems: 300}}); err != nil {
+		t.Fatalf("failed to schedule pack sizes: %v", err)
+	}
+	if _, err := service.SchedulePackSizes(ctx, "SKU-1", now.Add(2*time.Hour), []models.PackSize{{MaxItems: 24}}); err != nil {
+		t.Fatalf("failed to schedule pack sizes: %v", err)
+	}
+
+	testCases := []struct {
+		name              string
+		sku               string
+		asOf              time.Duration
+		expectedPackSizes []models.PackSize
+	}{
+		{name: "Default now", expectedPackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}},
+		{name: "Default later", asOf: 90 * time.Minute, expectedPackSizes: []models.PackSize{{MaxItems: 300}}},
+		{name: "Default in the past", asOf: -time.Hour, expectedPackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}},
+		{name: "Product later", sku: "SKU-1", asOf: 90 * time.Minute, expectedPackSizes: []models.PackSize{{MaxItems: 6}, {MaxItems: 12}}},
+		{name: "Product after its schedule", sku: "SKU-1", asOf: 3 * time.Hour, expectedPackSizes: []models.PackSize{{MaxItems: 24}}},
+		{name: "Product using the default pack sizes later", sku: "SKU-2", asOf: 90 * time.Minute, expectedPackSizes: []models.PackSize{{MaxItems: 300}}},
+	}
+
+	for _, tc := range testCases {
+		// Act
+		packSizes, err := service.GetPackSizesAt(ctx, tc.sku, now.Add(tc.asOf))
+
+		// Assert
+		if err != nil {
+			t.Fatalf("%s: failed to get pack sizes: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(packSizes, tc.expectedPackSizes) {
+			t.Errorf("%s: expected pack sizes to be %v, but got %v", tc.name, tc.expectedPackSizes, packSizes)
+		}
+	}
+
+	// Act
+	asOf := now.Add(90 * time.Minute)
+	quote, err := service.CalculatePacks(ctx, models.Order{ItemQty: 500, AsOf: &asOf})
+	if err != nil {
+		t.Fatalf("failed to calculate packs: %v", err)
+	}
+
+	// Assert
+	expectedPacks := []models.PackLine{{MaxItems: 300, Quantity: 2}}
+	if !reflect.DeepEqual(quote.Packs, expectedPacks) {
+		t.Errorf("expected the quote to use packs %v, but got %v", expectedPacks, quote.Packs)
+	}
+	if !reflect.DeepEqual(provider.PackSizes, []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}) {
+		t.Errorf("expected quoting to leave the pack sizes unchanged, but got %v", provider.PackSizes)
+	}
+
+	// Act
+	now = now.Add(90 * time.Minute)
+	packSizes, err := service.GetPackSizes(context.Background(), "SKU-2")
+	if err != nil {
+		t.Fatalf("failed to get pack sizes: %v", err)
+	}
+
+	// Assert
+	expectedPackSizes := []models.PackSize{{MaxItems: 300}}
+	if !reflect.DeepEqual(packSizes, expectedPackSizes) || !reflect.DeepEqual(provider.PackSizes, expectedPackSizes) {
+		t.Errorf("expected the scheduled pack sizes %v to take effect, but got %v", expectedPackSizes, packSizes)
+	}
+
+	remaining, err := service.ScheduledPackSizes(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to get scheduled pack sizes: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("expected the scheduled pack sizes to be removed once they took effect, but got %+v", remaining)
+	}
+
+	versions, err := service.PackSizeVersions(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to get pack size versions: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Author != "alice" || !versions[0].CreatedAt.Equal(now) {
+		t.Errorf("expected the scheduled pack sizes to be saved as a version by alice at %v, but got %+v", now, versions)
+	}
+}
+
+func TestPackSizeSchedule_Errors(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	provider := &testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}}
+	clock := services.WithClock(func() time.Time { return now })
+	service := services.NewPackingService(provider, services.WithPackSizeSchedule(&testdata.MockPackSizeSchedule{}), clock)
+	unscheduled := services.NewPackingService(provider, clock)
+	later := now.Add(time.Hour)
+
+	// Act
+	_, pastErr := service.SchedulePackSizes(context.Background(), "", now, []models.PackSize{{MaxItems: 0}})
+	_, unavailableErr := unscheduled.SchedulePackSizes(context.Background(), "", later, []models.PackSize{{MaxItems: 300}})
+	unknownErr := service.UnschedulePackSizes(context.Background(), "", 7)
+	_, reserveErr := service.CalculatePacks(context.Background(), models.Order{ItemQty: 10, Reserve: true, AsOf: &later})
+
+	// Assert
+	var packSizesErr *services.PackSizesError
+	if !errors.As(pastErr, &packSizesErr) {
+		t.Fatalf("expected a *services.PackSizesError, but got %v", pastErr)
+	}
+	expectedFields := []models.FieldError{
+		{Field: "effectiveFrom", Message: "pack sizes must take effect after now"},
+		{Field: "packSizes[0].maxItems", Message: "pack size must hold at least 1 item(s)"},
+	}
+	if !reflect.DeepEqual(packSizesErr.Fields, expectedFields) {
+		t.Errorf("expected field errors to be %+v, but got %+v", expectedFields, packSizesErr.Fields)
+	}
+
+	errs := []error{unavailableErr, unknownErr, reserveErr}
+	expectedErrs := []error{services.ErrScheduleUnavailable, services.ErrUnknownSchedule, services.ErrReserveAsOf}
+	for i, err := range errs {
+		if expectedErr := expectedErrs[i]; !errors.Is(err, expectedErr) {
+			t.Errorf("expected error to be %v, but got %v", expectedErr, err)
+		}
+	}
+}
+
+func TestPackSizeChanges(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	provider := &testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}}
+	history := &testdata.MockPackSizeHistory{}
+	service := services.NewPackingService(provider, services.WithPackSizeHistory(history), services.WithPackSizeRules(services.PackSizeRules{MaxCount: 3}))
+	ctx := context.Background()
+	cost, stock := 2.5, 4
+
+	// Act
+	added, err := service.AddPackSize(ctx, "", models.PackSize{MaxItems: 750})
+	if err != nil {
+		t.Fatalf("failed to add pack size: %v", err)
+	}
+	patched, err := service.PatchPackSize(ctx, "", 500, models.PackSizePatch{Cost: &cost, Stock: &stock})
+	if err != nil {
+		t.Fatalf("failed to patch pack size: %v", err)
+	}
+	if err := service.RemovePackSize(ctx, "", 250); err != nil {
+		t.Fatalf("failed to remove pack size: %v", err)
+	}
+	if _, err := service.AddPackSize(ctx, "SKU-1", models.PackSize{MaxItems: 100}); err != nil {
+		t.Fatalf("failed to add product pack size: %v", err)
+	}
+
+	// Assert
+	if expectedAdded := (models.PackSize{MaxItems: 750}); !reflect.DeepEqual(added, expectedAdded) {
+		t.Errorf("expected the added pack size to be %+v, but got %+v", expectedAdded, added)
+	}
+
+	expectedPatched := models.PackSize{MaxItems: 500, Cost: cost, Stock: &stock}
+	if !reflect.DeepEqual(patched, expectedPatched) {
+		t.Errorf("expected the patched pack size to be %+v, but got %+v", expectedPatched, patched)
+	}
+
+	expectedPackSizes := []models.PackSize{{MaxItems: 500, Cost: cost, Stock: &stock}, {MaxItems: 750}}
+	if !reflect.DeepEqual(provider.PackSizes, expectedPackSizes) {
+		t.Errorf("expected pack sizes to be %+v, but got %+v", expectedPackSizes, provider.PackSizes)
+	}
+
+	expectedProductPackSizes := []models.PackSize{{MaxItems: 100}, {MaxItems: 500, Cost: cost, Stock: &stock}, {MaxItems: 750}}
+	if !reflect.DeepEqual(provider.ProductPackSizes["SKU-1"], expectedProductPackSizes) {
+		t.Errorf("expected product pack sizes to be %+v, but got %+v", expectedProductPackSizes, provider.ProductPackSizes["SKU-1"])
+	}
+
+	versions, err := service.PackSizeVersions(ctx, "")
+	if err != nil {
+		t.Fatalf("failed to get pack size versions: %v", err)
+	}
+	if len(versions) != 4 {
+		t.Errorf("expected a version for every change and the initial pack sizes, but got %+v", versions)
+	}
+
+	testCases := []struct {
+		name        string
+		change      func() error
+		expectedErr error
+	}{
+		{name: "Add existing", change: func() error {
+			_, err := service.AddPackSize(ctx, "", models.PackSize{MaxItems: 500})
+			return err
+		}, expectedErr: services.ErrPackSizeExists},
+		{name: "Patch unknown", change: func() error {
+			_, err := service.PatchPackSize(ctx, "", 42, models.PackSizePatch{Cost: &cost})
+			return err
+		}, expectedErr: services.ErrUnknownPackSize},
+		{name: "Remove unknown", change: func() error { return service.RemovePackSize(ctx, "", 42) }, expectedErr: services.ErrUnknownPackSize},
+	}
+
+	for _, tc := range testCases {
+		if err := tc.change(); !errors.Is(err, tc.expectedErr) {
+			t.Errorf("%s: expected error to be %v, but got %v", tc.name, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestPackSizeChanges_ConcurrentChange_RecordsChangeMade(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	provider := &testdata.MockPackSizeProvider{PackSizes: []models.PackSize{{MaxItems: 250}, {MaxItems: 500}}}
+	history := &testdata.MockPackSizeHistory{}
+	service := services.NewPackingService(provider, services.WithPackSizeHistory(history))
+	ctx := context.Background()
+
+	// Another writer adds a pack size between reading the pack sizes and saving the change
+	provider.BeforeSwap = func() {
+		provider.BeforeSwap = nil
+		provider.PackSizes = []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 1000}}
+	}
+
+	// Act
+	_, err := service.AddPackSize(ctx, "", models.PackSize{MaxItems: 750})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("failed to add pack size: %v", err)
+	}
+
+	expectedPackSizes := []models.PackSize{{MaxItems: 250}, {MaxItems: 500}, {MaxItems: 750}, {MaxItems: 1000}}
+	if !reflect.DeepEqual(provider.PackSizes, expectedPackSizes) {
+		t.Errorf("expected pack sizes to be %+v, but got %+v", expectedPackSizes, provider.PackSizes)
+	}
+
+	versions, err := service.PackSizeVersions(ctx, "")
+	if err != nil {
+		t.Fatalf("failed to get pack size versions: %v", err)
+	}
+	expectedChanges := []models.PackSizeChange{{MaxItems: 750, Type: models.PackSizeAdded, After: &models.PackSize{MaxItems: 750}}}
+	if len(versions) == 0 || !reflect.DeepEqual(versions[0].Changes, expectedChanges) {
+		t.Errorf("expected the version to record only the added pack size %+v, but got %+v", expectedChanges, versions)
+	}
+}
+
+func TestPackSizeChanges_InvalidPackSizes_ReturnFieldErrors(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	provider := &testdata.MockPackSizeProvider{
+		PackSizes:        []models.PackSize{{MaxItems: 250}, {MaxItems: 500}},
+		ProductPackSizes: map[string][]models.PackSize{"SKU-1": {{MaxItems: 6}}},
+	}
+	service := services.NewPackingService(provider, services.WithPackSizeRules(services.PackSizeRules{MaxCount: 2}))
+	negative, stock := -1.0, 3
+
+	testCases := []struct {
+		name           string
+		change         func() error
+		expectedFields []models.FieldError
+	}{
+		{
+			name: "Add too many",
+			change: func() error {
+				_, err := service.AddPackSize(context.Background(), "", models.PackSize{MaxItems: 0})
+				return err
+			},
+			expectedFields: []models.FieldError{
+				{Field: "maxItems", Message: "pack size must hold at least 1 item(s)"},
+				{Field: "packSizes", Message: "at most 2 pack sizes are allowed"},
+			},
+		},
+		{
+			name: "Patch",
+			change: func() error {
+				_, err := service.PatchPackSize(context.Background(), "", 500, models.PackSizePatch{Cost: &negative, Stock: &stock, UnlimitedStock: true})
+				return err
+			},
+			expectedFields: []models.FieldError{
+				{Field: "cost", Message: "pack cost cannot be negative"},
+				{Field: "unlimitedStock", Message: "stock cannot be both set and unlimited"},
+			},
+		},
+		{
+			name:           "Remove last",
+			change:         func() error { return service.RemovePackSize(context.Background(), "SKU-1", 6) },
+			expectedFields: []models.FieldError{{Field: "maxItems", Message: "the last pack size cannot be removed"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		// Act
+		err := tc.change()
+
+		// Assert
+		var packSizesErr *services.PackSizesError
+		if !errors.As(err, &packSizesErr) {
+			t.Fatalf("%s: expected a *services.PackSizesError, but got %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(packSizesErr.Fields, tc.expectedFields) {
+			t.Errorf("%s: expected field errors to be %+v, but got %+v", tc.name, tc.expectedFields, packSizesErr.Fields)
+		}
+	}
+
+	if provider.Updated != nil {
+		t.Errorf("expected invalid changes not to update the pack sizes, but got %+v", provider.Updated)
 	}
 }
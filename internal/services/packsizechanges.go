@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// maxSwapAttempts is how many times a change of the pack sizes is made again when they were changed concurrently
+const maxSwapAttempts = 5
+
+// AddPackSize adds a pack size to the pack sizes for the specified SKU (the default pack sizes when empty) and
+// returns the pack size as it was saved. A *PackSizesError is returned if the pack size breaks the pack size rules,
+// ErrPackSizeExists if the SKU already has a pack of the size.
+func (s PackingService) AddPackSize(ctx context.Context, sku string, packSize models.PackSize) (models.PackSize, error) {
+	var added models.PackSize
+	err := s.changePackSizes(ctx, sku, func(current []models.PackSize) ([]models.PackSize, error) {
+		fieldErrs := s.rules.packSizeErrors(packSize)
+		if s.rules.MaxCount > 0 && len(current) >= s.rules.MaxCount {
+			fieldErrs = append(fieldErrs, models.FieldError{Field: "packSizes", Message: fmt.Sprintf("at most %d pack sizes are allowed", s.rules.MaxCount)})
+		}
+		if len(fieldErrs) == 0 {
+			fieldErrs = packSizeSetErrors(append(slices.Clone(current), packSize))
+		}
+		if len(fieldErrs) > 0 {
+			return nil, &PackSizesError{Fields: fieldErrs}
+		}
+
+		if slices.ContainsFunc(current, func(other models.PackSize) bool { return other.MaxItems == packSize.MaxItems }) {
+			return nil, fmt.Errorf("%w: %d", ErrPackSizeExists, packSize.MaxItems)
+		}
+
+		// The pack size goes before the first larger one
+		i := slices.IndexFunc(current, func(other models.PackSize) bool {
+			return other.MaxItems > packSize.MaxItems
+		})
+		if i == -1 {
+			i = len(current)
+		}
+
+		packSizes := slices.Insert(slices.Clone(current), i, packSize)
+		added = packSizes[i]
+
+		return packSizes, nil
+	})
+	if err != nil {
+		return models.PackSize{}, err
+	}
+
+	return added, nil
+}
+
+// RemovePackSize removes a pack size from the pack sizes for the specified SKU. ErrUnknownPackSize is returned
+// if the SKU has no pack of the size, a *PackSizesError if it is the last pack size of the SKU.
+func (s PackingService) RemovePackSize(ctx context.Context, sku string, maxItems int) error {
+	return s.changePackSizes(ctx, sku, func(current []models.PackSize) ([]models.PackSize, error) {
+		i := indexOfPackSize(current, maxItems)
+		if i == -1 {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownPackSize, maxItems)
+		}
+
+		if len(current) == 1 {
+			return nil, &PackSizesError{Fields: []models.FieldError{{Field: "maxItems", Message: "the last pack size cannot be removed"}}}
+		}
+
+		return slices.Delete(slices.Clone(current), i, i+1), nil
+	})
+}
+
+// PatchPackSize changes the attributes of a pack size for the specified SKU and returns the changed pack size.
+// ErrUnknownPackSize is returned if the SKU has no pack of the size, a *PackSizesError if the changed pack size
+// breaks the pack size rules.
+func (s PackingService) PatchPackSize(ctx context.Context, sku string, maxItems int, patch models.PackSizePatch) (models.PackSize, error) {
+	var patched models.PackSize
+	err := s.changePackSizes(ctx, sku, func(current []models.PackSize) ([]models.PackSize, error) {
+		i := indexOfPackSize(current, maxItems)
+		if i == -1 {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownPackSize, maxItems)
+		}
+
+		packSizes := slices.Clone(current)
+		packSizes[i] = patch.Apply(current[i])
+
+		fieldErrs := s.rules.packSizeErrors(packSizes[i])
+		if patch.Stock != nil && patch.UnlimitedStock {
+			fieldErrs = append(fieldErrs, models.FieldError{Field: "unlimitedStock", Message: "stock cannot be both set and unlimited"})
+		}
+		if len(fieldErrs) == 0 {
+			fieldErrs = packSizeSetErrors(packSizes)
+		}
+		if len(fieldErrs) > 0 {
+			return nil, &PackSizesError{Fields: fieldErrs}
+		}
+
+		patched = packSizes[i]
+
+		return packSizes, nil
+	})
+	if err != nil {
+		return models.PackSize{}, err
+	}
+
+	return patched, nil
+}
+
+// changePackSizes applies the scheduled pack sizes that took effect, replaces the pack sizes for the specified
+// SKU with the ones the change makes of the current pack sizes and drops the cached packing tables. A version
+// of the changed pack sizes is kept in the history if there is one.
+func (s PackingService) changePackSizes(ctx context.Context, sku string, change func(current []models.PackSize) ([]models.PackSize, error)) error {
+	if err := s.applySchedules(ctx, sku); err != nil {
+		return err
+	}
+
+	previous, packSizes, err := s.swapPackSizes(ctx, sku, change)
+	if err != nil {
+		return err
+	}
+
+	s.tables.clear()
+
+	if s.history == nil {
+		return nil
+	}
+
+	_, err = s.recordVersion(ctx, sku, previous, packSizes, 0)
+
+	return err
+}
+
+// swapPackSizes replaces the pack sizes for the specified SKU with the ones the change makes of the current
+// pack sizes, on the condition that they were not changed in the meantime. The change is made again on the
+// pack sizes a concurrent change left, so the pack sizes it returns are exactly the ones replaced and saved.
+// ErrVersionConflict is returned if the pack sizes keep changing.
+func (s PackingService) swapPackSizes(ctx context.Context, sku string, change func(current []models.PackSize) ([]models.PackSize, error)) (previous, packSizes []models.PackSize, err error) {
+	for attempt := 1; ; attempt++ {
+		if previous, err = s.packSizeProvider.GetPackSizes(ctx, sku); err != nil {
+			return nil, nil, fmt.Errorf("failed to get pack sizes: %w", err)
+		}
+
+		if packSizes, err = change(previous); err != nil {
+			return nil, nil, err
+		}
+
+		err = s.packSizeProvider.CompareAndSwap(ctx, sku, models.PackSizesVersion(previous), packSizes)
+		if errors.Is(err, ErrVersionConflict) && attempt < maxSwapAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return previous, packSizes, nil
+	}
+}
+
+// indexOfPackSize returns the index of the pack of the size in the pack sizes, -1 when there is none
+func indexOfPackSize(packSizes []models.PackSize, maxItems int) int {
+	return slices.IndexFunc(packSizes, func(packSize models.PackSize) bool {
+		return packSize.MaxItems == maxItems
+	})
+}
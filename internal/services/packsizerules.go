@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// ErrInvalidPackSizeRules is returned when the pack size rules have negative or contradicting limits
+var ErrInvalidPackSizeRules = fmt.Errorf("invalid pack size rules")
+
+// DefaultMaxPackSizeItems is the most items a pack size may hold unless the rules say otherwise
+const DefaultMaxPackSizeItems = 100_000
+
+// PackSizeRules are the rules pack sizes have to follow to be saved
+type PackSizeRules struct {
+	// MinItems is the fewest items a pack size may hold, every pack size holds at least one item
+	MinItems int
+	// MaxItems is the most items a pack size may hold, unlimited when 0. A PackingService allows
+	// DefaultMaxPackSizeItems unless it is given other rules.
+	MaxItems int
+	// MaxCount is the most pack sizes a SKU may have, unlimited when 0
+	MaxCount int
+}
+
+// IsValid reports whether the limits are not negative and the fewest items do not exceed the most items
+func (r PackSizeRules) IsValid() bool {
+	if r.MinItems < 0 || r.MaxItems < 0 || r.MaxCount < 0 {
+		return false
+	}
+
+	return r.MaxItems == 0 || r.MinItems <= r.MaxItems
+}
+
+// PackSizesError is returned when pack sizes break the pack size rules
+type PackSizesError struct {
+	// Fields describes every invalid field of the pack sizes
+	Fields []models.FieldError
+}
+
+// Error returns the error message
+func (e *PackSizesError) Error() string {
+	return fmt.Sprintf("%d pack size field(s) are invalid", len(e.Fields))
+}
+
+// normalize checks the pack sizes against the rules and returns them sorted from the smallest to the largest,
+// with repeated pack sizes removed. A size repeated with a different cost, stock or limits is ambiguous and
+// reported along with every other invalid field as a *PackSizesError.
+func (r PackSizeRules) normalize(packSizes []models.PackSize) ([]models.PackSize, error) {
+	fieldErrs := []models.FieldError{}
+	seen := make(map[int]models.PackSize, len(packSizes))
+	normalized := make([]models.PackSize, 0, len(packSizes))
+	for i, packSize := range packSizes {
+		for _, fieldErr := range r.packSizeErrors(packSize) {
+			fieldErr.Field = fmt.Sprintf("packSizes[%d].%s", i, fieldErr.Field)
+			fieldErrs = append(fieldErrs, fieldErr)
+		}
+
+		previous, ok := seen[packSize.MaxItems]
+		if !ok {
+			seen[packSize.MaxItems] = packSize
+			normalized = append(normalized, packSize)
+			continue
+		}
+
+		if !previous.Equal(packSize) {
+			fieldErrs = append(fieldErrs, models.FieldError{
+				Field:   fmt.Sprintf("packSizes[%d].maxItems", i),
+				Message: fmt.Sprintf("pack size %d is repeated with a different cost, stock or limits", packSize.MaxItems),
+			})
+		}
+	}
+
+	if len(normalized) == 0 {
+		fieldErrs = append(fieldErrs, models.FieldError{Field: "packSizes", Message: "pack sizes cannot be empty"})
+	}
+
+	if r.MaxCount > 0 && len(normalized) > r.MaxCount {
+		fieldErrs = append(fieldErrs, models.FieldError{Field: "packSizes", Message: fmt.Sprintf("at most %d pack sizes are allowed", r.MaxCount)})
+	}
+
+	fieldErrs = append(fieldErrs, packSizeSetErrors(normalized)...)
+
+	if len(fieldErrs) > 0 {
+		return nil, &PackSizesError{Fields: fieldErrs}
+	}
+
+	slices.SortFunc(normalized, func(a, b models.PackSize) int {
+		return a.MaxItems - b.MaxItems
+	})
+
+	return normalized, nil
+}
+
+// packSizeSetErrors checks that orders can be packed with the pack sizes as a whole and returns the errors of
+// the pack sizes field. Large nearly coprime pack sizes leave orders too many amounts to consider, which is
+// rejected when the pack sizes are saved rather than when an order is packed.
+func packSizeSetErrors(packSizes []models.PackSize) []models.FieldError {
+	for _, objective := range []models.Objective{models.ObjectiveFewestItems, models.ObjectiveFewestPacks, models.ObjectiveLowestCost} {
+		if !fitsDPTable(packSizes, objective) {
+			return []models.FieldError{{Field: "packSizes", Message: "pack sizes are too large and share too few common divisors to pack orders with"}}
+		}
+	}
+
+	return nil
+}
+
+// packSizeErrors checks a single pack size against the rules and returns its invalid fields
+func (r PackSizeRules) packSizeErrors(packSize models.PackSize) []models.FieldError {
+	fieldErrs := []models.FieldError{}
+	invalid := func(field, format string, args ...interface{}) {
+		fieldErrs = append(fieldErrs, models.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	minItems := max(r.MinItems, 1)
+	switch {
+	case packSize.MaxItems < minItems:
+		invalid("maxItems", "pack size must hold at least %d item(s)", minItems)
+	case r.MaxItems > 0 && packSize.MaxItems > r.MaxItems:
+		invalid("maxItems", "pack size cannot hold more than %d items", r.MaxItems)
+	}
+
+	if packSize.Cost < 0 {
+		invalid("cost", "pack cost cannot be negative")
+	}
+
+	if packSize.Stock != nil && *packSize.Stock < 0 {
+		invalid("stock", "pack stock cannot be negative")
+	}
+
+	if packSize.MaxWeight < 0 {
+		invalid("maxWeight", "pack weight limit cannot be negative")
+	}
+
+	if packSize.MaxVolume < 0 {
+		invalid("maxVolume", "pack volume limit cannot be negative")
+	}
+
+	return fieldErrs
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"math"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// policyTarget returns the target of the ordered quantity with the range of items the policy allows shipping
+func policyTarget(policy models.PackingPolicy, orderQty int) PackingTarget {
+	target := NewPackingTarget(orderQty)
+	if policy.MaxOvershoot != nil {
+		target.MaxQty = min(target.MaxQty, addLimit(orderQty, float64(*policy.MaxOvershoot)))
+	}
+	if policy.MaxOvershootPercent != nil {
+		target.MaxQty = min(target.MaxQty, addLimit(orderQty, float64(orderQty)**policy.MaxOvershootPercent/100))
+	}
+
+	// Underfilling is allowed up to the tightest limit that is set, but at least one item is always shipped
+	underfill := -1
+	if policy.MaxUnderfill != nil {
+		underfill = *policy.MaxUnderfill
+	}
+	if policy.MaxUnderfillPercent != nil {
+		limit := int(min(float64(orderQty)**policy.MaxUnderfillPercent/100, float64(orderQty)))
+		if underfill == -1 || limit < underfill {
+			underfill = limit
+		}
+	}
+	if underfill > 0 {
+		target.MinQty = orderQty - min(underfill, orderQty-1)
+	}
+
+	return target
+}
+
+// addLimit adds the limit to the quantity, saturating at math.MaxInt
+func addLimit(qty int, limit float64) int {
+	if limit >= float64(math.MaxInt-qty) {
+		return math.MaxInt
+	}
+
+	return qty + int(limit)
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+var (
+	// ErrScheduleUnavailable is returned when pack sizes are scheduled but no schedule is kept
+	ErrScheduleUnavailable = fmt.Errorf("pack size schedule is not kept")
+
+	// ErrUnknownSchedule is returned when scheduled pack sizes do not exist
+	ErrUnknownSchedule = fmt.Errorf("unknown scheduled pack sizes")
+
+	// ErrReserveAsOf is returned when an order packed at a later time asks for a reservation
+	ErrReserveAsOf = fmt.Errorf("stock can only be reserved for orders packed now")
+)
+
+// PackSizeSchedule describes a type that keeps the pack sizes scheduled to take effect later per product
+type PackSizeSchedule interface {
+	// SchedulePackSizes saves the scheduled pack sizes of its SKU and returns them with their ID set
+	SchedulePackSizes(ctx context.Context, scheduled models.ScheduledPackSizes) (models.ScheduledPackSizes, error)
+	// ScheduledPackSizes returns the scheduled pack sizes for the specified SKU, ordered by when they take effect.
+	// ErrScheduleUnavailable is returned when no schedule is kept where the pack sizes are stored.
+	ScheduledPackSizes(ctx context.Context, sku string) ([]models.ScheduledPackSizes, error)
+	// UnschedulePackSizes removes scheduled pack sizes for the specified SKU, ErrUnknownSchedule if there are none
+	UnschedulePackSizes(ctx context.Context, sku string, id int) error
+}
+
+// WithPackSizeSchedule lets pack sizes be scheduled to take effect later
+func WithPackSizeSchedule(schedule PackSizeSchedule) Option {
+	return func(s *PackingService) {
+		s.schedule = schedule
+	}
+}
+
+// WithClock sets the function telling the current time, time.Now by default
+func WithClock(now func() time.Time) Option {
+	return func(s *PackingService) {
+		s.now = now
+	}
+}
+
+// SchedulePackSizes schedules the pack sizes for the specified SKU to take effect at a later time. The pack sizes
+// are checked against the pack size rules like UpdatePackSizes does, a *PackSizesError is returned if they break
+// them or take effect before now.
+func (s PackingService) SchedulePackSizes(ctx context.Context, sku string, effectiveFrom time.Time, packSizes []models.PackSize) (models.ScheduledPackSizes, error) {
+	if s.schedule == nil {
+		return models.ScheduledPackSizes{}, ErrScheduleUnavailable
+	}
+
+	packSizes, err := s.rules.normalize(packSizes)
+	if !effectiveFrom.After(s.now()) {
+		fieldErr := models.FieldError{Field: "effectiveFrom", Message: "pack sizes must take effect after now"}
+		var packSizesErr *PackSizesError
+		if !errors.As(err, &packSizesErr) {
+			packSizesErr = &PackSizesError{}
+		}
+		packSizesErr.Fields = append([]models.FieldError{fieldErr}, packSizesErr.Fields...)
+		err = packSizesErr
+	}
+	if err != nil {
+		return models.ScheduledPackSizes{}, err
+	}
+
+	return s.schedule.SchedulePackSizes(ctx, models.ScheduledPackSizes{
+		SKU:           sku,
+		Author:        AuthorFromContext(ctx),
+		EffectiveFrom: effectiveFrom.UTC(),
+		PackSizes:     packSizes,
+	})
+}
+
+// ScheduledPackSizes returns the pack sizes scheduled for the specified SKU that have not taken effect yet,
+// ordered by when they take effect
+func (s PackingService) ScheduledPackSizes(ctx context.Context, sku string) ([]models.ScheduledPackSizes, error) {
+	if s.schedule == nil {
+		return nil, ErrScheduleUnavailable
+	}
+
+	if err := s.applySchedule(ctx, sku); err != nil {
+		return nil, err
+	}
+
+	return s.schedule.ScheduledPackSizes(ctx, sku)
+}
+
+// UnschedulePackSizes cancels pack sizes scheduled for the specified SKU before they take effect
+func (s PackingService) UnschedulePackSizes(ctx context.Context, sku string, id int) error {
+	if s.schedule == nil {
+		return ErrScheduleUnavailable
+	}
+
+	if err := s.applySchedule(ctx, sku); err != nil {
+		return err
+	}
+
+	return s.schedule.UnschedulePackSizes(ctx, sku, id)
+}
+
+// GetPackSizesAt returns the pack sizes for the specified SKU that are in effect at the specified time,
+// which are the current pack sizes for times before now
+func (s PackingService) GetPackSizesAt(ctx context.Context, sku string, at time.Time) ([]models.PackSize, error) {
+	if err := s.applySchedules(ctx, sku); err != nil {
+		return nil, err
+	}
+
+	packSizes, err := s.packSizeProvider.GetPackSizes(ctx, sku)
+	if err != nil || s.schedule == nil || !at.After(s.now()) {
+		return packSizes, err
+	}
+
+	scheduled, ok, err := s.scheduledAt(ctx, sku, at)
+	if err != nil || ok {
+		return scheduled, err
+	}
+
+	if sku == "" {
+		return packSizes, nil
+	}
+
+	// Products without pack sizes of their own follow the pack sizes scheduled for the default pack sizes
+	defaults, err := s.packSizeProvider.GetPackSizes(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if models.PackSizesVersion(defaults) != models.PackSizesVersion(packSizes) {
+		return packSizes, nil
+	}
+
+	if scheduled, ok, err = s.scheduledAt(ctx, "", at); err != nil || ok {
+		return scheduled, err
+	}
+
+	return packSizes, nil
+}
+
+// scheduledAt returns the last pack sizes scheduled for the SKU to take effect at the time or before,
+// false when there are none
+func (s PackingService) scheduledAt(ctx context.Context, sku string, at time.Time) ([]models.PackSize, bool, error) {
+	schedule, err := s.schedule.ScheduledPackSizes(ctx, sku)
+	if errors.Is(err, ErrScheduleUnavailable) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get scheduled pack sizes: %w", err)
+	}
+
+	for i := len(schedule) - 1; i >= 0; i-- {
+		if !schedule[i].EffectiveFrom.After(at) {
+			return schedule[i].PackSizes, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// applySchedules applies the scheduled pack sizes that took effect to the default pack sizes,
+// which products without pack sizes of their own use, and to the pack sizes of the SKU
+func (s PackingService) applySchedules(ctx context.Context, sku string) error {
+	if err := s.applySchedule(ctx, ""); err != nil || sku == "" {
+		return err
+	}
+
+	return s.applySchedule(ctx, sku)
+}
+
+// applySchedule updates the pack sizes for the specified SKU to the last of their scheduled pack sizes that
+// took effect, on behalf of whoever scheduled them, and removes the scheduled pack sizes that took effect.
+// Scheduled pack sizes are only applied by whoever removes them, so concurrent calls apply them once.
+func (s PackingService) applySchedule(ctx context.Context, sku string) error {
+	if s.schedule == nil {
+		return nil
+	}
+
+	schedule, err := s.schedule.ScheduledPackSizes(ctx, sku)
+	if errors.Is(err, ErrScheduleUnavailable) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get scheduled pack sizes: %w", err)
+	}
+
+	now := s.now()
+	due := -1
+	for i, scheduled := range schedule {
+		if !scheduled.EffectiveFrom.After(now) {
+			due = i
+		}
+	}
+	if due == -1 {
+		return nil
+	}
+
+	for _, scheduled := range schedule[:due] {
+		if err := s.schedule.UnschedulePackSizes(ctx, sku, scheduled.ID); err != nil && !errors.Is(err, ErrUnknownSchedule) {
+			return fmt.Errorf("failed to remove scheduled pack sizes: %w", err)
+		}
+	}
+
+	scheduled := schedule[due]
+	if err := s.schedule.UnschedulePackSizes(ctx, sku, scheduled.ID); err != nil {
+		if errors.Is(err, ErrUnknownSchedule) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to remove scheduled pack sizes: %w", err)
+	}
+
+	if _, err := s.savePackSizes(ContextWithAuthor(ctx, scheduled.Author), sku, "", scheduled.PackSizes, 0); err != nil {
+		return fmt.Errorf("failed to apply scheduled pack sizes: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// CalculateShipments packs the specified order like CalculatePacks and splits the packs into shipments within
+// the limits, balanced so every shipment holds about the same number of packs and items.
+// Pack sizes holding more items than a shipment may hold are not used, ErrShipmentLimitsExceeded is returned
+// if that leaves no pack size.
+func (s PackingService) CalculateShipments(ctx context.Context, order models.ShipmentOrder) (models.ShipmentPlan, error) {
+	if !order.Limits.IsValid() {
+		return models.ShipmentPlan{}, ErrInvalidShipmentLimits
+	}
+
+	result, err := s.calculatePacks(ctx, order.Order, order.Limits.MaxItems)
+	if err != nil {
+		return models.ShipmentPlan{}, err
+	}
+
+	plan := models.ShipmentPlan{PackingResult: result, Shipments: splitShipments(result.Packs, order.Limits)}
+	for _, shipment := range plan.Shipments {
+		plan.ShipmentCount += shipment.Quantity
+	}
+
+	return plan, nil
+}
+
+// shipmentGroup is a group of identical shipments, counts holds the number of packs of every pack line
+type shipmentGroup struct {
+	quantity         int
+	counts           []int
+	packCount, items int
+}
+
+// splitShipments splits the pack lines into the fewest shipments within the limits it finds. No pack holds more
+// items than a shipment may, so shipping every pack on its own always fits, and the number of shipments is
+// searched between the lower bound the limits give and the number of packs.
+func splitShipments(packs []models.PackLine, limits models.ShipmentLimits) []models.Shipment {
+	packCount, items := 0, 0
+	for _, line := range packs {
+		packCount += line.Quantity
+		items += line.MaxItems * line.Quantity
+	}
+
+	low := 1
+	if limits.MaxPacks > 0 {
+		low = max(low, (packCount+limits.MaxPacks-1)/limits.MaxPacks)
+	}
+	if limits.MaxItems > 0 {
+		low = max(low, (items+limits.MaxItems-1)/limits.MaxItems)
+	}
+
+	groups, ok := distributePacks(packs, low, limits)
+	if ok {
+		return shipments(packs, groups)
+	}
+
+	// The shipments fit more easily the more there are, so the fewest that fit are searched for by bisection
+	high := max(low, packCount)
+	groups, _ = distributePacks(packs, high, limits)
+	for low+1 < high {
+		mid := low + (high-low)/2
+		if midGroups, ok := distributePacks(packs, mid, limits); ok {
+			high, groups = mid, midGroups
+		} else {
+			low = mid
+		}
+	}
+
+	return shipments(packs, groups)
+}
+
+// distributePacks spreads the packs of every pack line, largest first, evenly over k shipments. The packs
+// left over after an even split go to the shipments holding the fewest items, which keeps the shipments
+// balanced. It reports whether every shipment stays within the limits.
+func distributePacks(packs []models.PackLine, k int, limits models.ShipmentLimits) ([]shipmentGroup, bool) {
+	groups := []shipmentGroup{{quantity: k, counts: make([]int, len(packs))}}
+	for i, line := range packs {
+		slices.SortStableFunc(groups, func(a, b shipmentGroup) int {
+			if a.items != b.items {
+				return cmp.Compare(a.items, b.items)
+			}
+
+			return cmp.Compare(a.packCount, b.packCount)
+		})
+
+		even, leftover := line.Quantity/k, line.Quantity%k
+		next := make([]shipmentGroup, 0, len(groups)+1)
+		for _, group := range groups {
+			if leftover > 0 && group.quantity > leftover {
+				// Only some of the shipments of the group get a pack left over, the group is split in two
+				extra := group.add(i, line.MaxItems, even+1)
+				extra.quantity = leftover
+				group.quantity -= leftover
+				next = append(next, extra, group.add(i, line.MaxItems, even))
+				leftover = 0
+
+				continue
+			}
+
+			packQty := even
+			if leftover > 0 {
+				packQty++
+				leftover -= group.quantity
+			}
+			next = append(next, group.add(i, line.MaxItems, packQty))
+		}
+		groups = next
+	}
+
+	for _, group := range groups {
+		if (limits.MaxPacks > 0 && group.packCount > limits.MaxPacks) || (limits.MaxItems > 0 && group.items > limits.MaxItems) {
+			return nil, false
+		}
+	}
+
+	return groups, true
+}
+
+// add returns a copy of the group with packQty more packs of the pack line at index i
+func (g shipmentGroup) add(i, packSize, packQty int) shipmentGroup {
+	g.counts = slices.Clone(g.counts)
+	g.counts[i] += packQty
+	g.packCount += packQty
+	g.items += packSize * packQty
+
+	return g
+}
+
+// shipments returns the shipments of the groups, merging identical groups, the fullest shipments first
+func shipments(packs []models.PackLine, groups []shipmentGroup) []models.Shipment {
+	slices.SortStableFunc(groups, func(a, b shipmentGroup) int {
+		if a.items != b.items {
+			return cmp.Compare(b.items, a.items)
+		}
+		if a.packCount != b.packCount {
+			return cmp.Compare(b.packCount, a.packCount)
+		}
+
+		return slices.Compare(b.counts, a.counts)
+	})
+
+	result := []models.Shipment{}
+	for i, group := range groups {
+		if i > 0 && slices.Equal(group.counts, groups[i-1].counts) {
+			result[len(result)-1].Quantity += group.quantity
+			continue
+		}
+
+		shipment := models.Shipment{Quantity: group.quantity, Packs: []models.PackLine{}, PackCount: group.packCount, ItemsShipped: group.items}
+		for j, packQty := range group.counts {
+			if packQty > 0 {
+				shipment.Packs = append(shipment.Packs, models.PackLine{MaxItems: packs[j].MaxItems, Quantity: packQty})
+			}
+		}
+		result = append(result, shipment)
+	}
+
+	return result
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"unsafe"
+
+	"github.com/cybre/order-packing/internal/models"
+)
+
+// DefaultTableCacheSize is the number of bytes of packing tables a PackingService keeps by default
+const DefaultTableCacheSize = 64 << 20
+
+// maxCachedAmount bounds the amounts a cached table covers, so a single table never takes most of the cache
+const maxCachedAmount = 1 << 18
+
+// dpTableCache keeps the dynamic programming tables of the pack size sets orders were packed with, so orders
+// packed with the same pack sizes reuse the table instead of building it again. The best packing of an amount
+// only depends on the pack sizes, so a table covering more amounts than an order needs serves the order as well.
+// Pack sizes with limited stock change with every reservation and are not cached. The tables take at most
+// maxSize bytes, the least recently used ones are dropped to make room for others.
+// A nil cache keeps nothing. It is safe for concurrent use, cached tables are never modified.
+type dpTableCache struct {
+	mu      sync.Mutex
+	maxSize int
+	size    int
+	// tables holds the elements of recent, ordered from the most to the least recently used, by key
+	tables map[string]*list.Element
+	recent *list.List
+}
+
+// cachedDPTable is a table kept in the cache under its key
+type cachedDPTable struct {
+	key   string
+	table dpTable
+}
+
+// newDPTableCache returns a cache keeping up to maxSize bytes of tables, nil when maxSize is not positive
+func newDPTableCache(maxSize int) *dpTableCache {
+	if maxSize <= 0 {
+		return nil
+	}
+
+	return &dpTableCache{maxSize: maxSize, tables: map[string]*list.Element{}, recent: list.New()}
+}
+
+// table returns a table of the items of the pack sizes covering the amounts up to maxAmount, building it when
+// the cache has none. Packs sizes must be unique, sorted in ascending order and have unlimited stock.
+func (c *dpTableCache) table(packSizes []models.PackSize, items []dpItem, objective models.Objective, maxAmount int) dpTable {
+	key := dpTableKey(packSizes, objective)
+
+	var cached dpTable
+	c.mu.Lock()
+	element, ok := c.tables[key]
+	if ok {
+		c.recent.MoveToFront(element)
+		cached = element.Value.(*cachedDPTable).table
+	}
+	c.mu.Unlock()
+	if ok && len(cached.entries) > maxAmount {
+		return cached
+	}
+
+	if maxAmount > maxCachedAmount {
+		return newDPTable(items, maxAmount, 0, objective, nil)
+	}
+
+	// Tables grow by doubling, so orders growing a little at a time do not rebuild them every time
+	size := maxAmount
+	if ok {
+		size = min(max(size, 2*(len(cached.entries)-1)), maxCachedAmount)
+	}
+	table := newDPTable(items, size, 0, objective, nil)
+	if table.size() > c.maxSize {
+		return table
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another order may have built a table for the pack sizes in the meantime, the larger one is kept
+	if element, ok := c.tables[key]; ok {
+		current := element.Value.(*cachedDPTable)
+		if len(current.table.entries) >= len(table.entries) {
+			return table
+		}
+
+		c.size += table.size() - current.table.size()
+		current.table = table
+		c.recent.MoveToFront(element)
+	} else {
+		c.tables[key] = c.recent.PushFront(&cachedDPTable{key: key, table: table})
+		c.size += table.size()
+	}
+
+	// The table just stored fits on its own, so it is never the one dropped
+	for c.size > c.maxSize {
+		evicted := c.recent.Remove(c.recent.Back()).(*cachedDPTable)
+		delete(c.tables, evicted.key)
+		c.size -= evicted.table.size()
+	}
+
+	return table
+}
+
+// clear drops every cached table
+func (c *dpTableCache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.tables)
+	c.recent.Init()
+	c.size = 0
+}
+
+// size returns the number of bytes the entries and the used bitmaps of the table take
+func (t dpTable) size() int {
+	size := len(t.entries) * int(unsafe.Sizeof(dpEntry{}))
+	for _, used := range t.used {
+		size += len(used) * 8
+	}
+
+	return size
+}
+
+// dpTableKey identifies the table of the pack sizes for the objective by what building the table depends on
+func dpTableKey(packSizes []models.PackSize, objective models.Objective) string {
+	key := []byte(objective)
+	for _, packSize := range packSizes {
+		key = append(key, ' ')
+		key = strconv.AppendInt(key, int64(packSize.MaxItems), 10)
+		key = append(key, ':')
+		key = strconv.AppendFloat(key, packSize.Cost, 'g', -1, 64)
+	}
+
+	return string(key)
+}
@@ -0,0 +1,57 @@
+package testdata
+
+import (
+	"context"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
+)
+
+// MockPackSizeHistory is a mock PackSizeHistory keeping the versions in memory
+type MockPackSizeHistory struct {
+	Error    error
+	Versions []models.PackSizeSetVersion
+}
+
+// AddPackSizeVersion saves the version with the next ID or returns an error if one was specified
+func (m *MockPackSizeHistory) AddPackSizeVersion(ctx context.Context, version models.PackSizeSetVersion) (models.PackSizeSetVersion, error) {
+	if m.Error != nil {
+		return models.PackSizeSetVersion{}, m.Error
+	}
+
+	version.ID = len(m.Versions) + 1
+	m.Versions = append(m.Versions, version)
+
+	return version, nil
+}
+
+// PackSizeVersions returns the versions of the SKU, the newest first, or an error if one was specified
+func (m *MockPackSizeHistory) PackSizeVersions(ctx context.Context, sku string) ([]models.PackSizeSetVersion, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+
+	versions := []models.PackSizeSetVersion{}
+	for i := len(m.Versions) - 1; i >= 0; i-- {
+		if m.Versions[i].SKU == sku {
+			versions = append(versions, m.Versions[i])
+		}
+	}
+
+	return versions, nil
+}
+
+// PackSizeVersion returns a version of the SKU or an error if one was specified
+func (m *MockPackSizeHistory) PackSizeVersion(ctx context.Context, sku string, id int) (models.PackSizeSetVersion, error) {
+	if m.Error != nil {
+		return models.PackSizeSetVersion{}, m.Error
+	}
+
+	for _, version := range m.Versions {
+		if version.ID == id && version.SKU == sku {
+			return version, nil
+		}
+	}
+
+	return models.PackSizeSetVersion{}, services.ErrUnknownVersion
+}
@@ -2,26 +2,88 @@ package testdata
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
 )
 
 // MockPackSizeProvider is a mock PackSizeProvider
 type MockPackSizeProvider struct {
-	Error     error
-	PackSizes []models.PackSize
+	Error            error
+	PackSizes        []models.PackSize
+	ProductPackSizes map[string][]models.PackSize
+	StockAdjustments []models.StockAdjustment
+	// Updated holds the pack sizes of the last update
+	Updated []models.PackSize
+	// Fetches counts the calls to GetPackSizes
+	Fetches atomic.Int64
+	// BeforeSwap is called by CompareAndSwap before it compares the versions, to change the pack sizes concurrently
+	BeforeSwap func()
 }
 
-// GetPackSizes returns the available pack sizes or an error
-func (m MockPackSizeProvider) GetPackSizes(ctx context.Context) ([]models.PackSize, error) {
+// GetPackSizes returns the pack sizes of the product, the default pack sizes or an error
+func (m *MockPackSizeProvider) GetPackSizes(ctx context.Context, sku string) ([]models.PackSize, error) {
+	m.Fetches.Add(1)
 	if m.Error != nil {
 		return nil, m.Error
 	}
 
+	if packSizes, ok := m.ProductPackSizes[sku]; ok {
+		return packSizes, nil
+	}
+
 	return m.PackSizes, nil
 }
 
-// Update returns an error if one was specified
-func (m MockPackSizeProvider) Update(ctx context.Context, packSizes []models.PackSize) error {
-	return m.Error
+// Update stores and records the pack sizes or returns an error if one was specified
+func (m *MockPackSizeProvider) Update(ctx context.Context, sku string, packSizes []models.PackSize) error {
+	if m.Error != nil {
+		return m.Error
+	}
+
+	m.Updated = packSizes
+	if sku == "" {
+		m.PackSizes = packSizes
+	} else {
+		if m.ProductPackSizes == nil {
+			m.ProductPackSizes = make(map[string][]models.PackSize)
+		}
+		m.ProductPackSizes[sku] = packSizes
+	}
+
+	return nil
+}
+
+// CompareAndSwap stores the pack sizes like Update if the pack sizes of the SKU have the version
+func (m *MockPackSizeProvider) CompareAndSwap(ctx context.Context, sku, version string, packSizes []models.PackSize) error {
+	if m.Error != nil {
+		return m.Error
+	}
+
+	if m.BeforeSwap != nil {
+		m.BeforeSwap()
+	}
+
+	current, ok := m.ProductPackSizes[sku]
+	if !ok {
+		current = m.PackSizes
+	}
+
+	if models.PackSizesVersion(current) != version {
+		return services.ErrVersionConflict
+	}
+
+	return m.Update(ctx, sku, packSizes)
+}
+
+// AdjustStock records the stock adjustments or returns an error if one was specified
+func (m *MockPackSizeProvider) AdjustStock(ctx context.Context, sku string, adjustments []models.StockAdjustment) error {
+	if m.Error != nil {
+		return m.Error
+	}
+
+	m.StockAdjustments = append(m.StockAdjustments, adjustments...)
+
+	return nil
 }
@@ -0,0 +1,66 @@
+package testdata
+
+import (
+	"context"
+	"slices"
+
+	"github.com/cybre/order-packing/internal/models"
+	"github.com/cybre/order-packing/internal/services"
+)
+
+// MockPackSizeSchedule is a mock PackSizeSchedule keeping the scheduled pack sizes in memory
+type MockPackSizeSchedule struct {
+	Error     error
+	Scheduled []models.ScheduledPackSizes
+	lastID    int
+}
+
+// SchedulePackSizes saves the scheduled pack sizes with the next ID or returns an error if one was specified
+func (m *MockPackSizeSchedule) SchedulePackSizes(ctx context.Context, scheduled models.ScheduledPackSizes) (models.ScheduledPackSizes, error) {
+	if m.Error != nil {
+		return models.ScheduledPackSizes{}, m.Error
+	}
+
+	m.lastID++
+	scheduled.ID = m.lastID
+	m.Scheduled = append(m.Scheduled, scheduled)
+	slices.SortStableFunc(m.Scheduled, func(a, b models.ScheduledPackSizes) int {
+		return a.EffectiveFrom.Compare(b.EffectiveFrom)
+	})
+
+	return scheduled, nil
+}
+
+// ScheduledPackSizes returns the scheduled pack sizes of the SKU or an error if one was specified
+func (m *MockPackSizeSchedule) ScheduledPackSizes(ctx context.Context, sku string) ([]models.ScheduledPackSizes, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+
+	schedule := []models.ScheduledPackSizes{}
+	for _, scheduled := range m.Scheduled {
+		if scheduled.SKU == sku {
+			schedule = append(schedule, scheduled)
+		}
+	}
+
+	return schedule, nil
+}
+
+// UnschedulePackSizes removes scheduled pack sizes of the SKU or returns an error if one was specified
+func (m *MockPackSizeSchedule) UnschedulePackSizes(ctx context.Context, sku string, id int) error {
+	if m.Error != nil {
+		return m.Error
+	}
+
+	i := slices.IndexFunc(m.Scheduled, func(scheduled models.ScheduledPackSizes) bool {
+		return scheduled.ID == id && scheduled.SKU == sku
+	})
+	if i == -1 {
+		return services.ErrUnknownSchedule
+	}
+
+	m.Scheduled = slices.Delete(m.Scheduled, i, i+1)
+
+	return nil
+}
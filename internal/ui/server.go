@@ -6,9 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"slices"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -51,17 +51,31 @@ func StartServer(ctx context.Context, address string) error {
 	return nil
 }
 
+// alternatives is the number of best packings listed under the result
+const alternatives = 5
+
 func buildRoutes(e *echo.Echo) {
 	apiAddress := os.Getenv("API_REMOTE_ADDRESS")
 
 	e.GET("/", indexHandler(apiAddress))
 	e.POST("/", packOrderHandler(apiAddress))
-	e.POST("/pack-sizes", updatePackSizesHandler(apiAddress))
+	e.POST("/pack-sizes", addPackSizeHandler(apiAddress))
+	e.POST("/pack-sizes/delete", removePackSizeHandler(apiAddress))
+	e.POST("/pack-sizes/rollback", rollbackPackSizesHandler(apiAddress))
+}
 
+// packSizesURL returns the API URL of the pack sizes for the specified SKU (the default pack sizes when empty)
+func packSizesURL(address, sku string) string {
+	if sku == "" {
+		return address + "/pack-sizes"
+	}
+
+	return address + "/products/" + url.PathEscape(sku) + "/pack-sizes"
 }
 
-func getPackSizes(ctx context.Context, address string) ([]int, error) {
-	packSizes, err := http.DefaultClient.Get(address + "/pack-sizes")
+// getPackSizes returns the pack sizes for the specified SKU
+func getPackSizes(ctx context.Context, address, sku string) ([]models.PackSize, error) {
+	packSizes, err := http.DefaultClient.Get(packSizesURL(address, sku))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pack sizes: %w", err)
 	}
@@ -79,103 +93,382 @@ func getPackSizes(ctx context.Context, address string) ([]int, error) {
 	return mapPackSizedToViewModel(packSizesData), nil
 }
 
-func mapPackSizedToViewModel(packSizes []models.PackSize) []int {
-	packSizesView := []int{}
-	for _, packSizeData := range packSizes {
-		packSizesView = append(packSizesView, packSizeData.MaxItems)
+func mapPackSizedToViewModel(packSizes []models.PackSize) []models.PackSize {
+	packSizesView := slices.Clone(packSizes)
+	slices.SortFunc(packSizesView, func(a, b models.PackSize) int {
+		return a.MaxItems - b.MaxItems
+	})
+
+	return packSizesView
+}
+
+// getPackSizeVersions returns the versions of the pack sizes for the specified SKU, nil when the API keeps no history
+func getPackSizeVersions(ctx context.Context, address, sku string) ([]packSizeVersionView, error) {
+	resp, err := http.DefaultClient.Get(packSizesURL(address, sku) + "/versions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pack size versions: %w", err)
 	}
+	defer resp.Body.Close()
 
-	sort.Ints(packSizesView)
+	if resp.StatusCode == http.StatusNotImplemented {
+		return nil, nil
+	}
 
-	return packSizesView
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get pack size versions: %s", resp.Status)
+	}
+
+	var versions []models.PackSizeSetVersion
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pack size versions: %w", err)
+	}
+
+	views := make([]packSizeVersionView, len(versions))
+	for i, version := range versions {
+		views[i] = packSizeVersionView{PackSizeSetVersion: version, Changes: make([]string, len(version.Changes))}
+		for j, change := range version.Changes {
+			views[i].Changes[j] = describeChange(change)
+		}
+	}
+
+	return views, nil
+}
+
+// packSizeVersionView is a version of the pack sizes with its changes described for the history panel
+type packSizeVersionView struct {
+	models.PackSizeSetVersion
+	Changes []string
+}
+
+// describeChange describes the change of a pack size in the notation of the pack sizes form
+func describeChange(change models.PackSizeChange) string {
+	switch change.Type {
+	case models.PackSizeAdded:
+		return "+ " + formatPackSize(*change.After)
+	case models.PackSizeRemoved:
+		return "- " + formatPackSize(*change.Before)
+	default:
+		return formatPackSize(*change.Before) + " → " + formatPackSize(*change.After)
+	}
+}
+
+// formatPackSize formats the pack size as "size[:cost[:stock[:maxWeight[:maxVolume]]]]", the way it is entered
+func formatPackSize(packSize models.PackSize) string {
+	formatFloat := func(value float64) string {
+		if value == 0 {
+			return ""
+		}
+
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	stock := ""
+	if packSize.Stock != nil {
+		stock = strconv.Itoa(*packSize.Stock)
+	}
+
+	fields := []string{strconv.Itoa(packSize.MaxItems), formatFloat(packSize.Cost), stock, formatFloat(packSize.MaxWeight), formatFloat(packSize.MaxVolume)}
+
+	return strings.TrimRight(strings.Join(fields, ":"), ":")
+}
+
+// authorCookie remembers who updates the pack sizes from this browser
+const authorCookie = "author"
+
+// author returns who updates the pack sizes from this browser, empty when unknown
+func author(c echo.Context) string {
+	cookie, err := c.Cookie(authorCookie)
+	if err != nil {
+		return ""
+	}
+
+	author, _ := url.QueryUnescape(cookie.Value)
+
+	return author
+}
+
+// packSizesPageData returns the page data showing the pack sizes for the specified SKU along with their history
+func packSizesPageData(c echo.Context, apiAddress, sku string) (map[string]interface{}, error) {
+	packSizes, err := getPackSizes(c.Request().Context(), apiAddress, sku)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := getPackSizeVersions(c.Request().Context(), apiAddress, sku)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"PackSizes":   packSizes,
+		"NewPackSize": map[string]string{},
+		"Versions":    versions,
+		"Author":      author(c),
+		"Objective":   models.ObjectiveFewestItems,
+		"SKU":         sku,
+	}, nil
 }
 
 func indexHandler(apiAddress string) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		packSizes, err := getPackSizes(c.Request().Context(), apiAddress)
+		pageData, err := packSizesPageData(c, apiAddress, c.QueryParam("sku"))
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 
-		pageData := map[string]interface{}{
-			"PackSizes": packSizes,
-		}
-
 		return c.Render(http.StatusOK, "index", pageData)
 	}
 }
 
 func packOrderHandler(apiAddress string) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		var order models.Order
+		var order models.ShipmentOrder
 		if err := c.Bind(&order); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
+		order.Alternatives = alternatives
+
+		// The packaging levels are entered as text, e.g. "carton:4,pallet:20"
+		if packaging := c.FormValue("packaging"); packaging != "" {
+			hierarchy, err := models.ParsePackagingHierarchy(packaging)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+			order.Hierarchy = hierarchy
+		}
 
-		orderData, err := json.Marshal(order)
+		// Orders with shipment limits are split into shipments, the packing result is part of the shipment plan
+		endpoint, body := "/v2/pack-order", interface{}(order.Order)
+		if order.Limits != (models.ShipmentLimits{}) {
+			endpoint, body = "/pack-order-shipments", order
+		}
+
+		orderData, err := json.Marshal(body)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 
-		resp, err := http.DefaultClient.Post(apiAddress+"/pack-order", "application/json", bytes.NewReader(orderData))
+		resp, err := http.DefaultClient.Post(apiAddress+endpoint, "application/json", bytes.NewReader(orderData))
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 		defer resp.Body.Close()
 
-		var orderPacks map[int]int
-		if err := json.NewDecoder(resp.Body).Decode(&orderPacks); err != nil {
+		// Orders the API rejects are reported under the order form, along with the order that was entered
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity:
+		default:
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to pack order"})
+		}
+
+		var plan models.ShipmentPlan
+		var orderErr struct {
+			Error string `json:"error"`
+		}
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&plan)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&orderErr)
+		}
+		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 
-		packSizes, err := getPackSizes(c.Request().Context(), apiAddress)
+		pageData, err := packSizesPageData(c, apiAddress, order.SKU)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
 		}
 
-		pageData := map[string]interface{}{
-			"PackSizes": packSizes,
-			"Results":   mapOrderPacksToViewModel(orderPacks),
-			"ItemQty":   order.ItemQty,
+		if resp.StatusCode == http.StatusOK {
+			pageData["Result"] = plan.PackingResult
+			pageData["Shipments"] = plan.Shipments
+			pageData["ShipmentCount"] = plan.ShipmentCount
+		} else {
+			pageData["OrderError"] = orderErr.Error
 		}
+		pageData["Limits"] = order.Limits
+		pageData["ItemQty"] = order.ItemQty
+		pageData["Objective"] = order.Objective
+		pageData["Explain"] = order.Explain
+		pageData["ItemWeight"] = order.ItemWeight
+		pageData["ItemVolume"] = order.ItemVolume
+		pageData["Packaging"] = c.FormValue("packaging")
+
+		return c.Render(resp.StatusCode, "index", pageData)
+	}
+}
 
-		return c.Render(http.StatusOK, "index", pageData)
+// addPackSizeHandler adds the pack size entered in the last row of the pack sizes table
+func addPackSizeHandler(apiAddress string) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		packSize, err := formPackSize(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		packSizeData, err := json.Marshal(packSize)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+
+		sku := c.FormValue("sku")
+		c.SetCookie(&http.Cookie{Name: authorCookie, Value: url.QueryEscape(c.FormValue("author")), Path: "/"})
+
+		return changePackSizes(c, apiAddress, sku, http.MethodPost, packSizesURL(apiAddress, sku), c.FormValue("author"), packSizeData)
+	}
+}
+
+// removePackSizeHandler removes the pack size of a row of the pack sizes table
+func removePackSizeHandler(apiAddress string) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		maxItems, err := strconv.Atoi(c.FormValue("maxItems"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid pack size"})
+		}
+
+		sku := c.FormValue("sku")
+
+		return changePackSizes(c, apiAddress, sku, http.MethodDelete, fmt.Sprintf("%s/%d", packSizesURL(apiAddress, sku), maxItems), author(c), nil)
 	}
 }
 
-func mapOrderPacksToViewModel(orderPacks map[int]int) []map[string]int {
-	results := []map[string]int{}
-	for packSize, packQty := range orderPacks {
-		results = append(results, map[string]int{
-			"Size":     packSize,
-			"Quantity": packQty,
-		})
+// changePackSizes sends a change of a single pack size to the API and shows the pack sizes again. Changes the API
+// rejects are reported under the pack sizes table, along with the pack size that was entered.
+func changePackSizes(c echo.Context, apiAddress, sku, method, endpoint, changedBy string, body []byte) error {
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Author", changedBy)
 
-	slices.SortFunc(results, func(a, b map[string]int) int {
-		return b["Size"] - a["Size"]
-	})
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusNoContent:
+		return redirectToPackSizes(c, sku)
+	case http.StatusUnprocessableEntity, http.StatusConflict, http.StatusNotFound:
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to change pack sizes"})
+	}
 
-	return results
+	messages, err := apiErrorMessages(resp)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	pageData, err := packSizesPageData(c, apiAddress, sku)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	pageData["PackSizeErrors"] = messages
+	if method == http.MethodPost {
+		pageData["NewPackSize"] = map[string]string{
+			"MaxItems":  c.FormValue("maxItems"),
+			"Cost":      c.FormValue("cost"),
+			"Stock":     c.FormValue("stock"),
+			"MaxWeight": c.FormValue("maxWeight"),
+			"MaxVolume": c.FormValue("maxVolume"),
+		}
+	}
+
+	return c.Render(resp.StatusCode, "index", pageData)
 }
 
-func updatePackSizesHandler(apiAddress string) func(c echo.Context) error {
-	return func(c echo.Context) error {
-		packSizes, err := extractPackSizes(c.FormValue("packSizes"))
+// redirectToPackSizes redirects to the page showing the pack sizes for the specified SKU
+func redirectToPackSizes(c echo.Context, sku string) error {
+	if sku != "" {
+		return c.Redirect(http.StatusFound, "/?sku="+url.QueryEscape(sku))
+	}
+
+	return c.Redirect(http.StatusFound, "/")
+}
+
+// apiErrorMessages returns the messages of the error the API responded with, one per field error when there are any
+func apiErrorMessages(resp *http.Response) ([]string, error) {
+	var apiErr struct {
+		Error  string              `json:"error"`
+		Fields []models.FieldError `json:"fields"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal error: %w", err)
+	}
+
+	if len(apiErr.Fields) > 0 {
+		return packSizeErrorMessages(apiErr.Fields), nil
+	}
+
+	return []string{apiErr.Error}, nil
+}
+
+// packSizeErrorMessages describes the field errors of a pack size
+func packSizeErrorMessages(fields []models.FieldError) []string {
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field.Message)
+	}
+
+	return messages
+}
+
+// formPackSize returns the pack size entered in the form, the fields other than the size are optional
+func formPackSize(c echo.Context) (models.PackSize, error) {
+	maxItems, err := strconv.Atoi(c.FormValue("maxItems"))
+	if err != nil {
+		return models.PackSize{}, fmt.Errorf("failed to parse pack size: %w", err)
+	}
+
+	packSize := models.PackSize{MaxItems: maxItems}
+	if cost := c.FormValue("cost"); cost != "" {
+		if packSize.Cost, err = strconv.ParseFloat(cost, 64); err != nil {
+			return models.PackSize{}, fmt.Errorf("failed to parse pack cost: %w", err)
+		}
+	}
+
+	if stockValue := c.FormValue("stock"); stockValue != "" {
+		stock, err := strconv.Atoi(stockValue)
 		if err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return models.PackSize{}, fmt.Errorf("failed to parse pack stock: %w", err)
 		}
+		packSize.Stock = &stock
+	}
 
-		packSizeData, err := json.Marshal(packSizes)
+	if maxWeight := c.FormValue("maxWeight"); maxWeight != "" {
+		if packSize.MaxWeight, err = strconv.ParseFloat(maxWeight, 64); err != nil {
+			return models.PackSize{}, fmt.Errorf("failed to parse pack weight limit: %w", err)
+		}
+	}
+
+	if maxVolume := c.FormValue("maxVolume"); maxVolume != "" {
+		if packSize.MaxVolume, err = strconv.ParseFloat(maxVolume, 64); err != nil {
+			return models.PackSize{}, fmt.Errorf("failed to parse pack volume limit: %w", err)
+		}
+	}
+
+	return packSize, nil
+}
+
+// rollbackPackSizesHandler restores a version from the history panel. Rollbacks the API rejects are reported
+// in the history panel.
+func rollbackPackSizesHandler(apiAddress string) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		id, err := strconv.Atoi(c.FormValue("id"))
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid pack size version"})
 		}
 
-		req, err := http.NewRequest(http.MethodPut, apiAddress+"/pack-sizes", bytes.NewReader(packSizeData))
+		sku := c.FormValue("sku")
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/versions/%d/rollback", packSizesURL(apiAddress, sku), id), nil)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
-		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Author", author(c))
 
 		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
@@ -183,28 +476,26 @@ func updatePackSizesHandler(apiAddress string) func(c echo.Context) error {
 		}
 		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusNoContent {
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update pack sizes"})
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return redirectToPackSizes(c, sku)
+		case http.StatusUnprocessableEntity, http.StatusConflict, http.StatusNotFound, http.StatusNotImplemented:
+		default:
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to roll back pack sizes"})
 		}
 
-		return c.Redirect(http.StatusFound, "/")
-	}
-}
-
-func extractPackSizes(packSizes string) ([]models.PackSize, error) {
-	if packSizes == "" {
-		return nil, fmt.Errorf("pack sizes cannot be empty")
-	}
+		messages, err := apiErrorMessages(resp)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
 
-	packSizeModels := []models.PackSize{}
-	for _, packSize := range strings.Split(packSizes, ",") {
-		packSizeInt, err := strconv.Atoi(strings.TrimSpace(packSize))
+		pageData, err := packSizesPageData(c, apiAddress, sku)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse pack size: %w", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 
-		packSizeModels = append(packSizeModels, models.PackSize{MaxItems: packSizeInt})
-	}
+		pageData["HistoryErrors"] = messages
 
-	return packSizeModels, nil
+		return c.Render(resp.StatusCode, "index", pageData)
+	}
 }